@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/sst/ion/pkg/console"
+)
+
+// set by initProject when logged in to the console, nil otherwise
+var live *console.Stream
+
+// consoleRenderer passes progress on to the console next to the renderer
+// that prints it
+type consoleRenderer struct {
+	Renderer
+	mode ProgressMode
+}
+
+func withConsole(renderer Renderer, mode ProgressMode) Renderer {
+	if live == nil {
+		return renderer
+	}
+	return &consoleRenderer{Renderer: renderer, mode: mode}
+}
+
+func (r *consoleRenderer) Progress(progress Progress) {
+	live.Send(progress)
+	r.Renderer.Progress(progress)
+}
+
+func (r *consoleRenderer) Finish(summary *ProgressSummary) {
+	update := map[string]interface{}{
+		"mode":    r.mode,
+		"summary": summary,
+	}
+	live.Send(update)
+	live.Track("update", update)
+	r.Renderer.Finish(summary)
+}
+
+func login() error {
+	code, err := console.StartLogin()
+	if err != nil {
+		return err
+	}
+	fmt.Println()
+	color.New(color.FgCyan, color.Bold).Print("➜  ")
+	color.New(color.FgWhite, color.Bold).Println("Confirm this code in your browser: " + code.UserCode)
+	uri := code.VerificationURIComplete
+	if uri == "" {
+		uri = code.VerificationURI
+	}
+	color.New(color.FgHiBlack).Println("   " + uri)
+
+	session, err := code.Wait()
+	if err != nil {
+		return err
+	}
+	color.New(color.FgGreen, color.Bold).Print("\n✔")
+	color.New(color.FgWhite, color.Bold).Printf("  Logged in as %v\n", session.Email)
+	return nil
+}
+
+func printSession(session *console.Session, err error) {
+	fmt.Println()
+	if session == nil {
+		color.New(color.FgHiBlack).Println("   Not logged in, everything stays on this machine")
+		return
+	}
+	printStatus("Email:", session.Email)
+	printStatus("Workspace:", session.Workspace)
+	printStatus("Console:", session.Url)
+	if err != nil {
+		color.New(color.FgYellow, color.Bold).Print("\n!  ")
+		color.New(color.FgWhite).Println(err.Error())
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/sst/ion/pkg/global"
+	"github.com/sst/ion/pkg/project"
+
+	cli "github.com/urfave/cli/v2"
+)
+
+type crashReport struct {
+	Time    string            `json:"time"`
+	Version string            `json:"version"`
+	Go      string            `json:"go"`
+	OS      string            `json:"os"`
+	Arch    string            `json:"arch"`
+	Command string            `json:"command"`
+	Error   string            `json:"error"`
+	Stack   string            `json:"stack,omitempty"`
+	Events  []json.RawMessage `json:"events"`
+}
+
+// pathCrashDir is the .sst directory of the project in the working directory,
+// or the global config directory when there is none
+func pathCrashDir() string {
+	cfgPath, err := project.Discover()
+	if err != nil {
+		return global.ConfigDir()
+	}
+	return filepath.Join(filepath.Dir(cfgPath), ".sst")
+}
+
+// writeCrash saves a report for an error that is not a failed deploy. The
+// command is reduced to its name and flag names since the values can be
+// secrets.
+func writeCrash(app *cli.App, err error, stack []byte) (string, error) {
+	dir := pathCrashDir()
+	report := crashReport{
+		Time:    time.Now().UTC().Format(time.RFC3339),
+		Version: version,
+		Go:      runtime.Version(),
+		OS:      runtime.GOOS,
+		Arch:    runtime.GOARCH,
+		Command: sanitizeCommand(app, os.Args[1:]),
+		Error:   err.Error(),
+		Stack:   string(stack),
+		Events:  lastEvents(filepath.Join(dir, "events"), 50),
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("crash-%v.json", time.Now().Unix()))
+	return path, os.WriteFile(path, data, 0600)
+}
+
+func sanitizeCommand(app *cli.App, args []string) string {
+	parts := []string{"sst"}
+	if name := commandName(app, args); name != "" {
+		parts = append(parts, name)
+	}
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-") {
+			parts = append(parts, strings.SplitN(arg, "=", 2)[0])
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// lastEvents reads the tail of the most recently written event log
+func lastEvents(dir string, count int) []json.RawMessage {
+	result := []json.RawMessage{}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return result
+	}
+	var latest string
+	var modified time.Time
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || entry.IsDir() {
+			continue
+		}
+		if info.ModTime().After(modified) {
+			latest = filepath.Join(dir, entry.Name())
+			modified = info.ModTime()
+		}
+	}
+	if latest == "" {
+		return result
+	}
+	file, err := os.Open(latest)
+	if err != nil {
+		return result
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+	for scanner.Scan() {
+		result = append(result, json.RawMessage(append([]byte{}, scanner.Bytes()...)))
+		if len(result) > count {
+			result = result[1:]
+		}
+	}
+	return result
+}
+
+// event fields that carry resource inputs, outputs or program output
+var redactedFields = map[string]bool{
+	"old":          true,
+	"new":          true,
+	"inputs":       true,
+	"outputs":      true,
+	"detailedDiff": true,
+	"Text":         true,
+	"Message":      true,
+}
+
+var secretPatterns = []*regexp.Regexp{
+	// aws access key ids
+	regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`),
+	// key=value and key: value pairs that look like credentials
+	regexp.MustCompile(`(?i)((?:secret|token|password|passwd|key|authorization)[\w-]*["']?\s*[:=]\s*["']?)[^\s"',]+`),
+	// long opaque strings like session tokens and signatures
+	regexp.MustCompile(`[A-Za-z0-9+/=_-]{40,}`),
+}
+
+func redactString(input string) string {
+	for i, pattern := range secretPatterns {
+		if i == 1 {
+			input = pattern.ReplaceAllString(input, "${1}[redacted]")
+			continue
+		}
+		input = pattern.ReplaceAllString(input, "[redacted]")
+	}
+	return input
+}
+
+func redactJSON(input interface{}) interface{} {
+	switch value := input.(type) {
+	case map[string]interface{}:
+		result := map[string]interface{}{}
+		for key, item := range value {
+			if redactedFields[key] {
+				result[key] = "[redacted]"
+				continue
+			}
+			result[key] = redactJSON(item)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(value))
+		for i, item := range value {
+			result[i] = redactJSON(item)
+		}
+		return result
+	case string:
+		return redactString(value)
+	}
+	return input
+}
+
+// redactReport drops resource values and program output from the events and
+// masks anything that looks like a credential before a report leaves the
+// machine
+func redactReport(data []byte) ([]byte, error) {
+	var report crashReport
+	err := json.Unmarshal(data, &report)
+	if err != nil {
+		return nil, err
+	}
+	report.Error = redactString(report.Error)
+	report.Stack = redactString(report.Stack)
+	for i, event := range report.Events {
+		var parsed interface{}
+		if json.Unmarshal(event, &parsed) != nil {
+			report.Events[i] = json.RawMessage(`"[redacted]"`)
+			continue
+		}
+		redacted, err := json.Marshal(redactJSON(parsed))
+		if err != nil {
+			return nil, err
+		}
+		report.Events[i] = redacted
+	}
+	return json.MarshalIndent(report, "", "  ")
+}
+
+// latestCrash is the newest report in the project, empty if there is none
+func latestCrash() string {
+	matches, _ := filepath.Glob(filepath.Join(pathCrashDir(), "crash-*.json"))
+	if len(matches) == 0 {
+		return ""
+	}
+	sort.Strings(matches)
+	return matches[len(matches)-1]
+}
+
+func printCrash(err error, path string) {
+	color.New(color.FgRed, color.Bold).Print("❌ ")
+	color.New(color.FgWhite).Println(err.Error())
+	if path != "" {
+		color.New(color.FgHiBlack).Printf("   Crash report written to %v, run `sst report send` to share it\n", path)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/sst/ion/pkg/project"
+)
+
+func printDomains(domains []project.Domain) {
+	if len(domains) == 0 {
+		fmt.Println()
+		color.New(color.FgHiBlack).Println("   No custom domains deployed")
+		return
+	}
+	for _, domain := range domains {
+		fmt.Println()
+		color.New(color.FgCyan, color.Bold).Print("➜  ")
+		color.New(color.FgWhite, color.Bold).Println(domain.Name)
+		for _, alias := range domain.Aliases {
+			printStatus("Alias:", alias)
+		}
+		printStatus("Certificate:", domain.Status)
+		if domain.Failure != "" {
+			printStatus("Failure:", domain.Failure)
+		}
+		for _, record := range domain.Validation {
+			if record.Status == "SUCCESS" {
+				continue
+			}
+			printDomainRecord(record, "validate the certificate")
+		}
+		for _, record := range domain.Routing {
+			printDomainRecord(record, "route traffic")
+		}
+	}
+}
+
+func printDomainRecord(record project.DomainRecord, purpose string) {
+	if record.Managed {
+		color.New(color.FgGreen, color.Bold).Print("   ✔  ")
+		color.New(color.FgHiBlack).Printf("%v %v, managed by the app to %v\n", record.Type, record.Name, purpose)
+		return
+	}
+	color.New(color.FgYellow, color.Bold).Print("   !  ")
+	color.New(color.FgWhite).Printf("Add to your DNS to %v\n", purpose)
+	color.New(color.FgHiBlack).Printf("      %-6s %v\n", record.Type, record.Name)
+	color.New(color.FgHiBlack).Printf("      %-6s %v\n", "", record.Value)
+}
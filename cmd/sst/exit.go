@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/sst/ion/pkg/project"
+	"github.com/sst/ion/pkg/project/provider"
+)
+
+// exit codes are part of the cli contract so scripts can tell failures apart
+const (
+	exitDeployFailed = 1
+	exitLocked       = 2
+	exitConfig       = 3
+	exitCredentials  = 4
+	exitCancelled    = 5
+	// same as coreutils timeout
+	exitTimedOut = 124
+)
+
+// returned by commands whose progress output already reported the failure
+var errProgressFailed = fmt.Errorf("progress failed")
+var errLocked = fmt.Errorf("concurrent update")
+var errCancelled = fmt.Errorf("cancelled")
+
+// userError is a mistake the user can fix, like a usage error or a frozen
+// stage, it is shown without a crash report
+type userError struct {
+	message string
+}
+
+func (e *userError) Error() string {
+	return e.message
+}
+
+func userErrorf(format string, args ...interface{}) error {
+	return &userError{message: fmt.Sprintf(format, args...)}
+}
+
+func exitCode(err error) int {
+	var configErr *project.ConfigError
+	var credentialsErr *project.CredentialsError
+	var lockErr *provider.LockExistsError
+	var user *userError
+	var removed *project.StageRemovedError
+	switch {
+	case errors.Is(err, errLocked), errors.As(err, &lockErr):
+		return exitLocked
+	case errors.Is(err, errCancelled):
+		return exitCancelled
+	// frozen stages, removed stages and usage errors are fixed in the config
+	// or the command line
+	case errors.As(err, &configErr), errors.As(err, &user), errors.As(err, &removed):
+		return exitConfig
+	case errors.As(err, &credentialsErr):
+		return exitCredentials
+	}
+	return exitDeployFailed
+}
+
+// reported is true if the error was already shown by the progress output
+func reported(err error) bool {
+	return errors.Is(err, errProgressFailed) || errors.Is(err, errLocked) || errors.Is(err, errCancelled)
+}
+
+// expected is true for errors the user can fix, they do not need a crash report
+func expected(err error) bool {
+	code := exitCode(err)
+	return code == exitConfig || code == exitCredentials || code == exitLocked
+}
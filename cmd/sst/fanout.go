@@ -0,0 +1,300 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/fatih/color"
+	"github.com/sst/ion/pkg/project"
+)
+
+var fanoutColors = []color.Attribute{
+	color.FgCyan,
+	color.FgMagenta,
+	color.FgBlue,
+	color.FgYellow,
+	color.FgGreen,
+}
+
+type parallelResult struct {
+	Name    string
+	Summary *ProgressSummary
+	Error   error
+}
+
+// fanout runs the command against every configured target in parallel,
+// progress lines are prefixed with the target they belong to and a summary
+// of every target is printed at the end
+func fanout(p *project.Project, mode ProgressMode, run func(target *project.Project) (project.StackEventStream, error), after parallelAfter) (bool, error) {
+	targets, err := p.FanoutTargets()
+	if err != nil {
+		return false, err
+	}
+	if len(targets) == 0 {
+		return false, userErrorf("No fanout targets found")
+	}
+	names := []string{}
+	for _, target := range targets {
+		names = append(names, target.Name())
+	}
+	fmt.Println(progressStatus(mode), len(targets), "targets")
+	return parallel(mode, names, p.App().Fanout.Concurrency, func(i int) (*project.Project, error) {
+		return p.Target(targets[i])
+	}, run, after), nil
+}
+
+// parallelAfter runs once the update of a child succeeded, an error fails
+// the child. Its output goes through printLine so it is prefixed like the
+// progress of the child.
+type parallelAfter func(child *project.Project, printLine func(string)) error
+
+// parallel loads and runs every named copy of the project at the same time,
+// at most concurrency at once or all of them if it is 0. after is optional.
+func parallel(mode ProgressMode, names []string, concurrency int, load func(i int) (*project.Project, error), run func(child *project.Project) (project.StackEventStream, error), after parallelAfter) bool {
+	width := 0
+	for _, name := range names {
+		width = max(width, len(name))
+	}
+
+	if concurrency <= 0 {
+		concurrency = len(names)
+	}
+	slots := make(chan struct{}, concurrency)
+	results := make([]parallelResult, len(names))
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+
+	// children whose update is in flight, interrupts and the --timeout
+	// deadline are forwarded to all of them
+	active := map[*project.Project]bool{}
+	var activeLock sync.Mutex
+	defer interruptibleAll(func() []*project.Project {
+		activeLock.Lock()
+		defer activeLock.Unlock()
+		result := []*project.Project{}
+		for child := range active {
+			result = append(result, child)
+		}
+		return result
+	})()
+
+	fmt.Println()
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			results[i].Name = name
+			// children still waiting for a slot are not started once the
+			// run was interrupted
+			if userCancelled.Load() || timedOut.Load() {
+				results[i].Error = errCancelled
+				return
+			}
+
+			prefix := color.New(fanoutColors[i%len(fanoutColors)], color.Bold).Sprintf("%-*s", width, name)
+			printLine := func(line string) {
+				lock.Lock()
+				defer lock.Unlock()
+				fmt.Println(prefix + "  " + line)
+			}
+
+			child, err := load(i)
+			if err != nil {
+				results[i].Error = err
+				printLine(color.RedString("%v", err))
+				return
+			}
+			activeLock.Lock()
+			active[child] = true
+			activeLock.Unlock()
+			events, err := run(child)
+			if err != nil {
+				activeLock.Lock()
+				delete(active, child)
+				activeLock.Unlock()
+				results[i].Error = err
+				printLine(color.RedString("%v", err))
+				return
+			}
+
+			reducer := newProgressReducer(mode)
+			unchanged := 0
+			for evt := range events {
+				if evt.StdOutEvent != nil {
+					printLine(evt.StdOutEvent.Text)
+					continue
+				}
+				for _, item := range reducer.Reduce(evt) {
+					if !progressFilter.allows(item) {
+						continue
+					}
+					if concise && item.Label == "Skipped" {
+						unchanged++
+						continue
+					}
+					line := color.New(item.Color, color.Bold).Sprint("|  ") +
+						color.HiBlackString("%-11s %v", item.Label, formatURN(item.URN))
+					if item.Duration != 0 {
+						line += color.HiBlackString(" (%v)", formatDuration(item.Duration))
+					}
+					if item.Message != "" {
+						line += color.HiBlackString(" %v", item.Message)
+					}
+					printLine(line)
+				}
+			}
+			activeLock.Lock()
+			delete(active, child)
+			activeLock.Unlock()
+			if unchanged > 0 && progressFilter.allows(unchangedProgress(unchanged)) {
+				printLine(color.HiBlackString("|  %-11s %v resources", "Unchanged", formatCount(unchanged)))
+			}
+			reducer.Summary.Duration = reducer.now().Sub(reducer.start)
+			results[i].Summary = &reducer.Summary
+			succeeded := !reducer.Summary.ConcurrentUpdate && len(reducer.Summary.Errors) == 0
+			if after != nil && succeeded && !userCancelled.Load() && !timedOut.Load() {
+				err := after(child, printLine)
+				if err != nil {
+					results[i].Error = err
+					printLine(color.RedString("%v", err))
+				}
+			}
+		}(i, name)
+	}
+	wg.Wait()
+
+	ok := true
+	fmt.Println()
+	for _, result := range results {
+		failed := result.Error != nil ||
+			result.Summary.ConcurrentUpdate ||
+			len(result.Summary.Errors) > 0 ||
+			(failOnWarn && len(result.Summary.Warnings) > 0)
+		if !failed {
+			color.New(color.FgGreen, color.Bold).Print("✔  ")
+			color.New(color.FgWhite).Printf("%-*s", width, result.Name)
+			color.New(color.FgHiBlack).Printf("  %v", formatDuration(result.Summary.Duration))
+			if len(result.Summary.Warnings) > 0 {
+				color.New(color.FgYellow).Printf("  %v warnings", len(result.Summary.Warnings))
+			}
+			fmt.Println()
+			continue
+		}
+		ok = false
+		color.New(color.FgRed, color.Bold).Print("❌ ")
+		color.New(color.FgWhite).Printf("%-*s", width, result.Name)
+		fmt.Println()
+		switch {
+		case errors.Is(result.Error, errCancelled):
+			color.New(color.FgHiBlack).Println("   Cancelled before it started")
+		case result.Error != nil:
+			color.New(color.FgHiBlack).Printf("   %v\n", result.Error)
+		case result.Summary.ConcurrentUpdate:
+			color.New(color.FgHiBlack).Println("   Concurrent update detected")
+		}
+		if result.Summary != nil {
+			for _, status := range result.Summary.Errors {
+				if status.URN != "" {
+					color.New(color.FgHiBlack).Printf("   %v: %v\n", formatURN(status.URN), status.Error)
+					continue
+				}
+				color.New(color.FgHiBlack).Printf("   %v\n", status.Error)
+			}
+		}
+	}
+	return ok
+}
+
+// parallelErr is what a command returns after running in parallel
+func parallelErr(ok bool) error {
+	switch {
+	case ok:
+		return nil
+	case userCancelled.Load():
+		return errCancelled
+	}
+	return errProgressFailed
+}
+
+// deployParallel deploys a child and runs the same gates as a single deploy
+// once it succeeded: the output diff, migrations, seeds, health checks and
+// the smoke test. A failed gate fails the child but never rolls it back.
+// before runs ahead of the deploy and can refuse it.
+func deployParallel(before func(child *project.Project) error) (func(child *project.Project) (project.StackEventStream, error), parallelAfter) {
+	var previous sync.Map
+	run := func(child *project.Project) (project.StackEventStream, error) {
+		if before != nil {
+			err := before(child)
+			if err != nil {
+				return nil, err
+			}
+		}
+		outputs, err := child.Stack.Outputs()
+		if err != nil {
+			return nil, err
+		}
+		previous.Store(child, outputs)
+		return child.Stack.Deploy()
+	}
+	after := func(child *project.Project, printLine func(string)) error {
+		err := child.Stack.ClearDrift()
+		if err != nil {
+			return err
+		}
+		next, err := child.Stack.Outputs()
+		if err != nil {
+			return err
+		}
+		old, _ := previous.Load(child)
+		for _, diff := range project.DiffOutputs(old.(map[string]interface{}), next) {
+			printLine(outputDiffLine(diff))
+		}
+
+		if child.App().Migrations != nil {
+			_, err := child.Migrate(func(name string) {
+				printLine(color.New(color.FgCyan, color.Bold).Sprint("➜  ") + "Migrating: " + name)
+			})
+			if err != nil {
+				return err
+			}
+		}
+		seed, err := child.ShouldSeed()
+		if err != nil {
+			return err
+		}
+		if seed {
+			err := child.Seed(func(command string) {
+				printLine(color.New(color.FgCyan, color.Bold).Sprint("➜  ") + "Seeding: " + command)
+			})
+			if err != nil {
+				return err
+			}
+		}
+		health, ok, err := child.CheckHealth()
+		if err != nil {
+			return err
+		}
+		for _, result := range health {
+			if result.Healthy() {
+				printLine(color.New(color.FgGreen, color.Bold).Sprint("|  ") + color.HiBlackString("%-11s %v (%s)", "Healthy", result.URL, result.Latency))
+				continue
+			}
+			printLine(color.New(color.FgRed, color.Bold).Sprint("|  ") + color.HiBlackString("%-11s %v", "Unhealthy", result.Check.Output) + color.RedString(" %v", result.Error))
+		}
+		if !ok {
+			return fmt.Errorf("Health checks failed")
+		}
+		code, err := child.Smoke()
+		if err != nil {
+			return err
+		}
+		if code > 0 {
+			return fmt.Errorf("Smoke test failed with exit code %v", code)
+		}
+		return nil
+	}
+	return run, after
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"slices"
+	"strings"
+
+	cli "github.com/urfave/cli/v2"
+)
+
+// labels of the progress lines every operation produces
+var opLabels = map[string][]string{
+	"create":  {"create", "creating", "created"},
+	"update":  {"update", "updating", "updated"},
+	"replace": {"replace"},
+	"delete":  {"delete", "deleting", "deleted"},
+	"refresh": {"refreshing", "refreshed"},
+	"same":    {"skipped"},
+}
+
+var knownLabels = []string{"status", "shifting", "building", "bundled", "env"}
+
+// eventFilter decides which progress lines are rendered, errors are always
+// shown
+type eventFilter struct {
+	show map[string]bool
+	hide map[string]bool
+}
+
+// set from the --show and --hide flags
+var progressFilter *eventFilter
+
+var filterFlags = []cli.Flag{
+	&cli.StringSliceFlag{
+		Name:  "show",
+		Usage: "Only show these kinds of progress lines, like skipped,refreshed or ops=create,delete",
+	},
+	&cli.StringSliceFlag{
+		Name:  "hide",
+		Usage: "Hide these kinds of progress lines, like skipped,refreshed or ops=same",
+	},
+}
+
+// parseEventFilter reads values like "skipped,refreshed" or
+// "ops=create,delete", categories are progress labels and ops expand to every
+// label of the operation
+func parseEventFilter(show []string, hide []string) (*eventFilter, error) {
+	if len(show) == 0 && len(hide) == 0 {
+		return nil, nil
+	}
+	result := &eventFilter{}
+	var err error
+	result.show, err = parseCategories(show)
+	if err != nil {
+		return nil, err
+	}
+	result.hide, err = parseCategories(hide)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func parseCategories(input []string) (map[string]bool, error) {
+	if len(input) == 0 {
+		return nil, nil
+	}
+	result := map[string]bool{}
+	for _, value := range input {
+		ops := false
+		value = strings.ToLower(strings.TrimSpace(value))
+		if rest, ok := strings.CutPrefix(value, "ops="); ok {
+			ops = true
+			value = rest
+		}
+		for _, item := range strings.Split(value, ",") {
+			item = strings.TrimSpace(item)
+			if item == "" {
+				continue
+			}
+			if labels, ok := opLabels[item]; ok && ops {
+				for _, label := range labels {
+					result[label] = true
+				}
+				continue
+			}
+			if ops {
+				return nil, userErrorf("Unknown operation %q, use create, update, replace, delete, refresh or same", item)
+			}
+			if !isLabel(item) {
+				return nil, userErrorf("Unknown event category %q", item)
+			}
+			result[item] = true
+		}
+	}
+	return result, nil
+}
+
+func isLabel(input string) bool {
+	if slices.Contains(knownLabels, input) {
+		return true
+	}
+	for _, labels := range opLabels {
+		if slices.Contains(labels, input) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *eventFilter) allows(progress Progress) bool {
+	if f == nil || progress.Label == "Error" {
+		return true
+	}
+	label := strings.ToLower(progress.Label)
+	// the collapsed line stands in for the skipped resources
+	if label == "unchanged" {
+		label = "skipped"
+	}
+	if f.show != nil && !f.show[label] {
+		return false
+	}
+	return !f.hide[label]
+}
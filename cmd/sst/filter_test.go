@@ -0,0 +1,86 @@
+package main
+
+import (
+	"testing"
+
+	cli "github.com/urfave/cli/v2"
+)
+
+// parseFlags runs the filter flags through a cli app set up like the sst one
+func parseFlags(t *testing.T, args ...string) *eventFilter {
+	t.Helper()
+	var result *eventFilter
+	app := &cli.App{
+		DisableSliceFlagSeparator: true,
+		Flags:                     filterFlags,
+		Action: func(c *cli.Context) error {
+			var err error
+			result, err = parseEventFilter(c.StringSlice("show"), c.StringSlice("hide"))
+			return err
+		},
+	}
+	err := app.Run(append([]string{"sst"}, args...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestEventFilterFlags(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		shown []string
+		// labels that must be filtered out
+		hidden []string
+	}{
+		{
+			name:   "ops",
+			args:   []string{"--show", "ops=create,delete"},
+			shown:  []string{"Creating", "Created", "Deleting", "Deleted", "Error"},
+			hidden: []string{"Updating", "Skipped", "Refreshed"},
+		},
+		{
+			name:   "labels",
+			args:   []string{"--show", "skipped,refreshed"},
+			shown:  []string{"Skipped", "Unchanged", "Refreshed"},
+			hidden: []string{"Created", "Deleting"},
+		},
+		{
+			name:   "repeated",
+			args:   []string{"--show", "skipped", "--show", "ops=update,replace"},
+			shown:  []string{"Skipped", "Updated", "Replace"},
+			hidden: []string{"Created", "Delete"},
+		},
+		{
+			name:   "hide",
+			args:   []string{"--hide", "ops=same,refresh"},
+			shown:  []string{"Created", "Status"},
+			hidden: []string{"Skipped", "Unchanged", "Refreshing", "Refreshed"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := parseFlags(t, tt.args...)
+			for _, label := range tt.shown {
+				if !filter.allows(Progress{Label: label}) {
+					t.Errorf("%v is hidden, want it shown", label)
+				}
+			}
+			for _, label := range tt.hidden {
+				if filter.allows(Progress{Label: label}) {
+					t.Errorf("%v is shown, want it hidden", label)
+				}
+			}
+		})
+	}
+}
+
+func TestEventFilterUnknown(t *testing.T) {
+	for _, input := range []string{"ops=created", "creatd"} {
+		_, err := parseEventFilter([]string{input}, nil)
+		if err == nil {
+			t.Errorf("parseEventFilter(%q) succeeded, want an error", input)
+		}
+	}
+}
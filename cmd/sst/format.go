@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// formatDuration renders durations the way people say them, 1m 32s instead
+// of 1m32.431s
+func formatDuration(d time.Duration) string {
+	switch {
+	case d < time.Second:
+		return fmt.Sprintf("%vms", d.Milliseconds())
+	case d < 10*time.Second:
+		return strconv.FormatFloat(d.Round(100*time.Millisecond).Seconds(), 'f', -1, 64) + "s"
+	case d < time.Minute:
+		return fmt.Sprintf("%vs", int(d.Round(time.Second).Seconds()))
+	case d < time.Hour:
+		d = d.Round(time.Second)
+		return fmt.Sprintf("%vm %vs", int(d.Minutes()), int(d.Seconds())%60)
+	}
+	d = d.Round(time.Minute)
+	return fmt.Sprintf("%vh %vm", int(d.Hours()), int(d.Minutes())%60)
+}
+
+// formatCount adds thousands separators, 12345 becomes 12,345
+func formatCount(n int) string {
+	digits := strconv.Itoa(n)
+	sign := ""
+	if n < 0 {
+		sign, digits = "-", digits[1:]
+	}
+	result := ""
+	for len(digits) > 3 {
+		result = "," + digits[len(digits)-3:] + result
+		digits = digits[:len(digits)-3]
+	}
+	return sign + digits + result
+}
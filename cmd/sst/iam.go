@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/sst/ion/pkg/project"
+)
+
+func printIamReport(report *project.IamReport, out string) {
+	fmt.Println()
+	color.New(color.FgCyan, color.Bold).Print("➜  ")
+	color.New(color.FgWhite, color.Bold).Printf("%-12s", "Policy:")
+	color.New(color.FgHiBlack).Printf("%v actions\n", len(report.Actions))
+	if out != "" {
+		printStatus("Written:", out)
+	}
+	for _, item := range report.Unknown {
+		color.New(color.FgYellow, color.Bold).Print("!  ")
+		color.New(color.FgHiBlack).Printf("%v has no known permissions, allowing its whole service\n", item)
+	}
+}
+
+func printPreflight(denied []string) {
+	fmt.Println()
+	if len(denied) == 0 {
+		color.New(color.FgGreen, color.Bold).Print("✔  ")
+		color.New(color.FgWhite).Println("Credentials can perform every planned operation")
+		return
+	}
+	color.New(color.FgRed, color.Bold).Print("❌ ")
+	color.New(color.FgWhite).Printf("Credentials are missing %v permissions\n", len(denied))
+	for _, action := range denied {
+		color.New(color.FgHiBlack).Printf("   %v\n", action)
+	}
+}
+
+func iamPolicyJSON(report *project.IamReport) ([]byte, error) {
+	return json.MarshalIndent(report.Policy, "", "  ")
+}
+
+func printIamJSON(report *project.IamReport, denied []string) error {
+	return printJSON(map[string]interface{}{
+		"policy":  report.Policy,
+		"unknown": report.Unknown,
+		"denied":  denied,
+	})
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/sst/ion/pkg/global"
+	"github.com/sst/ion/pkg/project"
+
+	cli "github.com/urfave/cli/v2"
+)
+
+// rotated to sst.log.1 when it grows past this size
+const maxLogSize = 10 * 1024 * 1024
+
+func pathLog() string {
+	cfgPath, err := project.Discover()
+	if err != nil {
+		return filepath.Join(global.ConfigDir(), "log", "sst.log")
+	}
+	return filepath.Join(filepath.Dir(cfgPath), ".sst", "log", "sst.log")
+}
+
+// setupLogging writes json logs at the configured level to .sst/log/sst.log,
+// stderr only gets warnings unless --verbose is set so the ui stays readable
+func setupLogging(c *cli.Context) error {
+	level := slog.LevelInfo
+	input := c.String("log-level")
+	if input == "" {
+		input = os.Getenv("SST_LOG")
+	}
+	if input != "" {
+		err := level.UnmarshalText([]byte(input))
+		if err != nil {
+			return userErrorf("Invalid log level %q, expected debug, info, warn or error", input)
+		}
+	}
+
+	stderrLevel := slog.LevelWarn
+	if c.Bool("verbose") {
+		stderrLevel = level
+	}
+	handlers := []slog.Handler{
+		slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+			Level: stderrLevel,
+		}),
+	}
+
+	file, err := openLog(pathLog())
+	if err == nil {
+		handlers = append(handlers, slog.NewJSONHandler(file, &slog.HandlerOptions{
+			Level: level,
+		}))
+	}
+	slog.SetDefault(slog.New(&multiHandler{handlers}).With("pid", os.Getpid()))
+	if err != nil {
+		slog.Warn("failed to open log file", "err", err)
+	}
+	return nil
+}
+
+func openLog(path string) (io.Writer, error) {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(path); err == nil && info.Size() > maxLogSize {
+		os.Rename(path, path+".1")
+	}
+	return os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+}
+
+type multiHandler struct {
+	handlers []slog.Handler
+}
+
+func (m *multiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, handler := range m.handlers {
+		if handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *multiHandler) Handle(ctx context.Context, record slog.Record) error {
+	for _, handler := range m.handlers {
+		if !handler.Enabled(ctx, record.Level) {
+			continue
+		}
+		err := handler.Handle(ctx, record.Clone())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *multiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	result := &multiHandler{}
+	for _, handler := range m.handlers {
+		result.handlers = append(result.handlers, handler.WithAttrs(attrs))
+	}
+	return result
+}
+
+func (m *multiHandler) WithGroup(name string) slog.Handler {
+	result := &multiHandler{}
+	for _, handler := range m.handlers {
+		result.handlers = append(result.handlers, handler.WithGroup(name))
+	}
+	return result
+}
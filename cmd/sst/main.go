@@ -1,118 +1,1549 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime/debug"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/fatih/color"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/sst/ion/internal/fs"
+	"github.com/sst/ion/pkg/console"
+	"github.com/sst/ion/pkg/daemon"
 	"github.com/sst/ion/pkg/global"
+	"github.com/sst/ion/pkg/ion"
+	"github.com/sst/ion/pkg/migrate"
 	"github.com/sst/ion/pkg/project"
+	"github.com/sst/ion/pkg/project/provider"
+	"github.com/sst/ion/pkg/telemetry"
 
 	cli "github.com/urfave/cli/v2"
 )
 
 var version = "dev"
 
+// how long a timed out update gets to finish in-flight operations before it
+// is killed
+const timeoutGrace = 5 * time.Minute
+
+// set from the --timeout flag
+var timeout time.Duration
+
+// set from the hidden --inject-failure flag
+var injectFailure string
+var timedOut atomic.Bool
+
+// set by the first interrupt, the update fails as cancelled instead of failed
+var userCancelled atomic.Bool
+
 func main() {
 	app := &cli.App{
 		Name:        "sst",
 		Description: "deploy anything",
-		Flags: []cli.Flag{
+		// values like ops=create,delete hold commas, slice flags are repeated
+		// instead
+		DisableSliceFlagSeparator: true,
+		Flags: append([]cli.Flag{
 			&cli.BoolFlag{
 				Name: "verbose",
 			},
-		},
+			&cli.BoolFlag{
+				Name:  "fail-on-warn",
+				Usage: "Treat warnings as failures",
+			},
+			&cli.BoolFlag{
+				Name:  "auto-heal",
+				Usage: "Reconcile operations left pending by a crashed update without asking",
+			},
+			&cli.StringFlag{
+				Name:  "log-level",
+				Usage: "Level of the logs written to .sst/log/sst.log: debug, info, warn or error, defaults to SST_LOG or info",
+			},
+			&cli.DurationFlag{
+				Name:  "timeout",
+				Usage: "Cancel deploy, remove and refresh gracefully when they run longer than this, like 30m",
+			},
+			&cli.StringFlag{
+				Name:  "output",
+				Usage: "Progress output format: tty, plain or json",
+			},
+			&cli.BoolFlag{
+				Name:  "status-updates",
+				Value: true,
+				Usage: "Show status messages from providers while slow resources are being updated",
+			},
+			&cli.BoolFlag{
+				Name:  "concise",
+				Value: true,
+				Usage: "Collapse unchanged resources into a single line, --verbose shows all of them",
+			},
+			&cli.StringFlag{
+				Name:   "inject-failure",
+				Hidden: true,
+				Usage:  "Fail the update right before it reaches the resource with this urn, name or type",
+			},
+		}, filterFlags...),
 		Before: func(c *cli.Context) error {
-			level := slog.LevelWarn
-			if c.Bool("verbose") {
-				level = slog.LevelInfo
+			err := setupLogging(c)
+			if err != nil {
+				return err
 			}
-			slog.SetDefault(
-				slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
-					Level: level,
-				})),
-			)
+			slog.Info("running command", "command", sanitizeCommand(c.App, os.Args[1:]), "version", version)
 
 			if global.NeedsPlugins() {
 				fmt.Println("new installation, installing dependencies...")
-				err := global.InstallPlugins()
+				err = global.InstallPlugins()
 				if err != nil {
 					return err
 				}
 			}
+			outputFormat = c.String("output")
+			failOnWarn = c.Bool("fail-on-warn")
+			concise = c.Bool("concise") && !c.Bool("verbose")
+			statusUpdates = c.Bool("status-updates")
+			timeout = c.Duration("timeout")
+			injectFailure = c.String("inject-failure")
+			progressFilter, err = parseEventFilter(c.StringSlice("show"), c.StringSlice("hide"))
+			if err != nil {
+				return err
+			}
+			if outputFormat == "json" {
+				return nil
+			}
 			color.New(color.FgCyan, color.Bold).Print("SST ❍ ion " + version + "  ")
 			color.New(color.FgHiBlack).Print("ready!\n")
+			if telemetry.Notice() {
+				color.New(color.FgHiBlack).Println("SST collects anonymous usage data, run `sst telemetry disable` to opt out")
+			}
 			return nil
 		},
 		Commands: []*cli.Command{
 			{
-				Name:  "deploy",
+				Name: "deploy",
+				Flags: append([]cli.Flag{
+					&cli.BoolFlag{
+						Name:  "watch",
+						Usage: "Keep running and redeploy when files change, failed deploys are reported and retried on the next change",
+					},
+					&cli.StringFlag{
+						Name:  "override-freeze",
+						Usage: "Deploy during a freeze window, the reason is recorded in the audit log",
+					},
+					&cli.BoolFlag{
+						Name:  "fanout",
+						Usage: "Deploy to every account and region configured in fanout",
+					},
+					&cli.BoolFlag{
+						Name:  "notify",
+						Usage: "Show a desktop notification when the deploy finishes",
+					},
+					&cli.StringFlag{
+						Name:  "fail-on-size",
+						Usage: "Fail when a function zip is larger than this, like 50MB",
+					},
+				}, stageFlags...),
+				Action: func(cli *cli.Context) error {
+					started := time.Now()
+					stages, err := stageNames(cli)
+					if err != nil {
+						return err
+					}
+					if !cli.Bool("watch") && !cli.Bool("fanout") && !cli.IsSet("notify") && !cli.IsSet("fail-on-size") && len(stages) == 0 && injectFailure == "" {
+						if ok, err := runDaemon("up", ProgressModeDeploy); ok {
+							return err
+						}
+					}
+
+					p, err := initStageProject(stages)
+					if err != nil {
+						return err
+					}
+					if len(stages) > 1 {
+						if cli.Bool("watch") || cli.Bool("fanout") {
+							return userErrorf("Deploying several stages can not be combined with --watch or --fanout")
+						}
+						run, after := deployParallel(func(child *project.Project) error {
+							err := setBundleLimit(cli, child)
+							if err != nil {
+								return err
+							}
+							window, err := child.Frozen(time.Now())
+							if err != nil {
+								return err
+							}
+							if window != nil {
+								reason := cli.String("override-freeze")
+								if reason == "" {
+									return userErrorf("Stage %v is frozen (%v), pass --override-freeze <reason> to deploy anyway", child.App().Stage, window)
+								}
+								return child.Audit("override-freeze", reason)
+							}
+							return nil
+						})
+						ok := multiStage(p, ProgressModeDeploy, stages, run, after)
+						return parallelErr(ok)
+					}
+					err = setBundleLimit(cli, p)
+					if err != nil {
+						return err
+					}
+					printHeader(p.App())
+
+					window, err := p.Frozen(time.Now())
+					if err != nil {
+						return err
+					}
+					if window != nil {
+						reason := cli.String("override-freeze")
+						if reason == "" {
+							return userErrorf("Stage %v is frozen (%v), pass --override-freeze <reason> to deploy anyway", p.App().Stage, window)
+						}
+						err = p.Audit("override-freeze", reason)
+						if err != nil {
+							return err
+						}
+						color.New(color.FgYellow, color.Bold).Print("!  ")
+						color.New(color.FgWhite).Printf("Overriding freeze window %v\n\n", window)
+					}
+
+					if p.IsProtected() {
+						if git := p.Git(); git != nil && git.Dirty {
+							color.New(color.FgYellow, color.Bold).Print("!  ")
+							color.New(color.FgWhite).Printf("Deploying uncommitted changes to protected stage %v\n\n", p.App().Stage)
+						}
+					}
+
+					if cli.Bool("fanout") {
+						run, after := deployParallel(nil)
+						ok, err := fanout(p, ProgressModeDeploy, run, after)
+						if err != nil {
+							return err
+						}
+						if shouldNotify(cli, p) {
+							notify(p, "Deploy", ok, started)
+						}
+						return parallelErr(ok)
+					}
+
+					// deploy runs one update and the gates after it, ok is false
+					// if a gate failed
+					deploy := func() (bool, error) {
+						previous, err := p.Stack.Outputs()
+						if err != nil {
+							return false, err
+						}
+
+						err = recoverStack(p, cli.Bool("auto-heal"))
+						if err != nil {
+							return false, err
+						}
+
+						defer interruptible(p)()
+
+						events, err := p.Stack.Deploy()
+						if err != nil {
+							return false, err
+						}
+						result := progress(ProgressModeDeploy, events)
+						if err := result.Err(); err != nil {
+							return false, err
+						}
+
+						err = p.Stack.ClearDrift()
+						if err != nil {
+							return false, err
+						}
+						next, err := p.Stack.Outputs()
+						if err != nil {
+							return false, err
+						}
+						printOutputDiff(project.DiffOutputs(previous, next))
+
+						outdated, err := p.TypesOutdated()
+						if err != nil {
+							return false, err
+						}
+						if outdated {
+							color.New(color.FgYellow, color.Bold).Print("\n!  ")
+							color.New(color.FgWhite).Println("sst-env.d.ts is out of date, run `sst types` to regenerate it")
+						}
+
+						migrated, err := runMigrations(p)
+						if err != nil {
+							return false, err
+						}
+						// a half applied schema can not be rolled back by
+						// redeploying, so failed migrations only fail the deploy
+						ok := migrated
+						if ok {
+							ok, err = seedStage(p, false)
+							if err != nil {
+								return false, err
+							}
+						}
+						// only the gate that failed decides whether to roll back
+						failed := ""
+						if ok {
+							ok, err = checkHealth(p)
+							if err != nil {
+								return false, err
+							}
+							if !ok {
+								failed = "health"
+							}
+						}
+						if ok {
+							ok, err = smokeTest(p)
+							if err != nil {
+								return false, err
+							}
+							if !ok {
+								failed = "tests"
+							}
+						}
+						if failed != "" && p.App().RollbackOnFailure(failed) && !cli.Bool("watch") {
+							err = rollback(p)
+							if err != nil {
+								return false, err
+							}
+						}
+						return ok, nil
+					}
+
+					if !cli.Bool("watch") {
+						ok, err := deploy()
+						if shouldNotify(cli, p) {
+							notify(p, "Deploy", ok && err == nil, started)
+						}
+						if err != nil {
+							return err
+						}
+						if !ok {
+							return errProgressFailed
+						}
+						return nil
+					}
+
+					// every change evaluates sst.config.ts again in the same node
+					// process, the pulumi engine still starts once per update
+					changes, err := fs.Watch(p.PathRoot(), 200*time.Millisecond, []string{".sst", ".git", "node_modules"})
+					if err != nil {
+						return err
+					}
+					for {
+						_, err := deploy()
+						if errors.Is(err, errCancelled) {
+							return err
+						}
+						// failures are shown and the next change deploys again
+						if err != nil && !reported(err) {
+							color.New(color.FgRed, color.Bold).Print("\n❌")
+							color.New(color.FgWhite, color.Bold).Println(" " + err.Error())
+						}
+
+						for {
+							color.New(color.FgHiBlack).Print("\n   Watching for changes...\n")
+							file := <-changes
+							rel, _ := filepath.Rel(p.PathRoot(), file)
+							color.New(color.FgCyan, color.Bold).Print("\n➜  ")
+							color.New(color.FgWhite).Println("Changed", rel)
+							fmt.Println()
+							err := p.Reload()
+							if err == nil {
+								break
+							}
+							color.New(color.FgRed, color.Bold).Print("❌")
+							color.New(color.FgWhite, color.Bold).Println(" " + err.Error())
+						}
+					}
+				},
+			},
+			{
+				Name: "remove",
+				Flags: append([]cli.Flag{
+					&cli.BoolFlag{
+						Name:  "notify",
+						Usage: "Show a desktop notification when the removal finishes",
+					},
+				}, stageFlags...),
+				Action: func(cli *cli.Context) error {
+					started := time.Now()
+					stages, err := stageNames(cli)
+					if err != nil {
+						return err
+					}
+					if !cli.IsSet("notify") && len(stages) == 0 {
+						if ok, err := runDaemon("destroy", ProgressModeRemove); ok {
+							return err
+						}
+					}
+
+					p, err := initStageProject(stages)
+					if err != nil {
+						return err
+					}
+					if len(stages) > 1 {
+						color.New(color.FgYellow, color.Bold).Print("!  ")
+						color.New(color.FgWhite).Printf("Remove every resource in %v? (y/N) ", strings.Join(stages, ", "))
+						var answer string
+						fmt.Scanln(&answer)
+						if strings.ToLower(answer) != "y" {
+							return nil
+						}
+						ok := multiStage(p, ProgressModeRemove, stages, func(child *project.Project) (project.StackEventStream, error) {
+							return child.Stack.Remove()
+						}, func(child *project.Project, printLine func(string)) error {
+							return child.MarkRemoved()
+						})
+						return parallelErr(ok)
+					}
+					printHeader(p.App())
+
+					err = recoverStack(p, cli.Bool("auto-heal"))
+					if err != nil {
+						return err
+					}
+
+					defer interruptible(p)()
+
+					events, err := p.Stack.Remove()
+					if err != nil {
+						return err
+					}
+					result := progress(ProgressModeRemove, events)
+					if shouldNotify(cli, p) {
+						notify(p, "Remove", result.OK(), started)
+					}
+					if err := result.Err(); err != nil {
+						return err
+					}
+					err = p.MarkRemoved()
+					if err != nil {
+						return err
+					}
+
+					for evt := range events {
+						if evt.ResourcePreEvent != nil {
+							slog.Debug("got op", "op", evt.ResourcePreEvent.Metadata.Op)
+						}
+					}
+					return nil
+				},
+			},
+			{
+				Name: "refresh",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "interactive",
+						Usage: "Choose how to handle each drifted resource",
+					},
+				},
+				Action: func(cli *cli.Context) error {
+					interactive := cli.Bool("interactive")
+					if !interactive {
+						if ok, err := runDaemon("refresh", ProgressModeRefresh); ok {
+							return err
+						}
+					}
+
+					p, err := initProject()
+					if err != nil {
+						return err
+					}
+					printHeader(p.App())
+
+					err = recoverStack(p, cli.Bool("auto-heal"))
+					if err != nil {
+						return err
+					}
+
+					var snapshot project.CheckpointSnapshot
+					if interactive {
+						snapshot, err = p.Stack.Snapshot()
+						if err != nil {
+							return err
+						}
+					}
+
+					defer interruptible(p)()
+
+					events, err := p.Stack.Refresh()
+					if err != nil {
+						return err
+					}
+
+					drifted := []string{}
+					tee := make(project.StackEventStream)
+					go func() {
+						defer close(tee)
+						for evt := range events {
+							if evt.ResOutputsEvent != nil &&
+								(evt.ResOutputsEvent.Metadata.Op == apitype.OpUpdate || evt.ResOutputsEvent.Metadata.Op == apitype.OpDelete) {
+								drifted = append(drifted, evt.ResOutputsEvent.Metadata.URN)
+							}
+							tee <- evt
+						}
+					}()
+					result := progress(ProgressModeRefresh, tee)
+
+					for evt := range tee {
+						if evt.ResourcePreEvent != nil {
+							slog.Debug("got op", "op", evt.ResourcePreEvent.Metadata.Op)
+						}
+					}
+					if err := result.Err(); err != nil {
+						return err
+					}
+					if !interactive || len(drifted) == 0 {
+						return nil
+					}
+
+					fmt.Println()
+					correct := []string{}
+					for _, urn := range drifted {
+						for {
+							color.New(color.FgYellow, color.Bold).Print("?  ")
+							color.New(color.FgWhite).Print(formatURN(urn))
+							color.New(color.FgHiBlack).Print(" drifted, [a]ccept into state or [c]orrect on next deploy: ")
+							var answer string
+							fmt.Scanln(&answer)
+							if answer == "a" {
+								break
+							}
+							if answer == "c" {
+								correct = append(correct, urn)
+								break
+							}
+						}
+					}
+
+					err = p.Stack.Restore(snapshot, correct)
+					if err != nil {
+						return err
+					}
+					return p.Stack.MarkDrift(correct)
+				},
+			},
+			{
+				Name:  "diff",
+				Usage: "Preview the changes a deploy would make, works with read-only credentials",
+				Flags: append([]cli.Flag{
+					&cli.StringFlag{
+						Name:  "fail-on-size",
+						Usage: "Fail when a function zip is larger than this, like 50MB",
+					},
+				}, stageFlags...),
+				Action: func(cli *cli.Context) error {
+					stages, err := stageNames(cli)
+					if err != nil {
+						return err
+					}
+					p, err := initStageProject(stages)
+					if err != nil {
+						return err
+					}
+					if len(stages) > 1 {
+						ok := multiStage(p, ProgressModeDiff, stages, func(child *project.Project) (project.StackEventStream, error) {
+							err := setBundleLimit(cli, child)
+							if err != nil {
+								return nil, err
+							}
+							return child.Stack.Preview()
+						}, nil)
+						return parallelErr(ok)
+					}
+					err = setBundleLimit(cli, p)
+					if err != nil {
+						return err
+					}
+					printHeader(p.App())
+					printReadOnly(p)
+
+					events, err := p.Stack.Preview()
+					if err != nil {
+						return err
+					}
+					return progress(ProgressModeDiff, events).Err()
+				},
+			},
+			{
+				Name:  "resources",
+				Usage: "Browse the resources deployed to the stage",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "list",
+						Usage: "List the deployed resources",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "type",
+								Usage: "Only list resources of this type, like aws:s3/bucketV2:BucketV2, its short form aws:s3:Bucket, or a prefix like aws:s3",
+							},
+							&cli.StringFlag{
+								Name:  "grep",
+								Usage: "Only list resources whose urn or id contains this",
+							},
+						},
+						Action: func(cli *cli.Context) error {
+							p, err := initProject()
+							if err != nil {
+								return err
+							}
+							resources, err := p.Resources(project.ResourceFilter{
+								Type: cli.String("type"),
+								Grep: cli.String("grep"),
+							})
+							if err != nil {
+								return err
+							}
+							if outputFormat == "json" {
+								return printJSON(resources)
+							}
+							printHeader(p.App())
+							printResources(resources)
+							return nil
+						},
+					},
+					{
+						Name:      "show",
+						Usage:     "Show the inputs and outputs of a deployed resource",
+						ArgsUsage: "<urn>",
+						Action: func(cli *cli.Context) error {
+							if cli.Args().Len() != 1 {
+								return userErrorf("Usage: sst resources show <urn>")
+							}
+							p, err := initProject()
+							if err != nil {
+								return err
+							}
+							resource, err := p.Resource(cli.Args().First())
+							if err != nil {
+								return err
+							}
+							if outputFormat == "json" {
+								return printJSON(resource)
+							}
+							printHeader(p.App())
+							return printResource(resource)
+						},
+					},
+				},
+			},
+			{
+				Name:  "graph",
+				Usage: "Export the resource dependency graph of the stage",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "format",
+						Usage: "Format of the graph, dot, mermaid or json",
+						Value: "dot",
+					},
+					&cli.BoolFlag{
+						Name:  "plan",
+						Usage: "Build the graph from a preview of the next deploy instead of the deployed state",
+					},
+				},
+				Action: func(cli *cli.Context) error {
+					format := cli.String("format")
+					if format != "dot" && format != "mermaid" && format != "json" {
+						return userErrorf("Unknown graph format %q, use dot, mermaid or json", format)
+					}
+					p, err := initProject()
+					if err != nil {
+						return err
+					}
+					var graph *project.Graph
+					if cli.Bool("plan") {
+						// the graph is written to stdout, so no read-only notice
+						if _, err := p.ReadOnly(); err != nil {
+							slog.Warn("failed to check write access", "err", err)
+						}
+						plan, err := p.Stack.Plan()
+						if err != nil {
+							return err
+						}
+						graph = project.PlanGraph(plan)
+					} else {
+						graph, err = p.Graph()
+						if err != nil {
+							return err
+						}
+					}
+					switch format {
+					case "json":
+						return printJSON(graph)
+					case "mermaid":
+						fmt.Print(graph.Mermaid())
+					default:
+						fmt.Print(graph.Dot())
+					}
+					return nil
+				},
+			},
+			{
+				Name:  "domains",
+				Usage: "Show the certificate validation status and DNS records of custom domains",
+				Action: func(cli *cli.Context) error {
+					p, err := initProject()
+					if err != nil {
+						return err
+					}
+					domains, err := p.Domains()
+					if err != nil {
+						return err
+					}
+					if outputFormat == "json" {
+						return printJSON(domains)
+					}
+					printHeader(p.App())
+					printDomains(domains)
+					return nil
+				},
+			},
+			{
+				Name:  "seed",
+				Usage: "Run the seed commands against the stage",
+				Action: func(cli *cli.Context) error {
+					p, err := initProject()
+					if err != nil {
+						return err
+					}
+					printHeader(p.App())
+					if p.IsProtected() {
+						color.New(color.FgYellow, color.Bold).Print("!  ")
+						color.New(color.FgWhite).Printf("%v is a protected stage, seed it anyway? (y/N) ", p.App().Stage)
+						var answer string
+						fmt.Scanln(&answer)
+						if strings.ToLower(answer) != "y" {
+							return nil
+						}
+					}
+					ok, err := seedStage(p, true)
+					if err != nil {
+						return err
+					}
+					if !ok {
+						return errProgressFailed
+					}
+					return nil
+				},
+			},
+			{
+				Name:  "migrations",
+				Usage: "Show the database migrations applied to the stage",
+				Action: func(cli *cli.Context) error {
+					p, err := initProject()
+					if err != nil {
+						return err
+					}
+					applied, err := p.Migrations()
+					if err != nil {
+						return err
+					}
+					pending, err := p.PendingMigrations()
+					if err != nil {
+						return err
+					}
+					if outputFormat == "json" {
+						return printJSON(map[string]interface{}{
+							"applied": applied,
+							"pending": pending,
+						})
+					}
+					printHeader(p.App())
+					printMigrations(applied, pending)
+					return nil
+				},
+				Subcommands: []*cli.Command{
+					{
+						Name:  "run",
+						Usage: "Apply the pending migrations without deploying",
+						Action: func(cli *cli.Context) error {
+							p, err := initProject()
+							if err != nil {
+								return err
+							}
+							printHeader(p.App())
+							ok, err := runMigrations(p)
+							if err != nil {
+								return err
+							}
+							if !ok {
+								return errProgressFailed
+							}
+							return nil
+						},
+					},
+				},
+			},
+			{
+				Name:  "trigger",
+				Usage: "Invoke the functions behind a cron or queue with a synthesized event",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "cron",
+						Usage:     "Run the targets of a schedule now",
+						ArgsUsage: "<name>",
+						Action: func(cli *cli.Context) error {
+							if cli.Args().Len() != 1 {
+								return userErrorf("Usage: sst trigger cron <name>")
+							}
+							p, err := initProject()
+							if err != nil {
+								return err
+							}
+							results, err := p.TriggerCron(cli.Args().First())
+							if err != nil {
+								return err
+							}
+							if outputFormat == "json" {
+								return printJSON(results)
+							}
+							if !printTrigger(results) {
+								return errProgressFailed
+							}
+							return nil
+						},
+					},
+					{
+						Name:      "queue",
+						Usage:     "Deliver a message to the consumers of a queue",
+						ArgsUsage: "<name>",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "payload",
+								Usage: "File with the message body, a JSON array sends one message per item",
+							},
+						},
+						Action: func(cli *cli.Context) error {
+							if cli.Args().Len() != 1 {
+								return userErrorf("Usage: sst trigger queue <name> --payload file.json")
+							}
+							payload := []byte("{}")
+							if cli.String("payload") != "" {
+								data, err := os.ReadFile(cli.String("payload"))
+								if err != nil {
+									return err
+								}
+								payload = data
+							}
+							p, err := initProject()
+							if err != nil {
+								return err
+							}
+							results, err := p.TriggerQueue(cli.Args().First(), payload)
+							if err != nil {
+								return err
+							}
+							if outputFormat == "json" {
+								return printJSON(results)
+							}
+							if !printTrigger(results) {
+								return errProgressFailed
+							}
+							return nil
+						},
+					},
+				},
+			},
+			{
+				Name:  "warm",
+				Usage: "Show and scale the provisioned concurrency of the stage",
+				Action: func(cli *cli.Context) error {
+					p, err := initProject()
+					if err != nil {
+						return err
+					}
+					statuses, err := p.Warm()
+					if err != nil {
+						return err
+					}
+					if outputFormat == "json" {
+						return printJSON(statuses)
+					}
+					mode, err := p.WarmMode()
+					if err != nil {
+						return err
+					}
+					printHeader(p.App())
+					printWarm(mode, statuses)
+					return nil
+				},
+				Subcommands: []*cli.Command{
+					{
+						Name:  "down",
+						Usage: "Remove provisioned concurrency until `sst warm up`, schedules stop on the next deploy",
+						Action: func(cli *cli.Context) error {
+							p, err := initProject()
+							if err != nil {
+								return err
+							}
+							err = p.WarmDown()
+							if err != nil {
+								return err
+							}
+							color.New(color.FgGreen, color.Bold).Print("✔  ")
+							color.New(color.FgWhite).Println("Scaled down " + p.App().Stage)
+							return nil
+						},
+					},
+					{
+						Name:  "up",
+						Usage: "Restore the configured provisioned concurrency, schedules resume on the next deploy",
+						Action: func(cli *cli.Context) error {
+							p, err := initProject()
+							if err != nil {
+								return err
+							}
+							err = p.WarmUp()
+							if err != nil {
+								return err
+							}
+							color.New(color.FgGreen, color.Bold).Print("✔  ")
+							color.New(color.FgWhite).Println("Scaled up " + p.App().Stage)
+							return nil
+						},
+					},
+				},
+			},
+			{
+				Name:  "iam",
+				Usage: "Inspect the permissions a deploy needs",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "report",
+						Usage: "Print the least privilege policy for deploying the planned changes",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "out",
+								Usage: "Write the policy to a file instead of printing it",
+							},
+							&cli.BoolFlag{
+								Name:  "check",
+								Usage: "Simulate the policy against the current credentials",
+							},
+						},
+						Action: func(cli *cli.Context) error {
+							p, err := initProject()
+							if err != nil {
+								return err
+							}
+							// the policy is written to stdout, so no read-only notice
+							if _, err := p.ReadOnly(); err != nil {
+								slog.Warn("failed to check write access", "err", err)
+							}
+
+							plan, err := p.Stack.Plan()
+							if err != nil {
+								return err
+							}
+							report := project.NewIamReport(plan)
+							policy, err := iamPolicyJSON(report)
+							if err != nil {
+								return err
+							}
+
+							var denied []string
+							var checkErr error
+							if cli.Bool("check") {
+								denied, checkErr = p.Preflight(report)
+								if denied == nil && checkErr != nil {
+									return checkErr
+								}
+							}
+
+							if outputFormat == "json" {
+								err = printIamJSON(report, denied)
+								if err != nil {
+									return err
+								}
+								return checkErr
+							}
+
+							out := cli.String("out")
+							if out != "" {
+								err = os.WriteFile(out, append(policy, '\n'), 0644)
+								if err != nil {
+									return err
+								}
+							} else {
+								fmt.Println(string(policy))
+							}
+							printIamReport(report, out)
+							if cli.Bool("check") {
+								printPreflight(denied)
+							}
+							return checkErr
+						},
+					},
+				},
+			},
+			{
+				Name:  "status",
+				Flags: []cli.Flag{},
+				Action: func(cli *cli.Context) error {
+					p, err := initProject()
+					if err != nil {
+						return err
+					}
+					printHeader(p.App())
+					printReadOnly(p)
+
+					status, err := p.Stack.Status()
+					if err != nil {
+						return err
+					}
+
+					if status.Deployed.IsZero() {
+						color.New(color.FgHiBlack).Println("   Not deployed")
+					} else {
+						printStatus("Deployed:", status.Deployed.Local().Format(time.DateTime))
+					}
+					if status.Git != nil {
+						printStatus("Commit:", status.Git.Short())
+					}
+					printStatus("Resources:", fmt.Sprint(status.Resources))
+					if status.Locked {
+						printStatus("Lock:", "locked, run `sst cancel` if no update is running")
+					} else {
+						printStatus("Lock:", "unlocked")
+					}
+					if len(status.Pending) > 0 {
+						printStatus("Pending:", fmt.Sprintf("%v operations", len(status.Pending)))
+						for _, op := range status.Pending {
+							color.New(color.FgHiBlack).Printf("   %-12s%-11s %v\n", "", op.Type, op.Resource.URN)
+						}
+					}
+					if len(status.Outputs) > 0 {
+						printStatus("Outputs:", "")
+						for k, v := range status.Outputs {
+							color.New(color.FgHiBlack).Print("   ")
+							color.New(color.FgHiBlack, color.Bold).Print(k + ": ")
+							color.New(color.FgWhite).Println(v)
+						}
+					}
+					return nil
+				},
+			},
+			{
+				Name:  "output",
+				Usage: "Print the outputs of the last update",
+				Flags: []cli.Flag{},
+				Action: func(cli *cli.Context) error {
+					p, err := initProject()
+					if err != nil {
+						return err
+					}
+					printHeader(p.App())
+
+					removed, err := p.Removed()
+					if err != nil {
+						return err
+					}
+					if removed != nil {
+						return &project.StageRemovedError{Stage: p.App().Stage, Removed: removed.Removed}
+					}
+					outputs, err := p.Stack.Outputs()
+					if err != nil {
+						return err
+					}
+					for k, v := range outputs {
+						color.New(color.FgHiBlack).Print("   ")
+						color.New(color.FgHiBlack, color.Bold).Print(k + ": ")
+						color.New(color.FgWhite).Println(v)
+					}
+					return nil
+				},
+				Subcommands: []*cli.Command{
+					{
+						Name:      "diff",
+						Usage:     "Compare the outputs of two updates listed in sst history",
+						ArgsUsage: "<versionA> <versionB>",
+						Action: func(cli *cli.Context) error {
+							if cli.NArg() != 2 {
+								return userErrorf("Expected two versions")
+							}
+							versionA, err := strconv.Atoi(cli.Args().Get(0))
+							if err != nil {
+								return err
+							}
+							versionB, err := strconv.Atoi(cli.Args().Get(1))
+							if err != nil {
+								return err
+							}
+
+							p, err := initProject()
+							if err != nil {
+								return err
+							}
+							printHeader(p.App())
+
+							a, err := p.Stack.OutputsAt(versionA)
+							if err != nil {
+								return err
+							}
+							b, err := p.Stack.OutputsAt(versionB)
+							if err != nil {
+								return err
+							}
+							diffs := project.DiffOutputs(a, b)
+							if len(diffs) == 0 {
+								color.New(color.FgHiBlack).Println("   No changes")
+								return nil
+							}
+							printOutputDiff(diffs)
+							return nil
+						},
+					},
+				},
+			},
+			{
+				Name:  "types",
+				Usage: "Generate sst-env.d.ts describing the outputs and linked resources of the stage",
+				Flags: []cli.Flag{},
+				Action: func(cli *cli.Context) error {
+					p, err := initProject()
+					if err != nil {
+						return err
+					}
+					printHeader(p.App())
+
+					err = p.WriteTypes()
+					if err != nil {
+						return err
+					}
+					rel, _ := filepath.Rel(p.PathRoot(), p.PathTypes())
+					color.New(color.FgGreen, color.Bold).Print("✔")
+					color.New(color.FgWhite, color.Bold).Println("  Generated " + rel)
+					return nil
+				},
+			},
+			{
+				Name:  "local",
+				Usage: "Manage your personal overrides in .sst/local.config",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "encrypt",
+						Usage: "Encrypt .sst/local.config with a key only stored on this machine",
+						Action: func(cli *cli.Context) error {
+							p, err := initProject()
+							if err != nil {
+								return err
+							}
+							return p.EncryptLocalConfig()
+						},
+					},
+					{
+						Name:  "decrypt",
+						Usage: "Decrypt .sst/local.config so it can be edited",
+						Action: func(cli *cli.Context) error {
+							p, err := initProject()
+							if err != nil {
+								return err
+							}
+							return p.DecryptLocalConfig()
+						},
+					},
+				},
+			},
+			{
+				Name:  "history",
 				Flags: []cli.Flag{},
 				Action: func(cli *cli.Context) error {
 					p, err := initProject()
 					if err != nil {
 						return err
 					}
-					printHeader(p)
+					printHeader(p.App())
 
-					events, err := p.Stack.Deploy()
+					updates, err := p.Stack.History()
 					if err != nil {
 						return err
 					}
-					progress(ProgressModeDeploy, events)
+					if len(updates) == 0 {
+						color.New(color.FgHiBlack).Println("   No updates found")
+						return nil
+					}
 
+					for _, update := range updates {
+						resultColor := color.FgGreen
+						if update.Result != "succeeded" {
+							resultColor = color.FgRed
+						}
+						color.New(resultColor, color.Bold).Print("|  ")
+						color.New(color.FgWhite).Printf("%-5v%-11s", update.Version, update.Kind)
+						color.New(color.FgHiBlack).Print(update.Started().Format(time.DateTime))
+						color.New(color.FgHiBlack).Printf(" (%v)", formatDuration(update.Duration()))
+						if update.Message != "" {
+							color.New(color.FgHiBlack).Print(" ", update.Message)
+						}
+						fmt.Println()
+					}
 					return nil
 				},
 			},
 			{
-				Name:  "remove",
+				Name:  "daemon",
+				Usage: "Keep the project loaded in the background to speed up other commands",
 				Flags: []cli.Flag{},
 				Action: func(cli *cli.Context) error {
 					p, err := initProject()
 					if err != nil {
 						return err
 					}
-					printHeader(p)
+					printHeader(p.App())
 
-					events, err := p.Stack.Remove()
+					go func() {
+						interrupt := make(chan os.Signal, 1)
+						signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+						for sig := range interrupt {
+							// `sst cancel` interrupts the daemon when it runs the update
+							if sig == os.Interrupt && daemon.Interrupt(p) {
+								continue
+							}
+							os.Remove(daemon.SocketPath(p.PathRoot()))
+							os.Exit(0)
+						}
+					}()
+
+					color.New(color.FgHiBlack).Println("   Daemon running, other commands in this project will connect to it")
+					return daemon.Serve(p)
+				},
+			},
+			{
+				Name:  "serve",
+				Usage: "Serve deploy, remove, refresh, preview and outputs of a stage over a local http api",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "stage",
+						Usage: "Stage to serve, defaults to your personal stage",
+					},
+					&cli.StringFlag{
+						Name:  "addr",
+						Usage: "Loopback address or unix:<path> to listen on",
+						Value: "127.0.0.1:13557",
+					},
+				},
+				Action: func(cli *cli.Context) error {
+					cfgPath, err := project.Discover()
+					if err != nil {
+						return err
+					}
+					stack, err := ion.Open(ion.Options{
+						Config:  cfgPath,
+						Stage:   cli.String("stage"),
+						Version: version,
+					})
 					if err != nil {
 						return err
 					}
-					progress(ProgressModeRemove, events)
+					defer stack.Close()
+					printHeader(stack.App())
 
-					for evt := range events {
-						if evt.ResourcePreEvent != nil {
-							slog.Info("got op", "op", evt.ResourcePreEvent.Metadata.Op)
-						}
+					token, err := ion.NewToken()
+					if err != nil {
+						return err
+					}
+					tokenPath := filepath.Join(stack.Project().PathTemp(), "serve.token")
+					err = os.WriteFile(tokenPath, []byte(token), 0600)
+					if err != nil {
+						return err
+					}
+					defer os.Remove(tokenPath)
+
+					go func() {
+						interrupt := make(chan os.Signal, 1)
+						signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+						<-interrupt
+						os.Remove(tokenPath)
+						stack.Close()
+						os.Exit(0)
+					}()
+
+					color.New(color.FgHiBlack).Printf("   Listening on %v\n", cli.String("addr"))
+					color.New(color.FgHiBlack).Printf("   Send the token in %v as a bearer token\n", tokenPath)
+					return ion.Serve(stack, cli.String("addr"), token)
+				},
+			},
+			{
+				Name:      "replay",
+				Usage:     "Render a recorded event stream from .sst/events",
+				ArgsUsage: "<file>",
+				Flags:     []cli.Flag{},
+				Action: func(cli *cli.Context) error {
+					path := cli.Args().First()
+					if path == "" {
+						return userErrorf("Missing event file")
 					}
+
+					mode := ProgressModeDeploy
+					switch strings.TrimSuffix(filepath.Base(path), ".ndjson") {
+					case "destroy":
+						mode = ProgressModeRemove
+					case "refresh":
+						mode = ProgressModeRefresh
+					case "cancel":
+						mode = ProgressModeCancel
+					}
+
+					events, err := project.Replay(path)
+					if err != nil {
+						return err
+					}
+					progress(mode, events)
 					return nil
 				},
 			},
 			{
-				Name:  "refresh",
-				Flags: []cli.Flag{},
+				Name:  "bootstrap",
+				Usage: "Show the bootstrap resources of the account and region used by the stage",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "status",
+						Usage: "Show the bootstrap resources without changing them, the default",
+					},
+					&cli.BoolFlag{
+						Name:  "upgrade",
+						Usage: "Bootstrap the account and region or upgrade the bootstrap resources to the latest version",
+					},
+					&cli.BoolFlag{
+						Name:  "remove",
+						Usage: "Delete the bootstrap bucket and parameters, every app in the account and region loses its state",
+					},
+					&cli.BoolFlag{
+						Name:  "fanout",
+						Usage: "Bootstrap every account and region configured in fanout",
+					},
+				},
 				Action: func(cli *cli.Context) error {
+					if cli.Bool("status") && (cli.Bool("upgrade") || cli.Bool("remove")) {
+						return userErrorf("--status can not be combined with --upgrade or --remove")
+					}
 					p, err := initProject()
 					if err != nil {
 						return err
 					}
-					printHeader(p)
+					printHeader(p.App())
 
-					events, err := p.Stack.Refresh()
+					if cli.Bool("fanout") {
+						if cli.Bool("remove") {
+							return userErrorf("--remove can not be combined with --fanout")
+						}
+						targets, err := p.FanoutTargets()
+						if err != nil {
+							return err
+						}
+						for _, target := range targets {
+							child, err := p.Target(target)
+							if err != nil {
+								return err
+							}
+							status, err := child.BootstrapStatus()
+							if err != nil {
+								return err
+							}
+							if cli.Bool("upgrade") {
+								status, err = child.UpgradeBootstrap()
+								if err != nil {
+									return err
+								}
+							}
+							printStatus(target.Name()+":", bootstrapVersion(status))
+						}
+						return nil
+					}
+
+					status, err := p.BootstrapStatus()
 					if err != nil {
 						return err
 					}
-					progress(ProgressModeRefresh, events)
 
-					for evt := range events {
-						if evt.ResourcePreEvent != nil {
-							slog.Info("got op", "op", evt.ResourcePreEvent.Metadata.Op)
+					if cli.Bool("remove") {
+						if status.Version == 0 {
+							color.New(color.FgHiBlack).Println("   Not bootstrapped")
+							return nil
+						}
+						color.New(color.FgYellow, color.Bold).Print("!  ")
+						color.New(color.FgWhite).Printf("This deletes %v and the state of every app in account %v (%v)\n", status.Bucket, status.Account, status.Region)
+						color.New(color.FgWhite).Print("   Type the bucket name to confirm: ")
+						var answer string
+						fmt.Scanln(&answer)
+						if answer != status.Bucket {
+							return userErrorf("Bucket name did not match, nothing was removed")
+						}
+						err = p.RemoveBootstrap()
+						if err != nil {
+							return err
+						}
+						color.New(color.FgGreen, color.Bold).Print("\n✔")
+						color.New(color.FgWhite, color.Bold).Println("  Removed bootstrap")
+						return nil
+					}
+
+					if cli.Bool("upgrade") {
+						status, err = p.UpgradeBootstrap()
+						if err != nil {
+							return err
+						}
+					}
+
+					printStatus("Account:", status.Account)
+					printStatus("Region:", status.Region)
+					if status.Version != 0 {
+						printStatus("Bucket:", status.Bucket)
+					}
+					if status.AssetBucket != "" {
+						printStatus("Assets:", status.AssetBucket)
+					}
+					printStatus("Version:", bootstrapVersion(status))
+					return nil
+				},
+			},
+			{
+				Name:  "login",
+				Usage: "Log in to the console to sync deploys, audit logs and progress with your team",
+				Action: func(cli *cli.Context) error {
+					return login()
+				},
+			},
+			{
+				Name:  "logout",
+				Usage: "Log out of the console, nothing is synced afterwards",
+				Action: func(cli *cli.Context) error {
+					err := console.Logout()
+					if err != nil {
+						return err
+					}
+					color.New(color.FgGreen, color.Bold).Print("\n✔")
+					color.New(color.FgWhite, color.Bold).Println("  Logged out")
+					return nil
+				},
+			},
+			{
+				Name:  "whoami",
+				Usage: "Show the console account this machine is logged in to",
+				Action: func(cli *cli.Context) error {
+					session, err := console.Whoami()
+					if session == nil && err != nil {
+						return err
+					}
+					if outputFormat == "json" {
+						if session == nil {
+							return printJSON(nil)
 						}
+						return printJSON(map[string]string{
+							"email":     session.Email,
+							"workspace": session.Workspace,
+							"url":       session.Url,
+						})
 					}
+					printSession(session, err)
+					return nil
+				},
+			},
+			{
+				Name:  "telemetry",
+				Usage: "Manage the anonymous usage data sst collects",
+				Action: func(cli *cli.Context) error {
+					printTelemetry()
 					return nil
 				},
+				Subcommands: []*cli.Command{
+					{
+						Name:  "enable",
+						Usage: "Send anonymous usage data",
+						Action: func(cli *cli.Context) error {
+							err := telemetry.Enable()
+							if err != nil {
+								return err
+							}
+							if telemetry.Killed() {
+								color.New(color.FgYellow, color.Bold).Print("!  ")
+								color.New(color.FgWhite).Println("Telemetry is still disabled by SST_TELEMETRY_DISABLED or DO_NOT_TRACK")
+								return nil
+							}
+							color.New(color.FgGreen, color.Bold).Print("✔")
+							color.New(color.FgWhite, color.Bold).Println("  Telemetry enabled")
+							return nil
+						},
+					},
+					{
+						Name:  "disable",
+						Usage: "Stop sending anonymous usage data",
+						Action: func(cli *cli.Context) error {
+							err := telemetry.Disable()
+							if err != nil {
+								return err
+							}
+							color.New(color.FgGreen, color.Bold).Print("✔")
+							color.New(color.FgWhite, color.Bold).Println("  Telemetry disabled")
+							return nil
+						},
+					},
+					{
+						Name:  "status",
+						Usage: "Show whether anonymous usage data is sent",
+						Action: func(cli *cli.Context) error {
+							printTelemetry()
+							return nil
+						},
+					},
+				},
+			},
+			{
+				Name:  "report",
+				Usage: "Manage crash reports written to .sst",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "send",
+						Usage:     "Send a crash report to the SST team, the latest one by default",
+						ArgsUsage: "[path]",
+						Action: func(cli *cli.Context) error {
+							path := cli.Args().First()
+							if path == "" {
+								path = latestCrash()
+							}
+							if path == "" {
+								color.New(color.FgHiBlack).Println("   No crash reports found")
+								return nil
+							}
+							data, err := os.ReadFile(path)
+							if err != nil {
+								return err
+							}
+							data, err = redactReport(data)
+							if err != nil {
+								return err
+							}
+							color.New(color.FgYellow, color.Bold).Print("!  ")
+							color.New(color.FgWhite).Printf("%v includes the error, stack trace and last events of the update\n", path)
+							color.New(color.FgHiBlack).Println("   Resource values and program output are left out and credentials are masked")
+							color.New(color.FgWhite).Print("   Send it? (y/N) ")
+							var answer string
+							fmt.Scanln(&answer)
+							if strings.ToLower(answer) != "y" {
+								return nil
+							}
+							err = telemetry.SendReport(data)
+							if err != nil {
+								return err
+							}
+							color.New(color.FgGreen, color.Bold).Print("✔")
+							color.New(color.FgWhite, color.Bold).Println("  Report sent")
+							return nil
+						},
+					},
+				},
 			},
 			{
 				Name:  "create",
@@ -127,24 +1558,173 @@ func main() {
 				},
 			},
 			{
-				Name:  "cancel",
-				Flags: []cli.Flag{},
+				Name:  "migrate",
+				Usage: "Generate an sst.config.ts that adopts existing resources",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "profile",
+						Usage: "AWS profile used to read the existing resources",
+					},
+					&cli.StringFlag{
+						Name:  "region",
+						Usage: "AWS region of the existing resources",
+					},
+				},
+				Subcommands: []*cli.Command{
+					{
+						Name:      "cfn",
+						Usage:     "Adopt the resources of a CloudFormation stack",
+						ArgsUsage: "<stack-name>",
+						Action: func(cli *cli.Context) error {
+							if cli.NArg() != 1 {
+								return userErrorf("Expected a stack name")
+							}
+							cfg, err := migrate.LoadConfig(cli.String("profile"), cli.String("region"))
+							if err != nil {
+								return err
+							}
+							result, err := migrate.Cfn(cfg, cli.Args().First())
+							if err != nil {
+								return err
+							}
+							dir := migrationDir()
+							path, err := result.Write(dir)
+							if err != nil {
+								return err
+							}
+							retain, err := result.WriteRetain(dir)
+							if err != nil {
+								return err
+							}
+							printMigrationHeader(result.Result)
+							printMigration(result.Result, path)
+							if retain == "" {
+								color.New(color.FgYellow, color.Bold).Print("!  ")
+								color.New(color.FgWhite).Println("Template is not JSON, set DeletionPolicy: Retain on every resource, then delete the stack once the deploy adopted them")
+							}
+							printMigrationSteps(result.Commands(retain))
+							return nil
+						},
+					},
+					{
+						Name:      "terraform",
+						Usage:     "Import the resources in a Terraform state file into the stage",
+						ArgsUsage: "<tfstate>",
+						Action: func(cli *cli.Context) error {
+							if cli.NArg() != 1 {
+								return userErrorf("Expected a path to a terraform state file")
+							}
+							p, err := initProject()
+							if err != nil {
+								return err
+							}
+							printHeader(p.App())
+
+							result, err := migrate.Terraform(cli.Args().First())
+							if err != nil {
+								return err
+							}
+							result.App = p.App().Name
+							if len(result.Resources) > 0 {
+								file, err := result.ImportFile()
+								if err != nil {
+									return err
+								}
+								color.New(color.FgHiBlack).Printf("   Importing %v resources...\n", len(result.Resources))
+								err = p.Stack.Import(file)
+								if err != nil {
+									return err
+								}
+							}
+							path, err := result.Write(p.PathRoot())
+							if err != nil {
+								return err
+							}
+							printMigration(result, path)
+							printMigrationSteps([]string{
+								"Copy the resources from " + filepath.Base(path) + " into the run function of sst.config.ts",
+								"sst deploy",
+								"terraform state rm the imported resources so terraform stops managing them",
+							})
+							return nil
+						},
+					},
+					{
+						Name:      "serverless",
+						Usage:     "Convert a Serverless Framework config into an sst.config.ts",
+						ArgsUsage: "<serverless.yml>",
+						Action: func(cli *cli.Context) error {
+							file := "serverless.yml"
+							if cli.NArg() > 0 {
+								file = cli.Args().First()
+							}
+							result, err := migrate.Serverless(file)
+							if err != nil {
+								return err
+							}
+							path, err := result.Write(filepath.Dir(file))
+							if err != nil {
+								return err
+							}
+							printMigrationHeader(result.Result)
+							printStatus("Functions:", strconv.Itoa(result.Functions))
+							printMigration(result.Result, path)
+							printMigrationSteps([]string{
+								"Build each handler into the bundle directory set on its function",
+								"Resolve the comments marked review in " + filepath.Base(path),
+								"sst deploy",
+							})
+							return nil
+						},
+					},
+				},
+			},
+			{
+				Name: "cancel",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "force",
+						Usage: "Release the lock without waiting for a running update",
+					},
+				},
 				Action: func(cli *cli.Context) error {
+					force := cli.Bool("force")
+					if force {
+						if ok, err := runDaemon("cancel", ProgressModeCancel); ok {
+							return err
+						}
+					}
+
 					p, err := initProject()
 					if err != nil {
 						return err
 					}
-					printHeader(p)
+					printHeader(p.App())
+
+					if !force {
+						color.New(color.FgHiBlack).Println("   Waiting for in-flight operations to finish...")
+						stopped, err := p.Stack.Stop()
+						if err != nil {
+							return err
+						}
+						if stopped {
+							color.New(color.FgGreen, color.Bold).Print("\n✔")
+							color.New(color.FgWhite, color.Bold).Println("  Cancelled")
+							return nil
+						}
+					}
 
 					events, err := p.Stack.Cancel()
 					if err != nil {
 						return err
 					}
-					progress(ProgressModeCancel, events)
+					if err := progress(ProgressModeCancel, events).Err(); err != nil {
+						return err
+					}
 
 					for evt := range events {
 						if evt.ResourcePreEvent != nil {
-							slog.Info("got op", "op", evt.ResourcePreEvent.Metadata.Op)
+							slog.Debug("got op", "op", evt.ResourcePreEvent.Metadata.Op)
 						}
 					}
 					return nil
@@ -153,14 +1733,54 @@ func main() {
 		},
 	}
 
+	defer func() {
+		if r := recover(); r != nil {
+			err := fmt.Errorf("%v", r)
+			path, _ := writeCrash(app, err, debug.Stack())
+			printCrash(err, path)
+			os.Exit(1)
+		}
+	}()
+
+	telemetry.Flush()
+	go console.Flush()
+	started := time.Now()
 	err := app.Run(os.Args)
+	if live != nil {
+		live.Close()
+	}
+	telemetry.Track(version, commandName(app, os.Args[1:]), time.Since(started), err)
 	if err != nil {
-		panic(err)
+		if timedOut.Load() {
+			color.New(color.FgHiBlack).Println("   The checkpoint was written and the lock released, the next deploy continues from here")
+			os.Exit(exitTimedOut)
+		}
+		if reported(err) {
+			os.Exit(exitCode(err))
+		}
+		if expected(err) {
+			printCrash(err, "")
+			os.Exit(exitCode(err))
+		}
+		path, _ := writeCrash(app, err, nil)
+		printCrash(err, path)
+		os.Exit(exitDeployFailed)
+	}
+	// the update was interrupted but had nothing left in flight, scripts
+	// still need to know it did not run to completion
+	if userCancelled.Load() {
+		os.Exit(exitCancelled)
 	}
 
 }
 
 func initProject() (*project.Project, error) {
+	return initStageProject(nil)
+}
+
+// initStageProject is initProject for the stages passed with --stage, the
+// first one is used so the personal stage is never asked for
+func initStageProject(stages []string) (*project.Project, error) {
 	slog.Info("initializing project", "version", version)
 
 	cfgPath, err := project.Discover()
@@ -181,6 +1801,9 @@ func initProject() (*project.Project, error) {
 	}
 
 	app := p.App()
+	if len(stages) > 0 {
+		app.Stage = stages[0]
+	}
 	if app.Stage == "" {
 		p.LoadPersonalStage()
 		if app.Stage == "" {
@@ -188,6 +1811,11 @@ func initProject() (*project.Project, error) {
 				var stage string
 				fmt.Print("Enter a stage name for your personal stage: ")
 				_, err := fmt.Scanln(&stage)
+				// nothing left to read, like in CI
+				if errors.Is(err, io.EOF) {
+					p.Stack.Kill()
+					return nil, userErrorf("No stage set, pass --stage")
+				}
 				if err != nil {
 					continue
 				}
@@ -200,12 +1828,409 @@ func initProject() (*project.Project, error) {
 		}
 	}
 	slog.Info("loaded config", "app", app.Name, "stage", app.Stage)
+	if injectFailure != "" {
+		slog.Warn("injecting failure", "target", injectFailure)
+		p.InjectFailure(injectFailure)
+	}
+	if live == nil {
+		live = console.Live(app.Name, app.Stage)
+	}
+
+	timings, err = p.Timings()
+	if err != nil {
+		slog.Warn("failed to load timings", "err", err)
+	}
 
 	return p, nil
 }
 
-func printHeader(p *project.Project) {
-	app := p.App()
+// recoverStack checks for operations left pending by a crashed update and
+// reconciles them against the cloud before running anything else
+func recoverStack(p *project.Project, autoHeal bool) error {
+	pending, err := p.Stack.Pending()
+	if err != nil {
+		return err
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	color.New(color.FgYellow, color.Bold).Print("!  ")
+	color.New(color.FgWhite).Println("A previous update did not finish, these operations are still pending:")
+	for _, op := range pending {
+		color.New(color.FgHiBlack).Printf("   %-11s %v\n", op.Type, formatURN(string(op.Resource.URN)))
+	}
+	fmt.Println()
+
+	if !autoHeal {
+		color.New(color.FgWhite).Print("Reconcile them with the cloud before continuing? [y/n]: ")
+		var answer string
+		fmt.Scanln(&answer)
+		fmt.Println()
+		if answer != "y" {
+			return nil
+		}
+	}
+
+	events, err := p.Stack.Heal()
+	if err != nil {
+		return err
+	}
+	if events == nil {
+		color.New(color.FgHiBlack).Println("   Cleared the pending operations, none of the resources can be refreshed")
+		fmt.Println()
+		return nil
+	}
+	if err := progress(ProgressModeRefresh, events).Err(); err != nil {
+		return err
+	}
+	fmt.Println()
+	return nil
+}
+
+// interruptible forwards the first interrupt to the running update so it can
+// stop gracefully instead of leaving the stack locked and half written, a
+// second interrupt force quits. The same happens when --timeout is exceeded.
+func interruptible(p *project.Project) func() {
+	return interruptibleAll(func() []*project.Project {
+		return []*project.Project{p}
+	})
+}
+
+// interruptibleAll is interruptible for updates running in parallel, running
+// returns the projects whose update is in flight when the interrupt arrives
+func interruptibleAll(running func() []*project.Project) func() {
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	var deadline *time.Timer
+	finished := make(chan struct{})
+	// updates cancelled by the deadline, their locks are released at the end
+	var expired []*project.Project
+	var expiredLock sync.Mutex
+	interruptAll := func(projects []*project.Project) {
+		for _, p := range projects {
+			err := p.Stack.Interrupt()
+			if err != nil {
+				slog.Error("failed to interrupt", "app", p.App().Name, "stage", p.App().Stage, "err", err)
+			}
+		}
+	}
+	if timeout > 0 {
+		deadline = time.AfterFunc(timeout, func() {
+			timedOut.Store(true)
+			slog.Warn("timeout exceeded", "timeout", timeout)
+			color.New(color.FgYellow, color.Bold).Print("\n!  ")
+			color.New(color.FgWhite).Printf("Timed out after %v, cancelling and waiting for in-flight operations to finish\n", timeout)
+			projects := running()
+			expiredLock.Lock()
+			expired = projects
+			expiredLock.Unlock()
+			interruptAll(projects)
+			select {
+			case <-finished:
+				return
+			case <-time.After(timeoutGrace):
+				slog.Error("update did not stop in time, killing it")
+				for _, p := range projects {
+					p.Stack.Kill()
+					p.Stack.Unlock()
+				}
+				color.New(color.FgRed, color.Bold).Print("\n❌")
+				color.New(color.FgWhite, color.Bold).Printf(" Timed out after %v and in-flight operations did not finish within %v\n", timeout, timeoutGrace)
+				os.Exit(exitTimedOut)
+			}
+		})
+	}
+	go func() {
+		interrupted := false
+		for range interrupt {
+			if interrupted {
+				for _, p := range running() {
+					p.Stack.Kill()
+				}
+				color.New(color.FgRed, color.Bold).Print("\n\n❌")
+				color.New(color.FgWhite, color.Bold).Println(" Force quit, the stage may still be locked")
+				color.New(color.FgHiBlack).Println("   Run `sst cancel --force` to release the lock, the next deploy will reconcile any half written resources")
+				os.Exit(130)
+			}
+			interrupted = true
+			userCancelled.Store(true)
+			slog.Info("interrupt received")
+			color.New(color.FgYellow, color.Bold).Print("\n!  ")
+			color.New(color.FgWhite).Println("Cancelling, waiting for in-flight operations to finish. Press Ctrl-C again to force quit.")
+			interruptAll(running())
+		}
+	}()
+	return func() {
+		signal.Stop(interrupt)
+		close(interrupt)
+		close(finished)
+		if deadline != nil {
+			deadline.Stop()
+		}
+		if timedOut.Load() {
+			expiredLock.Lock()
+			defer expiredLock.Unlock()
+			for _, p := range expired {
+				err := p.Stack.Unlock()
+				if err != nil {
+					slog.Error("failed to release lock", "err", err)
+				}
+			}
+		}
+	}
+}
+
+// runDaemon forwards the command to a running daemon, the returned bool is
+// false if there is no daemon to forward to or the command needs something
+// only the regular path does
+func runDaemon(cmd string, mode ProgressMode) (bool, error) {
+	// deadlines are only armed by the regular path
+	if timeout > 0 {
+		return false, nil
+	}
+	cfgPath, err := project.Discover()
+	if err != nil {
+		return false, nil
+	}
+	client, err := daemon.Dial(filepath.Dir(cfgPath))
+	if err != nil {
+		return false, nil
+	}
+	slog.Info("using daemon", "command", cmd)
+
+	info, err := client.Info()
+	if err != nil {
+		return true, err
+	}
+	app := info.App
+	if info.Pending > 0 && cmd != "cancel" {
+		// pending operations are reconciled by the regular path
+		return false, nil
+	}
+	if cmd == "up" {
+		// let the regular path refuse or audit the deploy, run migrations,
+		// seeds and health checks
+		if window, _ := app.Frozen(time.Now()); window != nil {
+			return false, nil
+		}
+		if app.Health != nil || app.Tests != nil || app.Migrations != nil || app.Seed != nil {
+			return false, nil
+		}
+	}
+	if app.Notify && (cmd == "up" || cmd == "destroy") {
+		// notifications are shown by the regular path
+		return false, nil
+	}
+	printHeader(app)
+
+	var previous map[string]interface{}
+	if cmd == "up" {
+		if info.Protected && info.Dirty {
+			color.New(color.FgYellow, color.Bold).Print("!  ")
+			color.New(color.FgWhite).Printf("Deploying uncommitted changes to protected stage %v\n\n", app.Stage)
+		}
+		previous, err = client.Outputs()
+		if err != nil {
+			return true, err
+		}
+	}
+
+	events, err := client.Run(cmd)
+	if err != nil {
+		return true, err
+	}
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		for range interrupt {
+			userCancelled.Store(true)
+			color.New(color.FgYellow, color.Bold).Print("\n!  ")
+			color.New(color.FgWhite).Println("Cancelling, waiting for in-flight operations to finish")
+			err := client.Interrupt()
+			if err != nil {
+				slog.Error("failed to interrupt daemon", "err", err)
+			}
+		}
+	}()
+	result := progress(mode, events)
+	signal.Stop(interrupt)
+	close(interrupt)
+	if err := result.Err(); err != nil {
+		return true, err
+	}
+
+	switch cmd {
+	case "up":
+		deployed, err := client.Deployed()
+		if err != nil {
+			return true, err
+		}
+		printOutputDiff(project.DiffOutputs(previous, deployed.Outputs))
+		if deployed.TypesOutdated {
+			color.New(color.FgYellow, color.Bold).Print("\n!  ")
+			color.New(color.FgWhite).Println("sst-env.d.ts is out of date, run `sst types` to regenerate it")
+		}
+	case "destroy":
+		err := client.MarkRemoved()
+		if err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// runMigrations applies pending migrations after a deploy, a failed migration
+// fails the deploy
+func runMigrations(p *project.Project) (bool, error) {
+	if p.App().Migrations == nil {
+		return true, nil
+	}
+	applied, err := p.Migrate(func(name string) {
+		color.New(color.FgCyan, color.Bold).Print("\n➜  ")
+		color.New(color.FgWhite, color.Bold).Println("Migrating:", name)
+		fmt.Println()
+	})
+	if err != nil {
+		color.New(color.FgRed, color.Bold).Print("\n❌")
+		color.New(color.FgWhite, color.Bold).Println(" " + err.Error())
+		return false, nil
+	}
+	if len(applied) > 0 {
+		color.New(color.FgGreen, color.Bold).Print("\n✔")
+		color.New(color.FgWhite, color.Bold).Printf("  Applied %v migrations\n", len(applied))
+	}
+	return true, nil
+}
+
+func setBundleLimit(c *cli.Context, p *project.Project) error {
+	size := c.String("fail-on-size")
+	if size == "" {
+		return nil
+	}
+	limit, err := project.ParseSize(size)
+	if err != nil {
+		return err
+	}
+	p.SetBundleLimit(limit)
+	return nil
+}
+
+// seedStage runs the seed commands after the first deploy of a personal
+// stage, or always when forced by `sst seed`
+func seedStage(p *project.Project, force bool) (bool, error) {
+	if !force {
+		should, err := p.ShouldSeed()
+		if err != nil || !should {
+			return true, err
+		}
+	}
+	err := p.Seed(func(command string) {
+		color.New(color.FgCyan, color.Bold).Print("\n➜  ")
+		color.New(color.FgWhite, color.Bold).Println("Seeding:", command)
+		fmt.Println()
+	})
+	if err != nil {
+		color.New(color.FgRed, color.Bold).Print("\n❌")
+		color.New(color.FgWhite, color.Bold).Println(" " + err.Error())
+		return false, nil
+	}
+	color.New(color.FgGreen, color.Bold).Print("\n✔")
+	color.New(color.FgWhite, color.Bold).Println("  Seeded " + p.App().Stage)
+	return true, nil
+}
+
+// checkHealth waits for the configured health checks to pass after a deploy
+func checkHealth(p *project.Project) (bool, error) {
+	if p.App().Health == nil || len(p.App().Health.Checks) == 0 {
+		return true, nil
+	}
+	color.New(color.FgHiBlack).Print("\n   Waiting for health checks...\n")
+	results, ok, err := p.CheckHealth()
+	if err != nil {
+		return false, err
+	}
+	for _, result := range results {
+		if result.Healthy() {
+			color.New(color.FgGreen, color.Bold).Print("|  ")
+			color.New(color.FgHiBlack).Printf("%-11s %v", "Healthy", result.URL)
+			color.New(color.FgHiBlack).Printf(" (%s)\n", result.Latency)
+			continue
+		}
+		color.New(color.FgRed, color.Bold).Print("|  ")
+		color.New(color.FgHiBlack).Printf("%-11s %v", "Unhealthy", result.Check.Output)
+		color.New(color.FgRed).Printf(" %v\n", result.Error)
+	}
+	if !ok {
+		color.New(color.FgRed, color.Bold).Print("\n❌")
+		color.New(color.FgWhite, color.Bold).Println(" Health checks failed")
+	}
+	return ok, nil
+}
+
+// smokeTest runs the configured smoke test, a non-zero exit fails the deploy
+func smokeTest(p *project.Project) (bool, error) {
+	if p.App().Tests == nil || p.App().Tests.Smoke == "" {
+		return true, nil
+	}
+	color.New(color.FgCyan, color.Bold).Print("\n➜  ")
+	color.New(color.FgWhite, color.Bold).Println("Running smoke test:", p.App().Tests.Smoke)
+	fmt.Println()
+	code, err := p.Smoke()
+	if err != nil {
+		return false, err
+	}
+	if code != 0 {
+		color.New(color.FgRed, color.Bold).Print("\n❌")
+		color.New(color.FgWhite, color.Bold).Printf(" Smoke test failed with exit code %v\n", code)
+		return false, nil
+	}
+	color.New(color.FgGreen, color.Bold).Print("\n✔")
+	color.New(color.FgWhite, color.Bold).Println("  Smoke test passed")
+	return true, nil
+}
+
+// rollback redeploys the commit of the last successful update before this one
+func rollback(p *project.Project) error {
+	git, err := p.Stack.PreviousGit()
+	if err != nil {
+		return err
+	}
+	if git == nil || git.Commit == "" {
+		color.New(color.FgYellow, color.Bold).Print("\n!  ")
+		color.New(color.FgWhite).Println("No previous commit recorded, skipping rollback")
+		return nil
+	}
+	if git.Dirty {
+		color.New(color.FgYellow, color.Bold).Print("\n!  ")
+		color.New(color.FgWhite).Printf("Previous deploy of %v had uncommitted changes, rolling back to the commit only\n", git.Short())
+	}
+
+	color.New(color.FgCyan, color.Bold).Print("\n➜  ")
+	color.New(color.FgWhite, color.Bold).Printf("Rolling back to %v\n\n", git.Short())
+	previous, cleanup, err := p.Rollback(git.Commit)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	events, err := previous.Stack.Deploy()
+	if err != nil {
+		return err
+	}
+	err = progress(ProgressModeDeploy, events).Err()
+	if err != nil {
+		color.New(color.FgRed, color.Bold).Print("\n❌")
+		color.New(color.FgWhite, color.Bold).Printf(" Rollback to %v failed, the stage is still on the failed deploy\n", git.Short())
+		return err
+	}
+	return nil
+}
+
+func printHeader(app *project.App) {
+	if outputFormat == "json" {
+		return
+	}
 	fmt.Println()
 	color.New(color.FgCyan, color.Bold).Print("➜  ")
 
@@ -218,6 +2243,60 @@ func printHeader(p *project.Project) {
 	fmt.Println()
 }
 
+func printOutputDiff(diffs []project.OutputDiff) {
+	if len(diffs) == 0 {
+		return
+	}
+	color.New(color.FgCyan, color.Bold).Print("\n~")
+	color.New(color.FgWhite, color.Bold).Println("  Outputs changed:")
+	for _, diff := range diffs {
+		fmt.Println("   " + outputDiffLine(diff))
+	}
+}
+
+func outputDiffLine(diff project.OutputDiff) string {
+	key := color.New(color.FgHiBlack, color.Bold).Sprint(diff.Key + ": ")
+	switch diff.Op {
+	case project.OutputAdded:
+		return color.New(color.FgGreen, color.Bold).Sprint("+ ") + key + color.New(color.FgWhite).Sprint(diff.New)
+	case project.OutputRemoved:
+		return color.New(color.FgRed, color.Bold).Sprint("- ") + key + color.New(color.FgHiBlack).Sprint(diff.Old)
+	}
+	return color.New(color.FgYellow, color.Bold).Sprint("~ ") + key +
+		color.New(color.FgHiBlack).Sprint(diff.Old) +
+		color.New(color.FgWhite).Sprintf(" → %v", diff.New)
+}
+
+func bootstrapVersion(status *provider.BootstrapStatus) string {
+	if status.Version == 0 {
+		return "not bootstrapped, run `sst bootstrap --upgrade`"
+	}
+	if status.Outdated() {
+		return fmt.Sprintf("%v, run `sst bootstrap --upgrade` to upgrade to %v", status.Version, status.Latest)
+	}
+	return fmt.Sprintf("%v (latest)", status.Version)
+}
+
+// printReadOnly switches the project to read-only mode when the credentials
+// can not deploy, a failed detection is not fatal for commands that only read
+func printReadOnly(p *project.Project) {
+	readOnly, err := p.ReadOnly()
+	if err != nil {
+		slog.Warn("failed to check write access", "err", err)
+		return
+	}
+	if readOnly && outputFormat != "json" {
+		color.New(color.FgYellow, color.Bold).Print("!  ")
+		color.New(color.FgWhite).Println("Read-only credentials, changes can be previewed but not deployed")
+		fmt.Println()
+	}
+}
+
+func printStatus(label string, value string) {
+	color.New(color.FgWhite, color.Bold).Printf("   %-12s", label)
+	color.New(color.FgHiBlack).Println(value)
+}
+
 func prettyResourceName(input string) string {
 	splits := strings.Split(input, "::")
 	// take last two
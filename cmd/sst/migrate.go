@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/sst/ion/pkg/migrate"
+)
+
+func printMigrationHeader(result *migrate.Result) {
+	fmt.Println()
+	color.New(color.FgCyan, color.Bold).Print("➜  ")
+	color.New(color.FgWhite, color.Bold).Printf("%-12s", "App:")
+	color.New(color.FgHiBlack).Println(result.App)
+}
+
+func printMigration(result *migrate.Result, path string) {
+	printStatus("Config:", path)
+	if len(result.Resources) > 0 {
+		printStatus("Imported:", fmt.Sprintf("%v resources", len(result.Resources)))
+	}
+	fmt.Println()
+	for _, resource := range result.Resources {
+		color.New(color.FgGreen, color.Bold).Print("✔  ")
+		color.New(color.FgHiBlack).Printf("%v %v\n", resource.Constructor, resource.Name)
+		if resource.Review != "" {
+			color.New(color.FgYellow).Printf("   ↳ %v\n", resource.Review)
+		}
+	}
+	for _, item := range result.Unmapped {
+		color.New(color.FgYellow, color.Bold).Print("!  ")
+		color.New(color.FgHiBlack).Printf("%v %v: %v\n", item.Type, item.Name, item.Reason)
+	}
+}
+
+func printMigrationSteps(steps []string) {
+	fmt.Println()
+	color.New(color.FgWhite, color.Bold).Println("   Next steps")
+	for i, step := range steps {
+		color.New(color.FgHiBlack).Printf("   %v. %v\n", i+1, step)
+	}
+}
+
+func migrationDir() string {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "."
+	}
+	return cwd
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/sst/ion/pkg/project"
+)
+
+func printMigrations(applied []project.Migration, pending []string) {
+	fmt.Println()
+	if len(applied) == 0 && len(pending) == 0 {
+		color.New(color.FgHiBlack).Println("   No migrations")
+		return
+	}
+	for _, migration := range applied {
+		color.New(color.FgGreen, color.Bold).Print("✔  ")
+		color.New(color.FgWhite).Print(migration.Name)
+		color.New(color.FgHiBlack).Printf(" %v", migration.Applied.Local().Format(time.DateTime))
+		if migration.Commit != "" && len(migration.Commit) >= 7 {
+			color.New(color.FgHiBlack).Printf(" %v", migration.Commit[:7])
+		}
+		fmt.Println()
+	}
+	for _, name := range pending {
+		color.New(color.FgYellow, color.Bold).Print("•  ")
+		color.New(color.FgWhite).Print(name)
+		color.New(color.FgHiBlack).Println(" pending")
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/sst/ion/pkg/project"
+	cli "github.com/urfave/cli/v2"
+)
+
+// notify rings the terminal bell and shows a desktop notification with the
+// result of a finished command, failures to notify are only logged
+func notify(p *project.Project, action string, ok bool, started time.Time) {
+	title := fmt.Sprintf("%v %v", p.App().Name, p.App().Stage)
+	message := fmt.Sprintf("%v succeeded in %v", action, formatDuration(time.Since(started)))
+	if !ok {
+		message = fmt.Sprintf("%v failed after %v", action, formatDuration(time.Since(started)))
+	}
+	fmt.Fprint(os.Stderr, "\a")
+
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("osascript", "-e", fmt.Sprintf("display notification %q with title %q", message, title))
+	case "linux":
+		cmd = exec.Command("notify-send", title, message)
+	case "windows":
+		script := fmt.Sprintf(`[reflection.assembly]::loadwithpartialname('System.Windows.Forms') | Out-Null; `+
+			`$n = New-Object System.Windows.Forms.NotifyIcon; `+
+			`$n.Icon = [System.Drawing.SystemIcons]::Information; `+
+			`$n.Visible = $true; `+
+			`$n.ShowBalloonTip(10000, '%v', '%v', 'None')`,
+			strings.ReplaceAll(title, "'", "''"), strings.ReplaceAll(message, "'", "''"))
+		cmd = exec.Command("powershell", "-NoProfile", "-Command", script)
+	default:
+		return
+	}
+	err := cmd.Run()
+	if err != nil {
+		slog.Info("failed to show notification", "err", err)
+	}
+}
+
+// shouldNotify lets --notify override the notify default from the config
+func shouldNotify(c *cli.Context, p *project.Project) bool {
+	if c.IsSet("notify") {
+		return c.Bool("notify")
+	}
+	return p.App().Notify
+}
@@ -2,23 +2,23 @@ package main
 
 import (
 	"fmt"
+	"log/slog"
 	"regexp"
 	"strings"
 	"time"
 
-	"github.com/briandowns/spinner"
 	"github.com/fatih/color"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
 	"github.com/sst/ion/pkg/project"
 )
 
 type Progress struct {
-	Color   color.Attribute
-	Label   string
-	URN     string
-	Final   bool
-	Message string
-	time.Duration
+	Color         color.Attribute `json:"-"`
+	Label         string          `json:"label"`
+	URN           string          `json:"urn"`
+	Final         bool            `json:"final"`
+	Message       string          `json:"message,omitempty"`
+	time.Duration `json:"duration,omitempty"`
 }
 
 type ProgressMode string
@@ -28,334 +28,511 @@ const (
 	ProgressModeRemove  ProgressMode = "remove"
 	ProgressModeCancel  ProgressMode = "cancel"
 	ProgressModeRefresh ProgressMode = "refresh"
+	ProgressModeDiff    ProgressMode = "diff"
 )
 
-func progress(mode ProgressMode, events project.StackEventStream) bool {
-	spin := spinner.New(spinner.CharSets[14], 100*time.Millisecond)
-	pending := map[string]string{}
-	if mode == ProgressModeRemove {
-		spin.Suffix = "  Removing..."
-	}
-	if mode == ProgressModeDeploy {
-		spin.Suffix = "  Deploying..."
+type ProgressError struct {
+	Error string `json:"error"`
+	URN   string `json:"urn,omitempty"`
+}
+
+type ProgressSummary struct {
+	Errors           []ProgressError        `json:"errors"`
+	Warnings         []ProgressError        `json:"warnings"`
+	Outputs          map[string]interface{} `json:"outputs"`
+	ConcurrentUpdate bool                   `json:"concurrentUpdate,omitempty"`
+	Duration         time.Duration          `json:"duration"`
+	Bundles          []project.BundleEvent  `json:"bundles,omitempty"`
+}
+
+// ProgressResult is what progress reports back to the command so it can
+// pick an exit code
+type ProgressResult struct {
+	Summary *ProgressSummary
+	// the update was interrupted by the user
+	Cancelled bool
+	// the update succeeded but --fail-on-warn turned its warnings into a failure
+	FailedOnWarn bool
+}
+
+func (r *ProgressResult) OK() bool {
+	return !r.Summary.ConcurrentUpdate && len(r.Summary.Errors) == 0 && !r.FailedOnWarn
+}
+
+// Err is nil when the update succeeded, otherwise it is one of the errors
+// that exitCode maps to a distinct code
+func (r *ProgressResult) Err() error {
+	switch {
+	case r.OK():
+		return nil
+	case r.Summary.ConcurrentUpdate:
+		return errLocked
+	case r.Cancelled:
+		return errCancelled
 	}
-	if mode == ProgressModeCancel {
-		spin.Suffix = "  Cancelling..."
+	return errProgressFailed
+}
+
+// progressReducer turns stack events into Progress lines and accumulates the
+// final summary. It does no rendering so it can be driven by any Renderer.
+type progressReducer struct {
+	mode    ProgressMode
+	now     func() time.Time
+	start   time.Time
+	timing  map[string]time.Time
+	dedupe  map[string]bool
+	Summary ProgressSummary
+
+	// resource type and start of every resource still in flight
+	inflight map[string]string
+	started  int
+	finished int
+	measured []measurement
+}
+
+type measurement struct {
+	Type     string
+	Duration time.Duration
+}
+
+func newProgressReducer(mode ProgressMode) *progressReducer {
+	return &progressReducer{
+		mode:     mode,
+		now:      time.Now,
+		start:    time.Now(),
+		timing:   map[string]time.Time{},
+		dedupe:   map[string]bool{},
+		inflight: map[string]string{},
+		Summary: ProgressSummary{
+			Errors:   []ProgressError{},
+			Warnings: []ProgressError{},
+			Outputs:  map[string]interface{}{},
+		},
 	}
-	if mode == ProgressModeRefresh {
-		spin.Suffix = "  Refreshing..."
+}
+
+func (r *progressReducer) emit(progress Progress) []Progress {
+	dedupeKey := progress.URN + progress.Label + progress.Message
+	if r.dedupe[dedupeKey] {
+		return nil
 	}
-	spin.Start()
-	defer spin.Stop()
-
-	formatURN := func(urn string) string {
-		splits := strings.Split(urn, "::")[2:]
-		urn0 := splits[0]
-		resourceName0 := splits[1]
-		// convert aws:s3/bucket:Bucket to aws:s3:Bucket
-		urn1 := regexp.MustCompile(`\/[^:]+`).ReplaceAllString(urn0, "")
-		// convert sst:sst:Nextjs to sst:Nextjs
-		urn2 := regexp.MustCompile(`sst:sst:`).ReplaceAllString(urn1, "sst:")
-		// convert pulumi-nodejs:dynamic:Resource to sst:xxxx
-		urn3 := urn2
-		resourceName1 := resourceName0
-		resourceType := regexp.MustCompile(`\.sst\.(.+)$`).FindStringSubmatch(resourceName0)
-		if regexp.MustCompile(`pulumi-nodejs:dynamic:Resource$`).MatchString(urn2) &&
-			len(resourceType) > 1 {
-			urn3 = regexp.MustCompile(`pulumi-nodejs:dynamic:Resource$`).ReplaceAllString(urn2, resourceType[1])
-			resourceName1 = regexp.MustCompile(`\.sst\..+$`).ReplaceAllString(resourceName0, "")
-		}
-		urn4 := regexp.MustCompile(`\$`).ReplaceAllString(urn3, " → ")
-		// convert Nextjs$aws:s3:Bucket to Nextjs → aws:s3:Bucket
-		urn5 := regexp.MustCompile(`\$`).ReplaceAllString(urn4, " → ")
-		return urn5 + " → " + resourceName1
+	r.dedupe[dedupeKey] = true
+	return []Progress{progress}
+}
+
+func (r *progressReducer) Reduce(evt project.StackEvent) []Progress {
+	if evt.ConcurrentUpdateEvent != nil {
+		r.Summary.ConcurrentUpdate = true
+		return nil
 	}
 
-	dedupe := map[string]bool{}
-	printProgress := func(progress Progress) {
-		spin.Disable()
-		dedupeKey := progress.URN + progress.Label
-		if dedupe[dedupeKey] {
-			return
+	if evt.QuotaEvent != nil {
+		quota := evt.QuotaEvent
+		if quota.Error != "" {
+			r.Summary.Warnings = append(r.Summary.Warnings, ProgressError{Error: fmt.Sprintf("Could not check %v quota: %v", quota.Name, quota.Error)})
+			return nil
 		}
-		dedupe[dedupeKey] = true
-		defer spin.Enable()
-		if !progress.Final && false {
-			pending[progress.URN] =
-				color.New(color.FgWhite).Sprintf("   %-11s %v", progress.Label, formatURN(progress.URN))
-			suffix := "  Deploying...\n"
-			for _, item := range pending {
-				suffix += item + "\n"
-			}
-			spin.Suffix = strings.TrimRight(suffix, "\n")
-			return
+		msg := fmt.Sprintf("%v quota exceeded: %v of %v in use, this deploy needs %v more", quota.Name, quota.Usage, quota.Limit, quota.Planned)
+		if !quota.Fail {
+			r.Summary.Warnings = append(r.Summary.Warnings, ProgressError{Error: msg})
+			return nil
 		}
+		r.Summary.Errors = append(r.Summary.Errors, ProgressError{Error: msg})
+		return nil
+	}
 
-		color.New(progress.Color, color.Bold).Print("|  ")
-		color.New(color.FgHiBlack).Print(fmt.Sprintf("%-11s", progress.Label), " ", formatURN(progress.URN))
-		if progress.Duration != 0 {
-			color.New(color.FgHiBlack).Printf(" (%s)", progress.Duration)
-		}
-		if progress.Message != "" {
-			color.New(color.FgHiBlack).Print(" ", progress.Message)
-		}
-		fmt.Println()
+	if evt.PhaseEvent != nil {
+		return r.emit(Progress{
+			Color:   color.FgBlue,
+			Label:   "Shifting",
+			URN:     evt.PhaseEvent.URN,
+			Message: evt.PhaseEvent.Message,
+		})
 	}
 
-	timing := make(map[string]time.Time)
-	type errorStatus struct {
-		Error string
-		URN   string
+	if evt.BundleEvent != nil {
+		bundle := evt.BundleEvent
+		r.Summary.Bundles = append(r.Summary.Bundles, *bundle)
+		return r.emit(Progress{
+			Color:   color.FgMagenta,
+			Label:   "Bundled",
+			URN:     bundle.Name,
+			Message: fmt.Sprintf("%v zipped, ~%v cold start", project.FormatSize(bundle.Size), formatDuration(bundle.ColdStart())),
+		})
 	}
-	errors := []errorStatus{}
-	outputs := make(map[string]interface{})
 
-	for evt := range events {
-		if evt.SummaryEvent != nil {
-			spin.Suffix = "  Finalizing..."
+	if evt.BuildEvent != nil {
+		// build output repeats lines on purpose, like progress bars, so it
+		// is not deduped
+		return []Progress{{
+			Color:   color.FgMagenta,
+			Label:   "Building",
+			URN:     evt.BuildEvent.Name,
+			Message: evt.BuildEvent.Line,
+		}}
+	}
+
+	// a preview only plans, every resource is reported once with its change
+	if r.mode == ProgressModeDiff && evt.ResOutputsEvent != nil {
+		return nil
+	}
+	if r.mode == ProgressModeDiff && evt.ResourcePreEvent != nil {
+		if evt.ResourcePreEvent.Metadata.Type == "pulumi:pulumi:Stack" {
+			return nil
+		}
+		progress := Progress{
+			URN:   evt.ResourcePreEvent.Metadata.URN,
+			Final: true,
 		}
-		if evt.ConcurrentUpdateEvent != nil {
-			spin.Disable()
-			fmt.Println("Concurrent update detected, run `sst cancel` to delete lock file and retry.")
-			return false
+		switch evt.ResourcePreEvent.Metadata.Op {
+		case apitype.OpSame:
+			progress.Color = color.FgHiBlack
+			progress.Label = "Skipped"
+		case apitype.OpCreate:
+			progress.Color = color.FgGreen
+			progress.Label = "Create"
+		case apitype.OpUpdate:
+			progress.Color = color.FgYellow
+			progress.Label = "Update"
+		case apitype.OpReplace, apitype.OpCreateReplacement:
+			progress.Color = color.FgMagenta
+			progress.Label = "Replace"
+		case apitype.OpDelete:
+			progress.Color = color.FgRed
+			progress.Label = "Delete"
+		default:
+			return nil
 		}
+		return append(r.emit(progress), r.envChanges(evt.ResourcePreEvent.Metadata)...)
+	}
 
-		if evt.StdOutEvent != nil {
-			spin.Disable()
-			fmt.Println(evt.StdOutEvent.Text)
-			spin.Enable()
-			continue
+	if evt.ResourcePreEvent != nil {
+		r.timing[evt.ResourcePreEvent.Metadata.URN] = r.now()
+		if evt.ResourcePreEvent.Metadata.Type == "pulumi:pulumi:Stack" {
+			return nil
+		}
+		r.started++
+		if evt.ResourcePreEvent.Metadata.Op == apitype.OpSame {
+			r.finished++
+		} else {
+			r.inflight[evt.ResourcePreEvent.Metadata.URN] = evt.ResourcePreEvent.Metadata.Type
 		}
 
-		if evt.ResourcePreEvent != nil {
-			timing[evt.ResourcePreEvent.Metadata.URN] = time.Now()
-			if evt.ResourcePreEvent.Metadata.Type == "pulumi:pulumi:Stack" {
-				continue
-			}
+		progress := Progress{
+			Color: color.FgYellow,
+			URN:   evt.ResourcePreEvent.Metadata.URN,
+		}
+		switch evt.ResourcePreEvent.Metadata.Op {
+		case apitype.OpSame:
+			progress.Color = color.FgHiBlack
+			progress.Label = "Skipped"
+			progress.Final = true
+		case apitype.OpCreate, apitype.OpCreateReplacement, apitype.OpReplace:
+			progress.Label = "Creating"
+		case apitype.OpUpdate:
+			progress.Label = "Updating"
+		case apitype.OpDelete, apitype.OpDeleteReplaced:
+			progress.Label = "Deleting"
+		case apitype.OpRefresh:
+			progress.Label = "Refreshing"
+		default:
+			return nil
+		}
+		return append(r.emit(progress), r.envChanges(evt.ResourcePreEvent.Metadata)...)
+	}
 
-			if evt.ResourcePreEvent.Metadata.Op == apitype.OpSame {
-				printProgress(Progress{
-					Color: color.FgHiBlack,
-					Label: "Skipped",
-					Final: true,
-					URN:   evt.ResourcePreEvent.Metadata.URN,
-				})
-				continue
+	if evt.ResOutputsEvent != nil {
+		if evt.ResOutputsEvent.Metadata.Type == "pulumi:pulumi:Stack" && evt.ResOutputsEvent.Metadata.Op != apitype.OpDelete {
+			for k, v := range evt.ResOutputsEvent.Metadata.New.Outputs {
+				// internal outputs like _git are not meant for display
+				if strings.HasPrefix(k, "_") {
+					continue
+				}
+				r.Summary.Outputs[k] = v
 			}
+			return nil
+		}
 
-			if evt.ResourcePreEvent.Metadata.Op == apitype.OpCreate {
-				printProgress(Progress{
-					Color: color.FgYellow,
-					Label: "Creating",
-					URN:   evt.ResourcePreEvent.Metadata.URN,
-				})
-				continue
+		progress := Progress{
+			Color:    color.FgGreen,
+			Final:    true,
+			URN:      evt.ResOutputsEvent.Metadata.URN,
+			Duration: r.now().Sub(r.timing[evt.ResOutputsEvent.Metadata.URN]).Round(time.Millisecond),
+		}
+		if resourceType, ok := r.inflight[progress.URN]; ok {
+			delete(r.inflight, progress.URN)
+			r.finished++
+			r.measured = append(r.measured, measurement{Type: resourceType, Duration: progress.Duration})
+		}
+		switch evt.ResOutputsEvent.Metadata.Op {
+		case apitype.OpSame:
+			if r.mode != ProgressModeRefresh {
+				return nil
 			}
+			progress.Label = "Refreshed"
+		case apitype.OpCreate, apitype.OpCreateReplacement, apitype.OpReplace:
+			progress.Label = "Created"
+		case apitype.OpUpdate:
+			progress.Label = "Updated"
+		case apitype.OpDelete, apitype.OpDeleteReplaced:
+			progress.Color = color.FgRed
+			progress.Label = "Deleted"
+		default:
+			return nil
+		}
+		return r.emit(progress)
+	}
 
-			if evt.ResourcePreEvent.Metadata.Op == apitype.OpUpdate {
-				printProgress(Progress{
-					Color: color.FgYellow,
-					Label: "Updating",
-					URN:   evt.ResourcePreEvent.Metadata.URN,
-				})
-				continue
-			}
+	// status updates providers report while working on a slow resource
+	if evt.DiagnosticEvent != nil && evt.DiagnosticEvent.Severity == "info" && evt.DiagnosticEvent.URN != "" {
+		msg := strings.TrimSpace(evt.DiagnosticEvent.Message)
+		if msg == "" || !statusUpdates {
+			return nil
+		}
+		return r.emit(Progress{
+			Color:   color.FgHiBlack,
+			Label:   "Status",
+			URN:     evt.DiagnosticEvent.URN,
+			Message: msg,
+		})
+	}
 
-			if evt.ResourcePreEvent.Metadata.Op == apitype.OpCreateReplacement {
-				printProgress(Progress{
-					Color: color.FgYellow,
-					Label: "Creating",
-					URN:   evt.ResourcePreEvent.Metadata.URN,
-				})
+	if evt.DiagnosticEvent != nil && evt.DiagnosticEvent.Severity == "warning" {
+		msg := strings.TrimSpace(evt.DiagnosticEvent.Message)
+		if msg != "" {
+			r.Summary.Warnings = append(r.Summary.Warnings, ProgressError{
+				Error: msg,
+				URN:   evt.DiagnosticEvent.URN,
+			})
+		}
+		return nil
+	}
 
-				continue
+	if evt.DiagnosticEvent != nil && evt.DiagnosticEvent.Severity == "error" {
+		if evt.DiagnosticEvent.URN != "" {
+			msg := evt.DiagnosticEvent.Message
+			lines := strings.Split(evt.DiagnosticEvent.Message, "\n")
+			if len(lines) > 2 {
+				lines = strings.Split(lines[1], ":")
+				msg = strings.TrimSpace(lines[len(lines)-1])
 			}
+			r.Summary.Errors = append(r.Summary.Errors, ProgressError{
+				Error: msg,
+				URN:   evt.DiagnosticEvent.URN,
+			})
+			return r.emit(Progress{
+				URN:     evt.DiagnosticEvent.URN,
+				Color:   color.FgRed,
+				Final:   true,
+				Label:   "Error",
+				Message: msg,
+			})
+		}
 
-			if evt.ResourcePreEvent.Metadata.Op == apitype.OpDeleteReplaced {
-				printProgress(Progress{
-					Color: color.FgYellow,
-					Label: "Deleting",
-					URN:   evt.ResourcePreEvent.Metadata.URN,
-				})
-
+		lines := strings.Split(evt.DiagnosticEvent.Message, "\n")
+		out := []string{}
+		for _, line := range lines {
+			trimmed := strings.TrimSpace(line)
+			if trimmed == "" {
 				continue
 			}
+			if strings.HasPrefix(trimmed, "at") {
+				trimmed = "   " + trimmed
+			}
+			out = append(out, trimmed)
+		}
+		if len(out) > 1 {
+			r.Summary.Errors = append(r.Summary.Errors, ProgressError{
+				Error: strings.Join(out, "\n"),
+			})
+		}
+	}
 
-			if evt.ResourcePreEvent.Metadata.Op == apitype.OpReplace {
-				printProgress(Progress{
-					Color: color.FgYellow,
-					Label: "Creating",
-					URN:   evt.ResourcePreEvent.Metadata.URN,
-				})
+	return nil
+}
 
-				continue
-			}
+// envChanges shows which environment variables of a function change, values
+// are masked since they can hold secrets
+func (r *progressReducer) envChanges(step apitype.StepEventMetadata) []Progress {
+	result := []Progress{}
+	for _, change := range project.EnvDiff(step) {
+		progress := Progress{
+			Color: color.FgYellow,
+			Label: "Env",
+			URN:   step.URN,
+		}
+		switch change.Op {
+		case project.OutputAdded:
+			progress.Color = color.FgGreen
+			progress.Message = "+ " + change.Key
+		case project.OutputRemoved:
+			progress.Color = color.FgRed
+			progress.Message = "- " + change.Key
+		default:
+			progress.Message = "~ " + change.Key + " = ••••••"
+		}
+		result = append(result, r.emit(progress)...)
+	}
+	return result
+}
 
-			if evt.ResourcePreEvent.Metadata.Op == apitype.OpDelete {
-				printProgress(Progress{
-					Color: color.FgYellow,
-					Label: "Deleting",
-					URN:   evt.ResourcePreEvent.Metadata.URN,
-				})
-				continue
-			}
+// status describes how far along a deploy is, using the timings of previous
+// deploys to estimate how long the resources in flight still need
+func (r *progressReducer) status(timings *project.Timings) string {
+	return progressStatus(r.mode) + r.estimate(timings) + " · " + formatDuration(r.now().Sub(r.start).Truncate(time.Second))
+}
 
-			if evt.ResourcePreEvent.Metadata.Op == apitype.OpRefresh {
-				printProgress(Progress{
-					Color: color.FgYellow,
-					Label: "Refreshing",
-					URN:   evt.ResourcePreEvent.Metadata.URN,
-				})
-				continue
-			}
+func (r *progressReducer) estimate(timings *project.Timings) string {
+	if r.started == 0 {
+		return ""
+	}
+	result := ""
+	total := 0
+	if timings != nil && r.mode == ProgressModeDeploy {
+		total = timings.StageResources()
+	}
+	if total >= r.started {
+		result += fmt.Sprintf("  %v/%v resources", formatCount(r.finished), formatCount(total))
+	} else {
+		result += fmt.Sprintf("  %v resources", formatCount(r.finished))
+	}
+	if timings == nil || r.mode != ProgressModeDeploy {
+		return result
+	}
+
+	// resources are updated in parallel so the slowest one decides
+	var remaining time.Duration
+	for urn, resourceType := range r.inflight {
+		expected, ok := timings.Expected(resourceType)
+		if !ok {
+			continue
 		}
+		remaining = max(remaining, expected-r.now().Sub(r.timing[urn]))
+	}
+	if remaining > 0 {
+		result += fmt.Sprintf(", ~%v left", formatDuration(remaining))
+	}
+	return result
+}
 
-		if evt.ResOutputsEvent != nil {
-			if evt.ResOutputsEvent.Metadata.Type == "pulumi:pulumi:Stack" && evt.ResOutputsEvent.Metadata.Op != apitype.OpDelete {
-				outputs = evt.ResOutputsEvent.Metadata.New.Outputs
-				continue
-			}
-			duration := time.Since(timing[evt.ResOutputsEvent.Metadata.URN]).Round(time.Millisecond)
-			if evt.ResOutputsEvent.Metadata.Op == apitype.OpSame && mode == ProgressModeRefresh {
-				printProgress(Progress{
-					Color:    color.FgGreen,
-					Label:    "Refreshed",
-					Final:    true,
-					URN:      evt.ResOutputsEvent.Metadata.URN,
-					Duration: duration,
-				})
-				continue
+// set from the --fail-on-warn flag
+var failOnWarn = false
+
+// set from the --concise and --verbose flags, collapses unchanged resources
+// into a single line
+var concise = true
+
+// set from the --status-updates flag
+var statusUpdates = true
+
+// loaded by initProject, nil when progress is not tied to a project like in
+// sst replay
+var timings *project.Timings
+
+func progress(mode ProgressMode, events project.StackEventStream) *ProgressResult {
+	renderer := withConsole(newRenderer(), mode)
+	reducer := newProgressReducer(mode)
+	renderer.Start(mode)
+
+	// only the spinner can be updated in place
+	tty, _ := renderer.(*ttyRenderer)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	finalizing := false
+	collapse := concise && outputFormat != "json"
+	unchanged := 0
+
+loop:
+	for {
+		select {
+		case <-ticker.C:
+			if tty != nil && !finalizing {
+				tty.Status(reducer.status(timings))
 			}
-			if evt.ResOutputsEvent.Metadata.Op == apitype.OpCreate {
-				printProgress(Progress{
-					Color:    color.FgGreen,
-					Label:    "Created",
-					Final:    true,
-					URN:      evt.ResOutputsEvent.Metadata.URN,
-					Duration: duration,
-				})
+		case evt, ok := <-events:
+			if !ok {
+				break loop
 			}
-			if evt.ResOutputsEvent.Metadata.Op == apitype.OpUpdate {
-				printProgress(Progress{
-					Color:    color.FgGreen,
-					Label:    "Updated",
-					Final:    true,
-					URN:      evt.ResOutputsEvent.Metadata.URN,
-					Duration: duration,
-				})
-			}
-			if evt.ResOutputsEvent.Metadata.Op == apitype.OpDelete {
-				printProgress(Progress{
-					Color:    color.FgRed,
-					Label:    "Deleted",
-					Final:    true,
-					URN:      evt.ResOutputsEvent.Metadata.URN,
-					Duration: duration,
-				})
-			}
-			if evt.ResOutputsEvent.Metadata.Op == apitype.OpDeleteReplaced {
-				printProgress(Progress{
-					Color:    color.FgRed,
-					Label:    "Deleted",
-					Final:    true,
-					URN:      evt.ResOutputsEvent.Metadata.URN,
-					Duration: duration,
-				})
-			}
-			if evt.ResOutputsEvent.Metadata.Op == apitype.OpCreateReplacement {
-				printProgress(Progress{
-					Color:    color.FgGreen,
-					Label:    "Created",
-					Final:    true,
-					URN:      evt.ResOutputsEvent.Metadata.URN,
-					Duration: duration,
-				})
+			if evt.StdOutEvent != nil {
+				renderer.Log(evt.StdOutEvent.Text)
+				continue
 			}
-			if evt.ResOutputsEvent.Metadata.Op == apitype.OpReplace {
-				printProgress(Progress{
-					Color:    color.FgGreen,
-					Label:    "Created",
-					Final:    true,
-					URN:      evt.ResOutputsEvent.Metadata.URN,
-					Duration: duration,
-				})
+			if evt.SummaryEvent != nil {
+				finalizing = true
+				renderer.Status("Finalizing...")
 			}
-		}
-
-		if evt.ResOpFailedEvent != nil {
-		}
-
-		if evt.DiagnosticEvent != nil {
-			if evt.DiagnosticEvent.Severity == "error" {
-				if evt.DiagnosticEvent.URN != "" {
-					msg := evt.DiagnosticEvent.Message
-					lines := strings.Split(evt.DiagnosticEvent.Message, "\n")
-					if len(lines) > 2 {
-						lines = strings.Split(lines[1], ":")
-						msg = strings.TrimSpace(lines[len(lines)-1])
-					}
-					errors = append(errors, errorStatus{
-						Error: msg,
-						URN:   evt.DiagnosticEvent.URN,
-					})
-					printProgress(Progress{
-						URN:     evt.DiagnosticEvent.URN,
-						Color:   color.FgRed,
-						Final:   true,
-						Label:   "Error",
-						Message: msg,
-					})
+			for _, item := range reducer.Reduce(evt) {
+				if !progressFilter.allows(item) {
 					continue
 				}
-
-				lines := strings.Split(evt.DiagnosticEvent.Message, "\n")
-				out := []string{}
-				for _, line := range lines {
-					trimmed := strings.TrimSpace(line)
-					if trimmed == "" {
-						continue
-					}
-					if strings.HasPrefix(trimmed, "at") {
-						trimmed = "   " + trimmed
-					}
-					out = append(out, trimmed)
-				}
-				if len(out) > 1 {
-					errors = append(errors, errorStatus{
-						Error: strings.Join(out, "\n"),
-					})
+				if collapse && item.Label == "Skipped" {
+					unchanged++
+					continue
 				}
+				renderer.Progress(item)
+			}
+			if reducer.Summary.ConcurrentUpdate {
+				break loop
 			}
 		}
 	}
 
-	spin.Stop()
+	if timings != nil && mode == ProgressModeDeploy && !reducer.Summary.ConcurrentUpdate {
+		for _, item := range reducer.measured {
+			timings.Record(item.Type, item.Duration)
+		}
+		if len(reducer.Summary.Errors) == 0 {
+			timings.SetStageResources(reducer.started)
+		}
+		err := timings.Save()
+		if err != nil {
+			slog.Error("failed to save timings", "err", err)
+		}
+	}
 
-	if len(errors) == 0 {
-		color.New(color.FgGreen, color.Bold).Print("\n✔")
+	if unchanged > 0 && progressFilter.allows(unchangedProgress(unchanged)) {
+		renderer.Progress(unchangedProgress(unchanged))
+	}
+	reducer.Summary.Duration = reducer.now().Sub(reducer.start)
+	renderer.Finish(&reducer.Summary)
+	return &ProgressResult{
+		Summary:      &reducer.Summary,
+		Cancelled:    userCancelled.Load(),
+		FailedOnWarn: failOnWarn && len(reducer.Summary.Warnings) > 0,
+	}
+}
 
-		if len(outputs) > 0 {
-			color.New(color.FgWhite, color.Bold).Println("  Complete:")
-			for k, v := range outputs {
-				color.New(color.FgHiBlack).Print("   ")
-				color.New(color.FgHiBlack, color.Bold).Print(k + ": ")
-				color.New(color.FgWhite).Println(v)
-			}
-		} else {
-			color.New(color.FgWhite, color.Bold).Println("  Complete")
-		}
-		return true
-	} else {
-		color.New(color.FgRed, color.Bold).Print("\n❌")
-		color.New(color.FgWhite, color.Bold).Println(" Failed:")
+func unchangedProgress(count int) Progress {
+	return Progress{
+		Color:   color.FgHiBlack,
+		Label:   "Unchanged",
+		Final:   true,
+		Message: fmt.Sprintf("%v resources", formatCount(count)),
+	}
+}
 
-		for _, status := range errors {
-			color.New(color.FgHiBlack).Print("   ")
-			if status.URN != "" {
-				color.New(color.FgRed, color.Bold).Print(formatURN(status.URN) + ": ")
-			}
-			color.New(color.FgWhite).Println(strings.TrimSpace(status.Error))
-		}
-		return false
+func formatURN(urn string) string {
+	if strings.Count(urn, "::") < 3 {
+		return urn
+	}
+	splits := strings.Split(urn, "::")[2:]
+	urn0 := splits[0]
+	resourceName0 := splits[1]
+	// convert aws:s3/bucket:Bucket to aws:s3:Bucket
+	urn1 := regexp.MustCompile(`\/[^:]+`).ReplaceAllString(urn0, "")
+	// convert sst:sst:Nextjs to sst:Nextjs
+	urn2 := regexp.MustCompile(`sst:sst:`).ReplaceAllString(urn1, "sst:")
+	// convert pulumi-nodejs:dynamic:Resource to sst:xxxx
+	urn3 := urn2
+	resourceName1 := resourceName0
+	resourceType := regexp.MustCompile(`\.sst\.(.+)$`).FindStringSubmatch(resourceName0)
+	if regexp.MustCompile(`pulumi-nodejs:dynamic:Resource$`).MatchString(urn2) &&
+		len(resourceType) > 1 {
+		urn3 = regexp.MustCompile(`pulumi-nodejs:dynamic:Resource$`).ReplaceAllString(urn2, resourceType[1])
+		resourceName1 = regexp.MustCompile(`\.sst\..+$`).ReplaceAllString(resourceName0, "")
 	}
+	urn4 := regexp.MustCompile(`\$`).ReplaceAllString(urn3, " → ")
+	// convert Nextjs$aws:s3:Bucket to Nextjs → aws:s3:Bucket
+	urn5 := regexp.MustCompile(`\$`).ReplaceAllString(urn4, " → ")
+	return urn5 + " → " + resourceName1
 }
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/sst/ion/pkg/project"
+)
+
+const (
+	bucketURN = "urn:pulumi:dev::app::aws:s3/bucketV2:BucketV2::Bucket"
+	fnURN     = "urn:pulumi:dev::app::aws:lambda/function:Function::Fn"
+	stackURN  = "urn:pulumi:dev::app::pulumi:pulumi:Stack::app-dev"
+)
+
+func preEvent(op apitype.OpType, urn string, resourceType string) project.StackEvent {
+	evt := project.StackEvent{}
+	evt.ResourcePreEvent = &apitype.ResourcePreEvent{
+		Metadata: apitype.StepEventMetadata{Op: op, URN: urn, Type: resourceType},
+	}
+	return evt
+}
+
+func outputsEvent(op apitype.OpType, urn string, resourceType string) project.StackEvent {
+	evt := project.StackEvent{}
+	evt.ResOutputsEvent = &apitype.ResOutputsEvent{
+		Metadata: apitype.StepEventMetadata{Op: op, URN: urn, Type: resourceType},
+	}
+	return evt
+}
+
+func diagnosticEvent(severity string, urn string, message string) project.StackEvent {
+	evt := project.StackEvent{}
+	evt.DiagnosticEvent = &apitype.DiagnosticEvent{Severity: severity, URN: urn, Message: message}
+	return evt
+}
+
+func envEvent(op apitype.OpType) project.StackEvent {
+	evt := preEvent(op, fnURN, "aws:lambda/function:Function")
+	evt.ResourcePreEvent.Metadata.DetailedDiff = map[string]apitype.PropertyDiff{
+		"environment.variables.TOKEN": {Kind: apitype.DiffUpdate},
+		"environment.variables.NEW":   {Kind: apitype.DiffAdd},
+	}
+	return evt
+}
+
+func TestReduce(t *testing.T) {
+	stackOutputs := outputsEvent(apitype.OpUpdate, stackURN, "pulumi:pulumi:Stack")
+	stackOutputs.ResOutputsEvent.Metadata.New = &apitype.StepEventStateMetadata{
+		Outputs: map[string]interface{}{"url": "https://example.com", "_git": "abc"},
+	}
+
+	tests := []struct {
+		name     string
+		mode     ProgressMode
+		events   []project.StackEvent
+		labels   []string
+		messages []string
+		summary  ProgressSummary
+	}{
+		{
+			name: "deploy",
+			mode: ProgressModeDeploy,
+			events: []project.StackEvent{
+				preEvent(apitype.OpCreate, bucketURN, "aws:s3/bucketV2:BucketV2"),
+				outputsEvent(apitype.OpCreate, bucketURN, "aws:s3/bucketV2:BucketV2"),
+				preEvent(apitype.OpSame, fnURN, "aws:lambda/function:Function"),
+				outputsEvent(apitype.OpSame, fnURN, "aws:lambda/function:Function"),
+				stackOutputs,
+			},
+			labels:   []string{"Creating", "Created", "Skipped"},
+			messages: []string{"", "", ""},
+			summary: ProgressSummary{
+				Errors:   []ProgressError{},
+				Warnings: []ProgressError{},
+				Outputs:  map[string]interface{}{"url": "https://example.com"},
+			},
+		},
+		{
+			name: "deploy error",
+			mode: ProgressModeDeploy,
+			events: []project.StackEvent{
+				preEvent(apitype.OpCreate, bucketURN, "aws:s3/bucketV2:BucketV2"),
+				diagnosticEvent("error", bucketURN, "failed\n  error: creating bucket: BucketAlreadyExists\n  more"),
+				diagnosticEvent("error", bucketURN, "failed\n  error: creating bucket: BucketAlreadyExists\n  more"),
+			},
+			labels:   []string{"Creating", "Error"},
+			messages: []string{"", "BucketAlreadyExists"},
+			summary: ProgressSummary{
+				Errors: []ProgressError{
+					{Error: "BucketAlreadyExists", URN: bucketURN},
+					{Error: "BucketAlreadyExists", URN: bucketURN},
+				},
+				Warnings: []ProgressError{},
+				Outputs:  map[string]interface{}{},
+			},
+		},
+		{
+			name: "refresh",
+			mode: ProgressModeRefresh,
+			events: []project.StackEvent{
+				preEvent(apitype.OpRefresh, bucketURN, "aws:s3/bucketV2:BucketV2"),
+				outputsEvent(apitype.OpSame, bucketURN, "aws:s3/bucketV2:BucketV2"),
+			},
+			labels:   []string{"Refreshing", "Refreshed"},
+			messages: []string{"", ""},
+			summary: ProgressSummary{
+				Errors:   []ProgressError{},
+				Warnings: []ProgressError{},
+				Outputs:  map[string]interface{}{},
+			},
+		},
+		{
+			name: "diff",
+			mode: ProgressModeDiff,
+			events: []project.StackEvent{
+				preEvent(apitype.OpSame, stackURN, "pulumi:pulumi:Stack"),
+				preEvent(apitype.OpCreate, bucketURN, "aws:s3/bucketV2:BucketV2"),
+				outputsEvent(apitype.OpCreate, bucketURN, "aws:s3/bucketV2:BucketV2"),
+				preEvent(apitype.OpDelete, fnURN, "aws:lambda/function:Function"),
+			},
+			labels:   []string{"Create", "Delete"},
+			messages: []string{"", ""},
+			summary: ProgressSummary{
+				Errors:   []ProgressError{},
+				Warnings: []ProgressError{},
+				Outputs:  map[string]interface{}{},
+			},
+		},
+		{
+			name:     "env",
+			mode:     ProgressModeDiff,
+			events:   []project.StackEvent{envEvent(apitype.OpUpdate)},
+			labels:   []string{"Update", "Env", "Env"},
+			messages: []string{"", "+ NEW", "~ TOKEN = ••••••"},
+			summary: ProgressSummary{
+				Errors:   []ProgressError{},
+				Warnings: []ProgressError{},
+				Outputs:  map[string]interface{}{},
+			},
+		},
+		{
+			name: "quota",
+			mode: ProgressModeDeploy,
+			events: []project.StackEvent{
+				{QuotaEvent: &project.QuotaEvent{Name: "Lambda", Limit: 10, Usage: 9, Planned: 2}},
+				{QuotaEvent: &project.QuotaEvent{Name: "IAM roles", Limit: 10, Usage: 10, Planned: 1, Fail: true}},
+				{QuotaEvent: &project.QuotaEvent{Name: "Buckets", Error: "AccessDenied"}},
+			},
+			summary: ProgressSummary{
+				Errors: []ProgressError{
+					{Error: "IAM roles quota exceeded: 10 of 10 in use, this deploy needs 1 more"},
+				},
+				Warnings: []ProgressError{
+					{Error: "Lambda quota exceeded: 9 of 10 in use, this deploy needs 2 more"},
+					{Error: "Could not check Buckets quota: AccessDenied"},
+				},
+				Outputs: map[string]interface{}{},
+			},
+		},
+		{
+			name: "bundle",
+			mode: ProgressModeDeploy,
+			events: []project.StackEvent{
+				{BundleEvent: &project.BundleEvent{Name: "Fn", Size: 2 * 1024 * 1024}},
+				{BundleEvent: &project.BundleEvent{Name: "Fn", Size: 2 * 1024 * 1024}},
+			},
+			labels:   []string{"Bundled"},
+			messages: []string{"2.0 MB zipped, ~50ms cold start"},
+			summary: ProgressSummary{
+				Errors:   []ProgressError{},
+				Warnings: []ProgressError{},
+				Outputs:  map[string]interface{}{},
+				Bundles: []project.BundleEvent{
+					{Name: "Fn", Size: 2 * 1024 * 1024},
+					{Name: "Fn", Size: 2 * 1024 * 1024},
+				},
+			},
+		},
+		{
+			name: "warning",
+			mode: ProgressModeDeploy,
+			events: []project.StackEvent{
+				diagnosticEvent("warning", bucketURN, "  deprecated  "),
+				diagnosticEvent("warning", bucketURN, " "),
+			},
+			summary: ProgressSummary{
+				Errors:   []ProgressError{},
+				Warnings: []ProgressError{{Error: "deprecated", URN: bucketURN}},
+				Outputs:  map[string]interface{}{},
+			},
+		},
+		{
+			name: "concurrent update",
+			mode: ProgressModeDeploy,
+			events: []project.StackEvent{
+				{ConcurrentUpdateEvent: &project.ConcurrentUpdateEvent{}},
+			},
+			summary: ProgressSummary{
+				Errors:           []ProgressError{},
+				Warnings:         []ProgressError{},
+				Outputs:          map[string]interface{}{},
+				ConcurrentUpdate: true,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reducer := newProgressReducer(tt.mode)
+			reducer.now = func() time.Time { return time.Unix(0, 0) }
+			labels := []string{}
+			messages := []string{}
+			for _, evt := range tt.events {
+				for _, item := range reducer.Reduce(evt) {
+					labels = append(labels, item.Label)
+					messages = append(messages, item.Message)
+				}
+			}
+			if len(tt.labels) == 0 {
+				tt.labels = []string{}
+				tt.messages = []string{}
+			}
+			if !reflect.DeepEqual(labels, tt.labels) {
+				t.Errorf("labels = %v, want %v", labels, tt.labels)
+			}
+			if !reflect.DeepEqual(messages, tt.messages) {
+				t.Errorf("messages = %q, want %q", messages, tt.messages)
+			}
+			if !reflect.DeepEqual(reducer.Summary, tt.summary) {
+				t.Errorf("summary = %+v, want %+v", reducer.Summary, tt.summary)
+			}
+		})
+	}
+}
+
+func TestReduceCountsResources(t *testing.T) {
+	reducer := newProgressReducer(ProgressModeDeploy)
+	reducer.now = func() time.Time { return time.Unix(0, 0) }
+	reducer.Reduce(preEvent(apitype.OpCreate, bucketURN, "aws:s3/bucketV2:BucketV2"))
+	reducer.Reduce(preEvent(apitype.OpSame, fnURN, "aws:lambda/function:Function"))
+	if reducer.started != 2 || reducer.finished != 1 {
+		t.Fatalf("started %v finished %v, want 2 and 1", reducer.started, reducer.finished)
+	}
+	reducer.Reduce(outputsEvent(apitype.OpCreate, bucketURN, "aws:s3/bucketV2:BucketV2"))
+	if reducer.finished != 2 || len(reducer.inflight) != 0 {
+		t.Fatalf("finished %v with %v in flight, want 2 and 0", reducer.finished, len(reducer.inflight))
+	}
+	want := []measurement{{Type: "aws:s3/bucketV2:BucketV2", Duration: 0}}
+	if !reflect.DeepEqual(reducer.measured, want) {
+		t.Fatalf("measured = %v, want %v", reducer.measured, want)
+	}
+}
+
+func TestProgressResultErr(t *testing.T) {
+	tests := []struct {
+		name   string
+		result ProgressResult
+		want   error
+	}{
+		{"ok", ProgressResult{Summary: &ProgressSummary{}}, nil},
+		{"locked", ProgressResult{Summary: &ProgressSummary{ConcurrentUpdate: true}}, errLocked},
+		{"cancelled", ProgressResult{Summary: &ProgressSummary{Errors: []ProgressError{{Error: "x"}}}, Cancelled: true}, errCancelled},
+		{"failed", ProgressResult{Summary: &ProgressSummary{Errors: []ProgressError{{Error: "x"}}}}, errProgressFailed},
+		{"fail on warn", ProgressResult{Summary: &ProgressSummary{}, FailedOnWarn: true}, errProgressFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.result.Err(); err != tt.want {
+				t.Errorf("Err() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnchangedProgress(t *testing.T) {
+	got := unchangedProgress(1200)
+	want := Progress{Color: color.FgHiBlack, Label: "Unchanged", Final: true, Message: "1,200 resources"}
+	if got != want {
+		t.Errorf("unchangedProgress = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/briandowns/spinner"
+	"github.com/fatih/color"
+	"github.com/sst/ion/pkg/project"
+)
+
+type Renderer interface {
+	Start(mode ProgressMode)
+	Status(text string)
+	Progress(progress Progress)
+	Log(text string)
+	Finish(summary *ProgressSummary)
+}
+
+// set from the --output flag
+var outputFormat = ""
+
+func newRenderer() Renderer {
+	switch outputFormat {
+	case "json":
+		return &jsonRenderer{encoder: json.NewEncoder(os.Stdout)}
+	case "plain":
+		return &plainRenderer{}
+	case "tty":
+		return &ttyRenderer{}
+	}
+	if color.NoColor {
+		return &plainRenderer{}
+	}
+	return &ttyRenderer{}
+}
+
+// printJSON writes the result of a command for --output json
+func printJSON(value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+func progressStatus(mode ProgressMode) string {
+	switch mode {
+	case ProgressModeRemove:
+		return "Removing..."
+	case ProgressModeCancel:
+		return "Cancelling..."
+	case ProgressModeRefresh:
+		return "Refreshing..."
+	case ProgressModeDiff:
+		return "Diffing..."
+	}
+	return "Deploying..."
+}
+
+type ttyRenderer struct {
+	spin *spinner.Spinner
+}
+
+func (r *ttyRenderer) Start(mode ProgressMode) {
+	r.spin = spinner.New(spinner.CharSets[14], 100*time.Millisecond)
+	r.spin.Suffix = "  " + progressStatus(mode)
+	r.spin.Start()
+}
+
+func (r *ttyRenderer) Status(text string) {
+	r.spin.Suffix = "  " + text
+}
+
+func (r *ttyRenderer) Progress(progress Progress) {
+	r.spin.Disable()
+	defer r.spin.Enable()
+	// status updates and env changes hang off the line of the resource they
+	// belong to
+	if progress.Label == "Status" || progress.Label == "Env" {
+		color.New(color.FgHiBlack).Printf("|  %-11s ↳ %v %v\n", "", formatURN(progress.URN), progress.Message)
+		return
+	}
+	color.New(progress.Color, color.Bold).Print("|  ")
+	color.New(color.FgHiBlack).Print(fmt.Sprintf("%-11s", progress.Label))
+	if progress.URN != "" {
+		color.New(color.FgHiBlack).Print(" ", formatURN(progress.URN))
+	}
+	if progress.Duration != 0 {
+		color.New(color.FgHiBlack).Printf(" (%v)", formatDuration(progress.Duration))
+	}
+	if progress.Message != "" {
+		color.New(color.FgHiBlack).Print(" ", progress.Message)
+	}
+	fmt.Println()
+}
+
+func (r *ttyRenderer) Log(text string) {
+	r.spin.Disable()
+	defer r.spin.Enable()
+	fmt.Println(text)
+}
+
+func (r *ttyRenderer) Finish(summary *ProgressSummary) {
+	r.spin.Stop()
+	r.bundles(summary)
+	defer r.warnings(summary)
+
+	if summary.ConcurrentUpdate {
+		fmt.Println("Concurrent update detected, run `sst cancel` to delete lock file and retry.")
+		return
+	}
+
+	if len(summary.Errors) == 0 {
+		color.New(color.FgGreen, color.Bold).Print("\n✔")
+
+		if len(summary.Outputs) > 0 {
+			color.New(color.FgWhite, color.Bold).Printf("  Complete in %v:\n", formatDuration(summary.Duration))
+			for k, v := range summary.Outputs {
+				color.New(color.FgHiBlack).Print("   ")
+				color.New(color.FgHiBlack, color.Bold).Print(k + ": ")
+				color.New(color.FgWhite).Println(v)
+			}
+		} else {
+			color.New(color.FgWhite, color.Bold).Printf("  Complete in %v\n", formatDuration(summary.Duration))
+		}
+		return
+	}
+
+	color.New(color.FgRed, color.Bold).Print("\n❌")
+	color.New(color.FgWhite, color.Bold).Printf(" Failed after %v:\n", formatDuration(summary.Duration))
+
+	for _, status := range summary.Errors {
+		color.New(color.FgHiBlack).Print("   ")
+		if status.URN != "" {
+			color.New(color.FgRed, color.Bold).Print(formatURN(status.URN) + ": ")
+		}
+		color.New(color.FgWhite).Println(strings.TrimSpace(status.Error))
+	}
+}
+
+func (r *ttyRenderer) warnings(summary *ProgressSummary) {
+	if len(summary.Warnings) == 0 {
+		return
+	}
+	color.New(color.FgYellow, color.Bold).Print("\n!")
+	color.New(color.FgWhite, color.Bold).Println("  Warnings:")
+	for _, status := range summary.Warnings {
+		color.New(color.FgHiBlack).Print("   ")
+		if status.URN != "" {
+			color.New(color.FgYellow, color.Bold).Print(formatURN(status.URN) + ": ")
+		}
+		color.New(color.FgWhite).Println(status.Error)
+	}
+}
+
+func (r *ttyRenderer) bundles(summary *ProgressSummary) {
+	if len(summary.Bundles) == 0 {
+		return
+	}
+	color.New(color.FgMagenta, color.Bold).Print("\n➜")
+	color.New(color.FgWhite, color.Bold).Println("  Bundles:")
+	for _, bundle := range summary.Bundles {
+		color.New(color.FgHiBlack).Print("   ")
+		color.New(color.FgWhite, color.Bold).Printf("%-30s", bundle.Name)
+		color.New(color.FgHiBlack).Printf(" %9v  ~%v cold start\n", project.FormatSize(bundle.Size), formatDuration(bundle.ColdStart()))
+		for _, module := range bundle.Modules {
+			color.New(color.FgHiBlack).Printf("      %-27s %9v\n", module.Name, project.FormatSize(module.Size))
+		}
+	}
+}
+
+// plainRenderer writes one line per event with no spinner or colors, meant
+// for CI logs
+type plainRenderer struct{}
+
+func (r *plainRenderer) Start(mode ProgressMode) {
+	fmt.Println(progressStatus(mode))
+}
+
+func (r *plainRenderer) Status(text string) {
+	fmt.Println(text)
+}
+
+func (r *plainRenderer) Progress(progress Progress) {
+	line := fmt.Sprintf("|  %-11s", progress.Label)
+	if progress.URN != "" {
+		line += " " + formatURN(progress.URN)
+	}
+	if progress.Duration != 0 {
+		line += fmt.Sprintf(" (%v)", formatDuration(progress.Duration))
+	}
+	if progress.Message != "" {
+		line += " " + progress.Message
+	}
+	fmt.Println(line)
+}
+
+func (r *plainRenderer) Log(text string) {
+	fmt.Println(text)
+}
+
+func (r *plainRenderer) Finish(summary *ProgressSummary) {
+	r.bundles(summary)
+	defer r.warnings(summary)
+	if summary.ConcurrentUpdate {
+		fmt.Println("Concurrent update detected, run `sst cancel` to delete lock file and retry.")
+		return
+	}
+
+	if len(summary.Errors) == 0 {
+		fmt.Println()
+		fmt.Println("Complete in", formatDuration(summary.Duration))
+		for k, v := range summary.Outputs {
+			fmt.Printf("   %v: %v\n", k, v)
+		}
+		return
+	}
+
+	fmt.Println()
+	fmt.Println("Failed after", formatDuration(summary.Duration))
+	for _, status := range summary.Errors {
+		if status.URN != "" {
+			fmt.Printf("   %v: %v\n", formatURN(status.URN), strings.TrimSpace(status.Error))
+			continue
+		}
+		fmt.Printf("   %v\n", strings.TrimSpace(status.Error))
+	}
+}
+
+func (r *plainRenderer) warnings(summary *ProgressSummary) {
+	if len(summary.Warnings) == 0 {
+		return
+	}
+	fmt.Println()
+	fmt.Println("Warnings")
+	for _, status := range summary.Warnings {
+		if status.URN != "" {
+			fmt.Printf("   %v: %v\n", formatURN(status.URN), status.Error)
+			continue
+		}
+		fmt.Printf("   %v\n", status.Error)
+	}
+}
+
+func (r *plainRenderer) bundles(summary *ProgressSummary) {
+	if len(summary.Bundles) == 0 {
+		return
+	}
+	fmt.Println()
+	fmt.Println("Bundles")
+	for _, bundle := range summary.Bundles {
+		fmt.Printf("   %v: %v, ~%v cold start\n", bundle.Name, project.FormatSize(bundle.Size), formatDuration(bundle.ColdStart()))
+		for _, module := range bundle.Modules {
+			fmt.Printf("      %v: %v\n", module.Name, project.FormatSize(module.Size))
+		}
+	}
+}
+
+// jsonRenderer writes newline delimited JSON for other tools to consume
+type jsonRenderer struct {
+	encoder *json.Encoder
+}
+
+type jsonLine struct {
+	Type     string           `json:"type"`
+	Mode     ProgressMode     `json:"mode,omitempty"`
+	Text     string           `json:"text,omitempty"`
+	Progress *Progress        `json:"progress,omitempty"`
+	Summary  *ProgressSummary `json:"summary,omitempty"`
+}
+
+func (r *jsonRenderer) Start(mode ProgressMode) {
+	r.encoder.Encode(jsonLine{Type: "start", Mode: mode})
+}
+
+func (r *jsonRenderer) Status(text string) {
+	r.encoder.Encode(jsonLine{Type: "status", Text: text})
+}
+
+func (r *jsonRenderer) Progress(progress Progress) {
+	r.encoder.Encode(jsonLine{Type: "progress", Progress: &progress})
+}
+
+func (r *jsonRenderer) Log(text string) {
+	r.encoder.Encode(jsonLine{Type: "log", Text: text})
+}
+
+func (r *jsonRenderer) Finish(summary *ProgressSummary) {
+	r.encoder.Encode(jsonLine{Type: "summary", Summary: summary})
+}
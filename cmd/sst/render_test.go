@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/sst/ion/pkg/project"
+)
+
+// captureStdout returns everything fn prints
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	read, write, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := os.Stdout
+	os.Stdout = write
+	defer func() { os.Stdout = original }()
+
+	done := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(read)
+		done <- data
+	}()
+	fn()
+	write.Close()
+	return string(<-done)
+}
+
+func TestPlainRenderer(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(r Renderer)
+		want string
+	}{
+		{
+			name: "start",
+			run:  func(r Renderer) { r.Start(ProgressModeRefresh) },
+			want: "Refreshing...\n",
+		},
+		{
+			name: "progress",
+			run: func(r Renderer) {
+				r.Progress(Progress{Label: "Created", URN: bucketURN, Duration: 1500 * time.Millisecond})
+				r.Progress(Progress{Label: "Unchanged", Message: "3 resources"})
+			},
+			want: "|  Created     aws:s3:BucketV2 → Bucket (1.5s)\n" +
+				"|  Unchanged   3 resources\n",
+		},
+		{
+			name: "complete",
+			run: func(r Renderer) {
+				r.Finish(&ProgressSummary{
+					Duration: 2 * time.Second,
+					Outputs:  map[string]interface{}{"url": "https://example.com"},
+				})
+			},
+			want: "\nComplete in 2s\n   url: https://example.com\n",
+		},
+		{
+			name: "failed",
+			run: func(r Renderer) {
+				r.Finish(&ProgressSummary{
+					Duration: 2 * time.Second,
+					Errors: []ProgressError{
+						{Error: "BucketAlreadyExists ", URN: bucketURN},
+						{Error: "config error"},
+					},
+					Warnings: []ProgressError{{Error: "deprecated"}},
+				})
+			},
+			want: "\nFailed after 2s\n" +
+				"   aws:s3:BucketV2 → Bucket: BucketAlreadyExists\n" +
+				"   config error\n" +
+				"\nWarnings\n" +
+				"   deprecated\n",
+		},
+		{
+			name: "bundles",
+			run: func(r Renderer) {
+				r.Finish(&ProgressSummary{
+					Bundles: []project.BundleEvent{{
+						Name:    "Fn",
+						Size:    2 * 1024 * 1024,
+						Modules: []project.BundleModule{{Name: "aws-sdk", Size: 1024}},
+					}},
+				})
+			},
+			want: "\nBundles\n" +
+				"   Fn: 2.0 MB, ~50ms cold start\n" +
+				"      aws-sdk: 1.0 KB\n" +
+				"\nComplete in 0ms\n",
+		},
+		{
+			name: "concurrent update",
+			run: func(r Renderer) {
+				r.Finish(&ProgressSummary{ConcurrentUpdate: true})
+			},
+			want: "Concurrent update detected, run `sst cancel` to delete lock file and retry.\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := captureStdout(t, func() { tt.run(&plainRenderer{}) })
+			if got != tt.want {
+				t.Errorf("got\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONRenderer(t *testing.T) {
+	var out bytes.Buffer
+	r := &jsonRenderer{encoder: json.NewEncoder(&out)}
+	r.Start(ProgressModeDeploy)
+	r.Status("Finalizing...")
+	r.Log("hello")
+	r.Progress(Progress{Label: "Created", URN: bucketURN, Final: true, Duration: time.Second})
+	r.Finish(&ProgressSummary{
+		Errors:   []ProgressError{},
+		Warnings: []ProgressError{},
+		Outputs:  map[string]interface{}{"url": "https://example.com"},
+		Duration: time.Second,
+	})
+
+	want := []string{
+		`{"type":"start","mode":"deploy"}`,
+		`{"type":"status","text":"Finalizing..."}`,
+		`{"type":"log","text":"hello"}`,
+		`{"type":"progress","progress":{"label":"Created","urn":"` + bucketURN + `","final":true,"duration":1000000000}}`,
+		`{"type":"summary","summary":{"errors":[],"warnings":[],"outputs":{"url":"https://example.com"},"duration":1000000000}}`,
+	}
+	lines := bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n"))
+	if len(lines) != len(want) {
+		t.Fatalf("got %v lines, want %v:\n%s", len(lines), len(want), out.String())
+	}
+	for i, line := range lines {
+		if string(line) != want[i] {
+			t.Errorf("line %v = %s, want %s", i, line, want[i])
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/sst/ion/pkg/project"
+)
+
+func printResources(resources []project.Resource) {
+	fmt.Println()
+	if len(resources) == 0 {
+		color.New(color.FgHiBlack).Println("   No matching resources")
+		return
+	}
+	for _, resource := range resources {
+		color.New(color.FgWhite, color.Bold).Printf("   %-40s", resource.Name)
+		color.New(color.FgHiBlack).Printf(" %-40s", resource.Type)
+		color.New(color.FgHiBlack).Println(" " + resource.ID)
+	}
+	fmt.Println()
+	color.New(color.FgHiBlack).Printf("   %v resources\n", len(resources))
+}
+
+func printResource(resource *project.Resource) error {
+	fmt.Println()
+	color.New(color.FgCyan, color.Bold).Print("➜  ")
+	color.New(color.FgWhite, color.Bold).Println(resource.Name)
+	printStatus("URN:", resource.URN)
+	printStatus("Type:", resource.Type)
+	if resource.ID != "" {
+		printStatus("ID:", resource.ID)
+	}
+	if resource.Component != "" {
+		printStatus("Component:", resource.Component)
+	} else if resource.Parent != "" {
+		printStatus("Parent:", resource.Parent)
+	}
+	if resource.Created != nil {
+		printStatus("Created:", resource.Created.Local().Format(time.RFC1123))
+	}
+	if resource.Modified != nil {
+		printStatus("Modified:", resource.Modified.Local().Format(time.RFC1123))
+	}
+	for _, section := range []struct {
+		label  string
+		values map[string]interface{}
+	}{
+		{"Inputs", resource.Inputs},
+		{"Outputs", resource.Outputs},
+	} {
+		if len(section.values) == 0 {
+			continue
+		}
+		data, err := json.MarshalIndent(section.values, "   ", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println()
+		color.New(color.FgWhite, color.Bold).Println("   " + section.label)
+		color.New(color.FgHiBlack).Println("   " + strings.TrimSpace(string(data)))
+	}
+	return nil
+}
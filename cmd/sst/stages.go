@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/sst/ion/pkg/project"
+	cli "github.com/urfave/cli/v2"
+)
+
+// stageNames reads --stage and --stages-from, the file has a stage per line
+// and lines starting with # are ignored
+func stageNames(c *cli.Context) ([]string, error) {
+	result := []string{}
+	add := func(stage string) {
+		stage = strings.TrimSpace(stage)
+		if stage != "" && !strings.HasPrefix(stage, "#") && !slices.Contains(result, stage) {
+			result = append(result, stage)
+		}
+	}
+	for _, stage := range c.StringSlice("stage") {
+		add(stage)
+	}
+	path := c.String("stages-from")
+	if path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			add(scanner.Text())
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		if len(result) == 0 {
+			return nil, userErrorf("No stages found in %v", path)
+		}
+	}
+	return result, nil
+}
+
+// multiStage runs the command against every stage at the same time, each
+// stage gets its own js process
+func multiStage(p *project.Project, mode ProgressMode, names []string, run func(child *project.Project) (project.StackEventStream, error), after parallelAfter) bool {
+	header := *p.App()
+	header.Stage = strings.Join(names, ", ")
+	printHeader(&header)
+	fmt.Println(progressStatus(mode), len(names), "stages")
+	return parallel(mode, names, 0, func(i int) (*project.Project, error) {
+		return p.ForStage(names[i])
+	}, run, after)
+}
+
+var stageFlags = []cli.Flag{
+	&cli.StringSliceFlag{
+		Name:  "stage",
+		Usage: "Stage to run against instead of the default, repeat it to run against several stages in parallel",
+	},
+	&cli.StringFlag{
+		Name:  "stages-from",
+		Usage: "File with a stage per line to run against in parallel",
+	},
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/sst/ion/pkg/telemetry"
+
+	cli "github.com/urfave/cli/v2"
+)
+
+// commandName is the full name of the command in args, like "bootstrap" or
+// "migrate cfn", without any of its arguments
+func commandName(app *cli.App, args []string) string {
+	names := []string{}
+	commands := app.Commands
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-") {
+			continue
+		}
+		var match *cli.Command
+		for _, cmd := range commands {
+			if cmd.HasName(arg) {
+				match = cmd
+				break
+			}
+		}
+		if match == nil {
+			continue
+		}
+		names = append(names, match.Name)
+		commands = match.Subcommands
+		if len(commands) == 0 {
+			break
+		}
+	}
+	return strings.Join(names, " ")
+}
+
+func printTelemetry() {
+	switch {
+	case telemetry.Killed():
+		printStatus("Telemetry:", "disabled by environment variable")
+	case telemetry.Enabled():
+		printStatus("Telemetry:", "enabled")
+	default:
+		printStatus("Telemetry:", "disabled")
+	}
+}
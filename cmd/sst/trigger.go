@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/sst/ion/pkg/project"
+)
+
+// printTrigger returns false if one of the invocations failed
+func printTrigger(results []project.TriggerResult) bool {
+	ok := true
+	for _, result := range results {
+		fmt.Println()
+		if result.FunctionError != "" {
+			ok = false
+			color.New(color.FgRed, color.Bold).Print("❌ ")
+		} else {
+			color.New(color.FgGreen, color.Bold).Print("✔  ")
+		}
+		color.New(color.FgWhite, color.Bold).Println(result.Function)
+		for _, line := range strings.Split(strings.TrimSpace(result.Logs), "\n") {
+			if line == "" {
+				continue
+			}
+			color.New(color.FgHiBlack).Println("   " + line)
+		}
+		if len(result.Payload) > 0 && string(result.Payload) != "null" {
+			printStatus("Response:", string(result.Payload))
+		}
+	}
+	return ok
+}
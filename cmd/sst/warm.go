@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/sst/ion/pkg/project"
+)
+
+func printWarm(mode string, statuses []project.WarmStatus) {
+	fmt.Println()
+	switch mode {
+	case "off":
+		printStatus("Warming:", "scaled down, run `sst warm up` to restore")
+	case "personal":
+		printStatus("Warming:", "personal stage, only functions with `personal: true` are kept warm")
+	default:
+		printStatus("Warming:", "on")
+	}
+	if len(statuses) == 0 {
+		color.New(color.FgHiBlack).Println("   No functions have warm settings")
+		return
+	}
+	fmt.Println()
+	for _, status := range statuses {
+		color.New(color.FgCyan, color.Bold).Print("➜  ")
+		color.New(color.FgWhite, color.Bold).Println(status.Function)
+		if status.Configured > 0 {
+			live := "not deployed"
+			if status.Status != "" {
+				live = fmt.Sprintf("%v of %v ready, %v", status.Available, status.Requested, status.Status)
+			} else if status.Alias != "" {
+				live = "off"
+			}
+			printStatus("Provisioned:", fmt.Sprintf("%v configured, %v", status.Configured, live))
+		}
+		if status.Schedule != "" {
+			printStatus("Schedule:", fmt.Sprintf("every %v, %v instances", status.Schedule, status.Concurrency))
+		}
+	}
+}
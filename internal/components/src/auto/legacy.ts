@@ -0,0 +1,26 @@
+import { Stack } from "@pulumi/pulumi/automation/index.js";
+
+// sites used to upload every asset as its own BucketObject, they are now a
+// single BucketFiles resource that writes the same keys. The engine deletes
+// the old objects at the end of the update, after the new resource uploaded
+// them, so they are marked to be retained and only leave the state.
+export async function retainLegacyAssets(stack: Stack) {
+  const state = await stack.exportStack();
+  const resources: any[] = state.deployment?.resources ?? [];
+  const parents = new Map<string, string>(
+    resources.map((resource) => [resource.urn, resource.type])
+  );
+  let changed = 0;
+  for (const resource of resources) {
+    if (resource.type !== "aws:s3/bucketObject:BucketObject") continue;
+    if (resource.retainOnDelete) continue;
+    const name = resource.urn.split("::").pop() ?? "";
+    if (!name.includes("-asset-")) continue;
+    if (!parents.get(resource.parent)?.startsWith("sst:sst:")) continue;
+    resource.retainOnDelete = true;
+    changed++;
+  }
+  if (!changed) return;
+  console.debug(`retaining ${changed} legacy site assets`);
+  await stack.importStack(state);
+}
@@ -0,0 +1,207 @@
+import { Stack } from "@pulumi/pulumi/automation/index.js";
+import {
+  ServiceQuotasClient,
+  GetServiceQuotaCommand,
+  GetAWSDefaultServiceQuotaCommand,
+} from "@aws-sdk/client-service-quotas";
+import {
+  EC2Client,
+  DescribeVpcsCommand,
+  DescribeAddressesCommand,
+} from "@aws-sdk/client-ec2";
+import {
+  LambdaClient,
+  GetAccountSettingsCommand,
+} from "@aws-sdk/client-lambda";
+import {
+  CloudFrontClient,
+  ListDistributionsCommand,
+} from "@aws-sdk/client-cloudfront";
+
+interface Rule {
+  name: string;
+  type: string;
+  serviceCode: string;
+  quotaCode: string;
+  fallback: number;
+  // cloudfront quotas only exist in us-east-1
+  global?: boolean;
+  // how much of the quota a planned resource uses, defaults to 1
+  weight?: (inputs: Record<string, any>) => number;
+  usage: (region: string) => Promise<number>;
+}
+
+const rules: Rule[] = [
+  {
+    name: "Lambda concurrent executions",
+    type: "aws:lambda/function:Function",
+    serviceCode: "lambda",
+    quotaCode: "L-B99A9384",
+    fallback: 1000,
+    weight: (inputs) => Math.max(inputs.reservedConcurrentExecutions ?? 0, 0),
+    async usage(region) {
+      const client = new LambdaClient({ region });
+      const result = await client.send(new GetAccountSettingsCommand({}));
+      // lambda keeps 10 executions unreserved at all times
+      return (
+        result.AccountLimit!.ConcurrentExecutions! -
+        result.AccountLimit!.UnreservedConcurrentExecutions! +
+        10
+      );
+    },
+  },
+  {
+    name: "VPCs per region",
+    type: "aws:ec2/vpc:Vpc",
+    serviceCode: "vpc",
+    quotaCode: "L-F678F1CE",
+    fallback: 5,
+    async usage(region) {
+      const client = new EC2Client({ region });
+      let count = 0;
+      let token: string | undefined;
+      do {
+        const result = await client.send(
+          new DescribeVpcsCommand({ NextToken: token })
+        );
+        count += result.Vpcs?.length ?? 0;
+        token = result.NextToken;
+      } while (token);
+      return count;
+    },
+  },
+  {
+    name: "Elastic IP addresses",
+    type: "aws:ec2/eip:Eip",
+    serviceCode: "ec2",
+    quotaCode: "L-0263D0A3",
+    fallback: 5,
+    async usage(region) {
+      const client = new EC2Client({ region });
+      const result = await client.send(new DescribeAddressesCommand({}));
+      return result.Addresses?.length ?? 0;
+    },
+  },
+  {
+    name: "CloudFront distributions",
+    type: "aws:cloudfront/distribution:Distribution",
+    serviceCode: "cloudfront",
+    quotaCode: "L-24B04930",
+    fallback: 200,
+    global: true,
+    async usage() {
+      const client = new CloudFrontClient({ region: "us-east-1" });
+      let count = 0;
+      let marker: string | undefined;
+      do {
+        const result = await client.send(
+          new ListDistributionsCommand({ Marker: marker })
+        );
+        count += result.DistributionList?.Quantity ?? 0;
+        marker = result.DistributionList?.NextMarker;
+      } while (marker);
+      return count;
+    },
+  },
+];
+
+async function limit(rule: Rule, region: string) {
+  const client = new ServiceQuotasClient({
+    region: rule.global ? "us-east-1" : region,
+  });
+  const input = { ServiceCode: rule.serviceCode, QuotaCode: rule.quotaCode };
+  const applied = await client
+    .send(new GetServiceQuotaCommand(input))
+    .catch(() => undefined);
+  if (applied?.Quota?.Value !== undefined) return applied.Quota.Value;
+  const fallback = await client
+    .send(new GetAWSDefaultServiceQuotaCommand(input))
+    .catch(() => undefined);
+  return fallback?.Quota?.Value ?? rule.fallback;
+}
+
+// checkQuotas previews the update and compares what it creates against the
+// account's service quotas, exceeded quotas are reported to the cli. It
+// returns false if the update should not start. A failing preview is
+// reported like a failing deploy and rethrown.
+export async function checkQuotas(stack: Stack) {
+  const planned: Record<string, number> = {};
+  let reported = false;
+  try {
+    await stack.preview({
+      onEvent: (evt) => {
+        if (evt.diagnosticEvent?.severity === "error") {
+          reported = true;
+          console.log("~j" + JSON.stringify(evt));
+          return;
+        }
+        const metadata = evt.resourcePreEvent?.metadata;
+        if (!metadata) return;
+        if (metadata.op !== "create" && metadata.op !== "create-replacement")
+          return;
+        const rule = rules.find((rule) => rule.type === metadata.type);
+        if (!rule) return;
+        const inputs = metadata.new?.inputs ?? {};
+        planned[rule.type] =
+          (planned[rule.type] ?? 0) + (rule.weight ? rule.weight(inputs) : 1);
+      },
+    });
+  } catch (e: any) {
+    if (!reported && e.name !== "ConcurrentUpdateError") {
+      console.log(
+        "~j" +
+          JSON.stringify({
+            diagnosticEvent: {
+              severity: "error",
+              color: "never",
+              message: `Failed to preview the deploy to check quotas: ${e.message}`,
+            },
+          })
+      );
+    }
+    throw e;
+  }
+
+  const region = $app.providers?.aws?.region ?? process.env.AWS_DEFAULT_REGION!;
+  let ok = true;
+  for (const rule of rules) {
+    if (!planned[rule.type]) continue;
+    let quota: number;
+    let usage: number;
+    try {
+      [quota, usage] = await Promise.all([
+        limit(rule, region),
+        rule.usage(region),
+      ]);
+    } catch (e: any) {
+      // missing read permissions should not block the deploy
+      console.log(
+        "~j" +
+          JSON.stringify({
+            QuotaEvent: {
+              Name: rule.name,
+              Planned: planned[rule.type],
+              Error: e.message ?? String(e),
+            },
+          })
+      );
+      continue;
+    }
+    if (usage + planned[rule.type] <= quota) continue;
+    const fail = $app.quotas === "fail";
+    if (fail) ok = false;
+    console.log(
+      "~j" +
+        JSON.stringify({
+          QuotaEvent: {
+            Name: rule.name,
+            Limit: quota,
+            Usage: usage,
+            Planned: planned[rule.type],
+            Fail: fail,
+          },
+        })
+    );
+  }
+  return ok;
+}
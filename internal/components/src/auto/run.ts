@@ -1,6 +1,10 @@
 import { LocalWorkspace } from "@pulumi/pulumi/automation/index.js";
-import { runtime } from "@pulumi/pulumi";
+import { output, runtime } from "@pulumi/pulumi";
 import { PulumiFn } from "@pulumi/pulumi/automation";
+import { Links } from "../components/helpers/links";
+import { checkQuotas } from "./quota";
+import { retainLegacyAssets } from "./legacy";
+import { InjectedFailure } from "../components/providers/injected-failure";
 
 export async function run(program: PulumiFn) {
   const config: Record<string, { value: string }> = {};
@@ -23,6 +27,11 @@ export async function run(program: PulumiFn) {
   const stack = await LocalWorkspace.createOrSelectStack(
     {
       program: async () => {
+        const functions = new Set<string>();
+        runtime.registerStackTransformation((args) => {
+          if (args.type === "sst:sst:Function") functions.add(args.name);
+          return undefined;
+        });
         runtime.registerStackTransformation((args) => {
           if (
             $app.removalPolicy === "retain-all" ||
@@ -41,7 +50,57 @@ export async function run(program: PulumiFn) {
           return undefined;
         });
 
-        return program();
+        if ($cli.injectFailure) {
+          const target = $cli.injectFailure;
+          const injected = new Set<string>();
+          runtime.registerStackTransformation((args) => {
+            if (injected.size || args.type.startsWith("sst:")) return undefined;
+            if (
+              args.type !== target &&
+              args.name !== target &&
+              !target.endsWith("::" + args.name)
+            )
+              return undefined;
+            injected.add(args.name);
+            const failure = new InjectedFailure(`${args.name}-failure`, {
+              target,
+              ready: output(args.props).apply(() => true),
+            });
+            return {
+              props: args.props,
+              opts: util.mergeOptions({ dependsOn: [failure] }, args.opts),
+            };
+          });
+        }
+
+        let outputs: Awaited<ReturnType<PulumiFn>>;
+        try {
+          outputs = await program();
+          for (const [name, fn] of Object.entries($app.functions ?? {})) {
+            if (!functions.has(name))
+              throw new Error(
+                `Function "${name}" is configured in app.functions but does not exist`
+              );
+            for (const key of fn.link ?? []) {
+              if (!outputs || !(key in outputs))
+                throw new Error(
+                  `Function "${name}" links to "${key}" which is not an output returned from run()`
+                );
+            }
+          }
+        } catch (e) {
+          // settle the links so function environments waiting on them fail
+          // with the program instead of hanging
+          Links.reject(e as Error);
+          throw e;
+        }
+        Links.resolve(outputs ?? {});
+
+        if (!$cli.git) return outputs;
+        return {
+          ...outputs,
+          _git: $cli.git,
+        };
       },
       projectName: $app.name,
       stackName: $app.stage,
@@ -68,11 +127,32 @@ export async function run(program: PulumiFn) {
   );
 
   try {
+    if (
+      $cli.command === "up" &&
+      ($app.quotas === "warn" || $app.quotas === "fail")
+    ) {
+      if (!(await checkQuotas(stack))) return;
+    }
+    if ($cli.command === "up") await retainLegacyAssets(stack);
+    if ($cli.command === "up" && $cli.drift.length) {
+      await stack.refresh({
+        target: $cli.drift,
+        onEvent: (evt) => {
+          console.log("~j" + JSON.stringify(evt));
+        },
+      });
+    }
     await stack[$cli.command as "up"]({
       onEvent: (evt) => {
         console.log("~j" + JSON.stringify(evt));
       },
       logVerbosity: 11,
+      target: $cli.target.length ? $cli.target : undefined,
+      message: $cli.git
+        ? `deployed from ${$cli.git.commit.substring(0, 7)}${
+            $cli.git.dirty ? " (dirty)" : ""
+          }`
+        : undefined,
     });
   } catch (e: any) {
     if (e.name === "ConcurrentUpdateError") {
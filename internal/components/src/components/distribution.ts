@@ -10,6 +10,7 @@ import * as aws from "@pulumi/aws";
 import { DnsValidatedCertificate } from "./dns-validated-certificate.js";
 import { HttpsRedirect } from "./https-redirect.js";
 import { AWS } from "./helpers/aws.js";
+import { DnsProvider, dnsRecord } from "./helpers/dns.js";
 
 export interface DistributionDomainArgs {
   /**
@@ -69,6 +70,21 @@ export interface DistributionDomainArgs {
    * ```
    */
   hostedZoneId?: Input<string>;
+  /**
+   * Where the DNS records of the domain are managed. For Cloudflare the
+   * "hostedZoneId" is the Cloudflare zone id, and the zone is looked up from
+   * the domain name when not set. With external DNS run `sst domains` to get
+   * the records to add.
+   * @default "route53"
+   * @example
+   * ```js
+   * customDomain: {
+   *   domainName: "domain.com",
+   *   dns: "cloudflare",
+   * }
+   * ```
+   */
+  dns?: Input<DnsProvider>;
 }
 
 export interface DistributionArgs {
@@ -116,7 +132,7 @@ export class Distribution extends ComponentResource {
     const zoneId = lookupHostedZoneId();
     const certificate = createCertificate();
     const distribution = createDistribution();
-    createRoute53Records();
+    createDnsRecords();
     createRedirects();
 
     this.distribution = distribution;
@@ -129,7 +145,12 @@ export class Distribution extends ComponentResource {
 
       return output(args.customDomain).apply((customDomain) => {
         if (typeof customDomain === "string") {
-          return { domainName: customDomain, aliases: [], redirects: [] };
+          return {
+            domainName: customDomain,
+            aliases: [],
+            redirects: [],
+            dns: "route53" as DnsProvider,
+          };
         }
 
         if (!customDomain.domainName) {
@@ -138,7 +159,13 @@ export class Distribution extends ComponentResource {
         if (customDomain.hostedZone && customDomain.hostedZoneId) {
           throw new Error(`Do not set both "hostedZone" and "hostedZoneId".`);
         }
-        return { aliases: [], redirects: [], ...customDomain };
+        const dns = customDomain.dns ?? "route53";
+        if (dns !== "route53" && customDomain.redirects?.length) {
+          throw new Error(
+            `Redirects are only supported for domains hosted on Route 53.`
+          );
+        }
+        return { aliases: [], redirects: [], ...customDomain, dns };
       });
     }
 
@@ -165,6 +192,7 @@ export class Distribution extends ComponentResource {
 
       return output(customDomain).apply(async (customDomain) => {
         if (customDomain.hostedZoneId) return customDomain.hostedZoneId;
+        if (customDomain.dns !== "route53") return undefined;
 
         const zoneName = customDomain.hostedZone ?? customDomain.domainName;
         const zone = await aws.route53.getZone({ name: zoneName });
@@ -173,7 +201,7 @@ export class Distribution extends ComponentResource {
     }
 
     function createCertificate() {
-      if (!customDomain || !zoneId) return;
+      if (!customDomain) return;
 
       // Certificates used for CloudFront distributions are required to be
       // created in the us-east-1 region
@@ -183,6 +211,7 @@ export class Distribution extends ComponentResource {
           domainName: customDomain.domainName,
           alternativeNames: customDomain.aliases,
           zoneId,
+          dns: customDomain.dns,
         },
         { parent, provider: AWS.useProvider("us-east-1") }
       );
@@ -213,34 +242,32 @@ export class Distribution extends ComponentResource {
       );
     }
 
-    function createRoute53Records(): void {
-      if (!customDomain || !zoneId) {
+    function createDnsRecords(): void {
+      if (!customDomain) {
         return;
       }
 
       // Create DNS record
       output(customDomain).apply((customDomain) => {
+        // Route 53 aliases both address types, other providers get a CNAME
+        const types =
+          customDomain.dns === "route53" ? ["A", "AAAA"] : ["CNAME"];
         for (const recordName of [
           customDomain.domainName,
           ...customDomain.aliases,
         ]) {
-          for (const type of ["A", "AAAA"]) {
-            new aws.route53.Record(
-              `${name}-record-${recordName}-${type}`,
-              {
-                name: recordName,
-                zoneId,
-                type,
-                aliases: [
-                  {
-                    name: distribution.domainName,
-                    zoneId: distribution.hostedZoneId,
-                    evaluateTargetHealth: true,
-                  },
-                ],
+          for (const type of types) {
+            dnsRecord(parent, `${name}-record-${recordName}-${type}`, {
+              dns: customDomain.dns,
+              zoneId,
+              name: recordName,
+              type,
+              value: distribution.domainName,
+              alias: {
+                name: distribution.domainName,
+                zoneId: distribution.hostedZoneId,
               },
-              { parent }
-            );
+            });
           }
         }
       });
@@ -257,7 +284,8 @@ export class Distribution extends ComponentResource {
         new HttpsRedirect(
           `${name}-redirect`,
           {
-            zoneId,
+            // redirects are only allowed on Route 53
+            zoneId: zoneId as Output<string>,
             sourceDomains: customDomain.redirects,
             targetDomain: customDomain.domainName,
           },
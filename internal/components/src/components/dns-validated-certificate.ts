@@ -3,9 +3,11 @@ import {
   Output,
   ComponentResource,
   ComponentResourceOptions,
+  all,
 } from "@pulumi/pulumi";
 import * as aws from "@pulumi/aws";
 import { CertificateValidation } from "@pulumi/aws/acm";
+import { DnsProvider, dnsRecord } from "./helpers/dns.js";
 
 /**
  * Properties to create a DNS validated certificate managed by AWS Certificate Manager.
@@ -18,8 +20,15 @@ export interface DnsValidatedCertificateArgs {
   /**
    * Route 53 Hosted Zone used to perform DNS validation of the request.  The zone
    * must be authoritative for the domain name specified in the Certificate Request.
+   * For Cloudflare this is the zone id, it is looked up when not set.
    */
-  zoneId: Input<string>;
+  zoneId?: Input<string | undefined>;
+  /**
+   * Where the validation records are created. With external DNS the
+   * validation waits until the records from `sst domains` are added.
+   * @default "route53"
+   */
+  dns?: Input<DnsProvider>;
   /**
    * Set of domains that should be SANs in the issued certificate
    */
@@ -45,7 +54,7 @@ export class DnsValidatedCertificate extends ComponentResource {
     super("sst:sst:Certificate", name, args, opts);
 
     const parent = this;
-    const { domainName, alternativeNames, zoneId, region } = args;
+    const { domainName, alternativeNames, zoneId, region, dns } = args;
 
     const certificate = new aws.acm.Certificate(
       `${name}-certificate`,
@@ -57,22 +66,21 @@ export class DnsValidatedCertificate extends ComponentResource {
       { parent }
     );
 
-    const records: aws.route53.Record[] = [];
-    certificate.domainValidationOptions.apply((options) => {
+    const fqdns: Output<string>[] = [];
+    all([certificate.domainValidationOptions, dns]).apply(([options, dns]) => {
       options.forEach((option) => {
-        records.push(
-          new aws.route53.Record(
-            `${name}-record-${option.resourceRecordName}`,
-            {
-              name: option.resourceRecordName,
-              zoneId,
-              type: option.resourceRecordType,
-              records: [option.resourceRecordValue],
-              ttl: 60,
-            },
-            { parent }
-          )
+        const fqdn = dnsRecord(
+          parent,
+          `${name}-record-${option.resourceRecordName}`,
+          {
+            dns: dns ?? "route53",
+            zoneId,
+            name: option.resourceRecordName,
+            type: option.resourceRecordType,
+            value: option.resourceRecordValue,
+          }
         );
+        if (fqdn) fqdns.push(fqdn);
       });
     });
 
@@ -80,7 +88,7 @@ export class DnsValidatedCertificate extends ComponentResource {
       `${name}-validation`,
       {
         certificateArn: certificate.arn,
-        validationRecordFqdns: records.map((record) => record.fqdn),
+        validationRecordFqdns: fqdns,
       },
       { parent }
     );
@@ -15,8 +15,15 @@ import {
 } from "@pulumi/pulumi";
 import * as aws from "@pulumi/aws";
 import { FunctionCodeUpdater } from "./providers/function-code-updater.js";
+import {
+  FunctionCanary,
+  FunctionCanaryStrategy,
+} from "./providers/function-canary.js";
 import { AWS } from "./helpers/aws.js";
+import { Links } from "./helpers/links.js";
+import { reportBundle } from "./helpers/bundle.js";
 import { LogGroup } from "./providers/log-group.js";
+import { FunctionProvisioned } from "./providers/function-provisioned.js";
 import { Duration, toSeconds } from "./util/duration.js";
 import { Size, toMBs } from "./util/size.js";
 
@@ -221,6 +228,26 @@ export interface FunctionLoggingArgs {
   retention?: Input<keyof typeof RETENTION>;
 }
 
+export interface FunctionCanaryArgs {
+  /**
+   * How traffic is shifted to a new version of the function.
+   * @default `"Canary10Percent5Minutes"`
+   * @example
+   * ```js
+   * {
+   *   strategy: "Linear10PercentEvery1Minute"
+   * }
+   * ```
+   */
+  strategy?: Input<FunctionCanaryStrategy>;
+  /**
+   * Names of CloudWatch alarms to watch while traffic is shifting. If any of
+   * them goes into the `ALARM` state, traffic is moved back to the previous
+   * version and the deploy fails.
+   */
+  alarms?: Input<string[]>;
+}
+
 export interface FunctionArgs {
   description?: Input<string>;
   runtime?: Input<"nodejs18.x" | "nodejs20.x">;
@@ -257,6 +284,22 @@ export interface FunctionArgs {
    * ```
    */
   url?: Input<boolean | FunctionUrlArgs>;
+  /**
+   * Gradually shift traffic to new versions of the function through a `live`
+   * alias, instead of updating it in place. The function url and `arn` point
+   * to the alias, invoke it through them rather than `nodes.function`.
+   * @default Disabled
+   * @example
+   * ```js
+   * {
+   *   canary: {
+   *     strategy: "Canary10Percent10Minutes",
+   *     alarms: ["api-errors"]
+   *   }
+   * }
+   * ```
+   */
+  canary?: Input<true | FunctionCanaryArgs>;
   /**
    * Used to configure nodejs function properties
    */
@@ -270,6 +313,7 @@ export class Function extends ComponentResource {
   private function: Output<aws.lambda.Function>;
   private role: aws.iam.Role;
   private fnUrl: Output<aws.lambda.FunctionUrl | undefined>;
+  private invokeArn: Output<string>;
   private missingSourcemap?: boolean;
 
   constructor(
@@ -281,6 +325,7 @@ export class Function extends ComponentResource {
 
     const parent = this;
     const region = normalizeRegion();
+    const warm = normalizeWarm();
     const injections = normalizeInjections();
     const runtime = normalizeRuntime();
     const timeout = normalizeTimeout();
@@ -289,6 +334,7 @@ export class Function extends ComponentResource {
     const streaming = normalizeStreaming();
     const logging = normalizeLogging();
     const url = normalizeUrl();
+    const canary = normalizeCanary();
 
     const bindInjection = bind();
     const newHandler = wrapHandler();
@@ -297,14 +343,23 @@ export class Function extends ComponentResource {
     const bundleHash = args.bundleHash ?? calculateHash();
     const file = createBucketObject();
     const fnRaw = createFunction();
-    const fn = updateFunctionCode();
+    const code = updateFunctionCode();
+    const fn = code.apply((code) => code.fn);
+    const invokeArn = code.apply((code) =>
+      code.alias
+        ? interpolate`${code.fn.arn}:${code.alias.alias}`
+        : code.fn.arn
+    );
 
     createLogGroup();
+    createProvisioned();
+    createWarmer();
     const fnUrl = createUrl();
 
     this.function = fn;
     this.role = role;
     this.fnUrl = fnUrl;
+    this.invokeArn = invokeArn;
 
     function normalizeRegion() {
       return all([
@@ -314,7 +369,17 @@ export class Function extends ComponentResource {
     }
 
     function normalizeInjections() {
-      return output(args.injections).apply((injections) => injections ?? []);
+      return output(args.injections).apply((injections) => [
+        ...(injections ?? []),
+        // scheduled warm invocations return before reaching the handler
+        ...(warm.schedule
+          ? [
+              `if (event.type === "warmer") {`,
+              `  return new Promise((resolve) => setTimeout(() => resolve({ warmed: true }), 100));`,
+              `}`,
+            ]
+          : []),
+      ]);
     }
 
     function normalizeRuntime() {
@@ -330,7 +395,31 @@ export class Function extends ComponentResource {
     }
 
     function normalizeEnvironment() {
-      return output(args.environment).apply((environment) => environment ?? {});
+      const config = $app.functions?.[name];
+      return all([args.environment, linkEnvironment()]).apply(
+        ([environment, linked]) => ({
+          ...environment,
+          ...config?.environment,
+          ...linked,
+        })
+      );
+    }
+
+    function linkEnvironment() {
+      const link = $app.functions?.[name]?.link ?? [];
+      if (link.length === 0) return output({} as Record<string, string>);
+
+      return output(Links.outputs()).apply((outputs) =>
+        all(link.map((key) => outputs[key])).apply((values) => {
+          const acc: Record<string, string> = {};
+          link.forEach((key, index) => {
+            const value = values[index];
+            acc[`SST_LINK_${key}`] =
+              typeof value === "string" ? value : JSON.stringify(value);
+          });
+          return acc;
+        })
+      );
     }
 
     function normalizeStreaming() {
@@ -344,6 +433,40 @@ export class Function extends ComponentResource {
       }));
     }
 
+    function normalizeCanary() {
+      return output(args.canary).apply((canary) => {
+        if (canary === undefined) return;
+        if (canary === true) {
+          canary = {};
+        }
+        return {
+          alias: "live",
+          strategy: canary.strategy ?? "Canary10Percent5Minutes",
+          alarms: canary.alarms ?? [],
+        };
+      });
+    }
+
+    function normalizeWarm() {
+      const config = $app.functions?.[name]?.warm;
+      const enabled =
+        $cli.warm === "on" || ($cli.warm === "personal" && config?.personal);
+      if (!config || !enabled) return { provisioned: 0 };
+      const concurrency = config.concurrency ?? 1;
+      if (concurrency < 1 || concurrency > 5) {
+        throw new Error(
+          `In "${name}" function, warm.concurrency must be between 1 and 5`
+        );
+      }
+      return {
+        provisioned: config.provisioned ?? 0,
+        schedule: config.schedule
+          ? Math.max(1, Math.round(toSeconds(config.schedule as Duration) / 60))
+          : undefined,
+        concurrency,
+      };
+    }
+
     function normalizeUrl() {
       return output(args.url).apply((url) => {
         if (url === false || url === undefined) return;
@@ -473,7 +596,7 @@ export class Function extends ComponentResource {
       //       contains symlinks. Pulumi cannot zip symlinks correctly.
       //       We will zip the folder ourselves.
       return output(args.bundle).apply(async (bundle) => {
-        const zipPath = path.resolve($cli.paths.work, name, "code.zip");
+        const zipPath = path.resolve($cli.paths.run, name, "code.zip");
         await fs.promises.mkdir(path.dirname(zipPath), {
           recursive: true,
         });
@@ -495,6 +618,7 @@ export class Function extends ComponentResource {
           await archive.finalize();
         });
 
+        await reportBundle(name, bundle, zipPath);
         return zipPath;
       });
     }
@@ -548,27 +672,100 @@ export class Function extends ComponentResource {
     }
 
     function createUrl() {
-      return url.apply((url) => {
+      return all([url, code]).apply(([url, code]) => {
         if (url === undefined) return;
 
         return new aws.lambda.FunctionUrl(
           `${name}-url`,
           {
             functionName: fn.name,
+            qualifier: code.alias?.alias,
             authorizationType: url.authorization.toUpperCase(),
             invokeMode: streaming.apply((streaming) =>
               streaming ? "RESPONSE_STREAM" : "BUFFERED"
             ),
             cors: url.cors,
           },
-          { parent }
+          { parent, dependsOn: code.alias ? [code.alias] : [] }
+        );
+      });
+    }
+
+    function createProvisioned() {
+      // kept while scaled down so the alias stays and scaling up again only
+      // changes the concurrency
+      if (!$app.functions?.[name]?.warm?.provisioned) return;
+
+      all([code, canary]).apply(([code, canary]) => {
+        new FunctionProvisioned(
+          `${name}-provisioned`,
+          {
+            functionName: code.fn.name,
+            alias: canary?.alias ?? "warm",
+            codeHash: bundleHash,
+            provisioned: warm.provisioned,
+            manageAlias: !canary,
+            region,
+          },
+          { parent, dependsOn: code.alias ? [code.alias] : [] }
         );
       });
     }
 
+    function createWarmer() {
+      if (!warm.schedule) return;
+
+      const rule = new aws.cloudwatch.EventRule(
+        `${name}-warmer-rule`,
+        {
+          scheduleExpression: `rate(${warm.schedule} ${
+            warm.schedule === 1 ? "minute" : "minutes"
+          })`,
+        },
+        { parent }
+      );
+      // every target invokes the function once, so concurrent targets keep
+      // that many instances warm
+      for (let i = 0; i < warm.concurrency!; i++) {
+        new aws.cloudwatch.EventTarget(
+          `${name}-warmer-target-${i}`,
+          {
+            rule: rule.name,
+            arn: invokeArn,
+            input: JSON.stringify({ type: "warmer" }),
+          },
+          { parent }
+        );
+      }
+      new aws.lambda.Permission(
+        `${name}-warmer-permission`,
+        {
+          action: "lambda:InvokeFunction",
+          function: invokeArn,
+          principal: "events.amazonaws.com",
+          sourceArn: rule.arn,
+        },
+        { parent }
+      );
+    }
+
+    function configHash(fn: aws.lambda.Function) {
+      return all([
+        fn.environment,
+        fn.handler,
+        fn.runtime,
+        fn.memorySize,
+        fn.timeout,
+        fn.role,
+        fn.layers,
+      ]).apply((config) =>
+        crypto.createHash("sha256").update(JSON.stringify(config)).digest("hex")
+      );
+    }
+
     function updateFunctionCode() {
-      return output([fnRaw]).apply(([fnRaw]) => {
-        new FunctionCodeUpdater(
+      return all([fnRaw, canary]).apply(([fnRaw, canary]) => {
+        const updater = new FunctionCodeUpdater(
           `${name}-code-updater`,
           {
             functionName: fnRaw.name,
@@ -578,7 +775,24 @@ export class Function extends ComponentResource {
           },
           { parent }
         );
-        return fnRaw;
+        const alias =
+          canary &&
+          new FunctionCanary(
+            `${name}-canary`,
+            {
+              functionName: fnRaw.name,
+              alias: canary.alias,
+              codeHash: bundleHash,
+              configHash: configHash(fnRaw),
+              strategy: canary.strategy,
+              alarms: canary.alarms,
+              urn: parent.urn,
+              statusFile: path.join($cli.paths.run, "phases.ndjson"),
+              region,
+            },
+            { parent, dependsOn: [updater] }
+          );
+        return { fn: fnRaw, alias };
       });
     }
   }
@@ -590,6 +804,14 @@ export class Function extends ComponentResource {
     };
   }
 
+  /**
+   * The ARN to invoke the function with. With `canary` it points to the alias
+   * so invokers go through the traffic shift instead of `$LATEST`.
+   */
+  public get arn() {
+    return this.invokeArn;
+  }
+
   public get url() {
     return this.fnUrl.apply((url) => url?.functionUrl ?? output(undefined));
   }
@@ -30,17 +30,23 @@ export const AWS = {
       });
       try {
         const bucket = (async () => {
-          const result = await ssm
-            .send(
-              new GetParameterCommand({
-                Name: `/sst/bootstrap`,
-              })
-            )
-            .catch((err) => {
-              if (err instanceof ParameterNotFound) return;
-              throw err;
-            });
+          const get = (name: string) =>
+            ssm
+              .send(
+                new GetParameterCommand({
+                  Name: name,
+                })
+              )
+              .catch((err) => {
+                if (err instanceof ParameterNotFound) return;
+                throw err;
+              });
 
+          // `sst bootstrap --upgrade` keeps assets apart from the state
+          const assets = await get(`/sst/asset-bucket`);
+          if (assets?.Parameter?.Value) return assets.Parameter.Value;
+
+          const result = await get(`/sst/bootstrap`);
           if (result?.Parameter?.Value) return result.Parameter.Value;
 
           const name = `sst-bootstrap-${crypto.randomUUID()}`;
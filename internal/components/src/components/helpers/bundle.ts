@@ -0,0 +1,94 @@
+import fs from "fs";
+import path from "path";
+import type { Metafile } from "esbuild";
+
+export function metafilePath(name: string) {
+  return path.join($cli.paths.run, name, "metafile.json");
+}
+
+// node_modules/@scope/pkg/index.js -> @scope/pkg
+function packageName(file: string) {
+  const parts = file.split(/[\\/]/);
+  const index = parts.lastIndexOf("node_modules");
+  if (index === -1 || index + 1 >= parts.length) return;
+  const name = parts[index + 1];
+  if (name.startsWith("@") && index + 2 < parts.length)
+    return `${name}/${parts[index + 2]}`;
+  return name;
+}
+
+async function directorySize(dir: string): Promise<number> {
+  let total = 0;
+  const entries = await fs.promises.readdir(dir, { withFileTypes: true });
+  for (const entry of entries) {
+    const file = path.join(dir, entry.name);
+    if (entry.isDirectory()) total += await directorySize(file);
+    else if (entry.isFile()) total += (await fs.promises.stat(file)).size;
+  }
+  return total;
+}
+
+/**
+ * Reports the size of a zipped function to the cli and fails the build if it
+ * is larger than `--fail-on-size`
+ */
+export async function reportBundle(
+  name: string,
+  bundle: string,
+  zipPath: string
+) {
+  const size = (await fs.promises.stat(zipPath)).size;
+  const unzipped = await directorySize(bundle);
+
+  const modules: Record<string, number> = {};
+  const metafile = metafilePath(name);
+  if (fs.existsSync(metafile)) {
+    const meta: Metafile = JSON.parse(
+      await fs.promises.readFile(metafile, "utf8")
+    );
+    for (const output of Object.values(meta.outputs)) {
+      for (const [file, input] of Object.entries(output.inputs)) {
+        const pkg = packageName(file);
+        if (pkg) modules[pkg] = (modules[pkg] || 0) + input.bytesInOutput;
+      }
+    }
+  }
+  // packages from nodejs.install are copied as is
+  const installed = path.join(bundle, "node_modules");
+  if (fs.existsSync(installed)) {
+    for (const entry of await fs.promises.readdir(installed)) {
+      const names = entry.startsWith("@")
+        ? (await fs.promises.readdir(path.join(installed, entry))).map(
+            (child) => `${entry}/${child}`
+          )
+        : [entry];
+      for (const pkg of names) {
+        modules[pkg] =
+          (modules[pkg] || 0) +
+          (await directorySize(path.join(installed, pkg)));
+      }
+    }
+  }
+
+  fs.appendFileSync(
+    path.join($cli.paths.run, "bundles.ndjson"),
+    JSON.stringify({
+      name,
+      size,
+      unzipped,
+      modules: Object.entries(modules)
+        .sort((a, b) => b[1] - a[1])
+        .slice(0, 5)
+        .map(([name, size]) => ({ name, size })),
+    }) + "\n"
+  );
+
+  if ($cli.bundleLimit && size > $cli.bundleLimit) {
+    const mb = (bytes: number) => (bytes / 1024 / 1024).toFixed(1) + " MB";
+    throw new Error(
+      `Bundle of ${name} is ${mb(size)}, over the limit of ${mb(
+        $cli.bundleLimit
+      )}`
+    );
+  }
+}
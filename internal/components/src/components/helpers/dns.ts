@@ -0,0 +1,76 @@
+import { ComponentResource, Input, Output, output } from "@pulumi/pulumi";
+import * as aws from "@pulumi/aws";
+import { CloudflareRecord } from "../providers/cloudflare-record.js";
+
+/**
+ * Where the DNS records of a domain are managed.
+ * - "route53" - Records are created in the Route 53 hosted zone.
+ * - "cloudflare" - Records are created in the Cloudflare zone, needs
+ *   `CLOUDFLARE_API_TOKEN`.
+ * - "external" - No records are created, `sst domains` lists the ones to add.
+ */
+export type DnsProvider = "route53" | "cloudflare" | "external";
+
+export interface DnsRecordArgs {
+  dns: DnsProvider;
+  // the Route 53 hosted zone id, or the Cloudflare zone id
+  zoneId?: Input<string | undefined>;
+  name: Input<string>;
+  type: Input<string>;
+  value: Input<string>;
+  // Route 53 aliases the target instead of creating a CNAME
+  alias?: {
+    name: Input<string>;
+    zoneId: Input<string>;
+  };
+}
+
+// dnsRecord creates the record with the provider of the domain, nothing is
+// created for external DNS
+export function dnsRecord(
+  parent: ComponentResource,
+  name: string,
+  args: DnsRecordArgs
+): Output<string> | undefined {
+  switch (args.dns) {
+    case "route53":
+      if (args.alias) {
+        return new aws.route53.Record(
+          name,
+          {
+            name: args.name,
+            zoneId: output(args.zoneId).apply((zoneId) => zoneId!),
+            type: args.type,
+            aliases: [{ ...args.alias, evaluateTargetHealth: true }],
+          },
+          { parent }
+        ).fqdn;
+      }
+      return new aws.route53.Record(
+        name,
+        {
+          name: args.name,
+          zoneId: output(args.zoneId).apply((zoneId) => zoneId!),
+          type: args.type,
+          records: [args.value],
+          ttl: 60,
+        },
+        { parent }
+      ).fqdn;
+    case "cloudflare":
+      // Cloudflare flattens CNAMEs at the apex, it has no alias records
+      new CloudflareRecord(
+        name,
+        {
+          zoneId: args.zoneId,
+          name: args.name,
+          type: args.alias ? "CNAME" : args.type,
+          content: args.alias ? args.alias.name : args.value,
+        },
+        { parent }
+      );
+      return output(args.name);
+    case "external":
+      return;
+  }
+}
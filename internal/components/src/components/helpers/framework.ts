@@ -0,0 +1,119 @@
+import fs from "fs";
+import path from "path";
+
+export type FrameworkName = "nextjs" | "astro" | "sveltekit" | "remix";
+
+export interface Framework {
+  name: FrameworkName;
+  buildCommand: string;
+  // where the static output is written, relative to the site
+  outputDir: string;
+  // served for paths that do not match a file, the first one the build wrote
+  // is used
+  errorPages: string[];
+  // directory with content hashed file names that can be cached forever
+  versionedDir?: string;
+  // how pages are written, /about as about/index.html or as about.html. Unset
+  // for single page apps that route everything through the error page.
+  pages?: PageLayout;
+}
+
+export type PageLayout = "directory" | "file";
+
+const FRAMEWORKS: {
+  name: FrameworkName;
+  dependency: string;
+  configs: string[];
+}[] = [
+  {
+    name: "nextjs",
+    dependency: "next",
+    configs: ["next.config.js", "next.config.mjs", "next.config.ts"],
+  },
+  {
+    name: "astro",
+    dependency: "astro",
+    configs: ["astro.config.mjs", "astro.config.js", "astro.config.ts"],
+  },
+  {
+    name: "sveltekit",
+    dependency: "@sveltejs/kit",
+    configs: ["svelte.config.js"],
+  },
+  {
+    name: "remix",
+    dependency: "@remix-run/dev",
+    configs: ["remix.config.js", "remix.config.mjs"],
+  },
+];
+
+export function detectFramework(sitePath: string): Framework | undefined {
+  const packageJsonPath = path.join(sitePath, "package.json");
+  const packageJson = fs.existsSync(packageJsonPath)
+    ? JSON.parse(fs.readFileSync(packageJsonPath).toString())
+    : {};
+  const dependencies = {
+    ...packageJson.dependencies,
+    ...packageJson.devDependencies,
+  };
+
+  const match = FRAMEWORKS.find(
+    (framework) =>
+      dependencies[framework.dependency] ||
+      framework.configs.some((config) =>
+        fs.existsSync(path.join(sitePath, config))
+      )
+  );
+  if (!match) return;
+  return useFramework(match.name, sitePath);
+}
+
+export function useFramework(
+  name: FrameworkName,
+  sitePath: string
+): Framework {
+  switch (name) {
+    case "nextjs":
+      // static exports with `output: "export"`, server rendered sites use the
+      // Nextjs component
+      return {
+        name,
+        buildCommand: "npm run build",
+        outputDir: "out",
+        errorPages: ["404.html"],
+        versionedDir: "_next",
+        pages: "file",
+      };
+    case "astro":
+      return {
+        name,
+        buildCommand: "npm run build",
+        outputDir: "dist",
+        errorPages: ["404.html"],
+        versionedDir: "_astro",
+        pages: "directory",
+      };
+    case "sveltekit":
+      // adapter-static writes to build/, the fallback page serves client side
+      // routes
+      return {
+        name,
+        buildCommand: "npm run build",
+        outputDir: "build",
+        errorPages: ["200.html", "404.html"],
+        versionedDir: "_app/immutable",
+        pages: "file",
+      };
+    case "remix":
+      // spa mode with vite writes the client bundle to build/client
+      return {
+        name,
+        buildCommand: "npm run build",
+        outputDir: fs.existsSync(path.join(sitePath, "vite.config.ts"))
+          ? "build/client"
+          : "public",
+        errorPages: ["index.html"],
+        versionedDir: "assets",
+      };
+  }
+}
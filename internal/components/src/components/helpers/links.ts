@@ -0,0 +1,28 @@
+let resolveOutputs: (outputs: Record<string, any>) => void;
+let rejectOutputs: (error: Error) => void;
+const outputs = new Promise<Record<string, any>>((resolve, reject) => {
+  resolveOutputs = resolve;
+  rejectOutputs = reject;
+});
+// the program error is reported by run(), not as an unhandled rejection
+outputs.catch(() => {});
+
+export const Links = {
+  /**
+   * Resolves with the outputs returned from `run()` once the program is done,
+   * so functions can link to resources defined after them
+   */
+  outputs() {
+    return outputs;
+  },
+  resolve(input: Record<string, any>) {
+    resolveOutputs(input);
+  },
+  /**
+   * Fails the links when the program fails, so outputs waiting on them
+   * settle instead of hanging the update
+   */
+  reject(error: Error) {
+    rejectOutputs(error);
+  },
+};
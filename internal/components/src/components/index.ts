@@ -1,5 +1,9 @@
 export * from "./nextjs.js";
+export * from "./static-site.js";
 export * from "./function.js";
 export * from "./providers/log-group.js";
 export * from "./providers/function-code-updater.js";
+export * from "./providers/function-canary.js";
 export * from "./providers/distribution-invalidation.js";
+export * from "./providers/bucket-files.js";
+export * from "./providers/cloudflare-record.js";
@@ -0,0 +1,150 @@
+import fs from "fs";
+import {
+  CustomResourceOptions,
+  Input,
+  Output,
+  dynamic,
+} from "@pulumi/pulumi";
+import {
+  S3Client,
+  PutObjectCommand,
+  DeleteObjectsCommand,
+} from "@aws-sdk/client-s3";
+import { AWS } from "../helpers/aws.js";
+
+// S3 allows deleting up to 1,000 keys in a single request
+const DELETE_LIMIT = 1000;
+const UPLOAD_CONCURRENCY = 16;
+
+export interface BucketFile {
+  source: string;
+  key: string;
+  hash: string;
+  cacheControl?: string;
+  contentType?: string;
+}
+
+export interface BucketFilesInputs {
+  bucketName: Input<string>;
+  files: Input<BucketFile[]>;
+  region?: Input<aws.Region>;
+}
+
+interface Inputs {
+  bucketName: string;
+  files: BucketFile[];
+  region?: aws.Region;
+}
+
+// uploads a whole directory as one resource, on update only the files whose
+// content or headers changed are uploaded and the removed ones are deleted
+class Provider implements dynamic.ResourceProvider {
+  async create(inputs: Inputs): Promise<dynamic.CreateResult> {
+    await this.upload(inputs, inputs.files);
+    return { id: inputs.bucketName, outs: inputs };
+  }
+
+  async diff(
+    id: string,
+    olds: Inputs,
+    news: Inputs
+  ): Promise<dynamic.DiffResult> {
+    const changes =
+      olds.bucketName !== news.bucketName ||
+      olds.region !== news.region ||
+      this.changed(olds, news).length > 0 ||
+      this.removed(olds, news).length > 0;
+    return {
+      changes,
+      replaces: olds.bucketName !== news.bucketName ? ["bucketName"] : [],
+    };
+  }
+
+  async update(
+    id: string,
+    olds: Inputs,
+    news: Inputs
+  ): Promise<dynamic.UpdateResult> {
+    const changed = this.changed(olds, news);
+    const removed = this.removed(olds, news);
+    console.log(
+      `uploading ${changed.length} changed files, deleting ${removed.length} files`
+    );
+    await this.upload(news, changed);
+    await this.purge(news, removed);
+    return { outs: news };
+  }
+
+  async delete(id: string, olds: Inputs) {
+    // the bucket is emptied by forceDestroy when it is removed
+  }
+
+  changed(olds: Inputs, news: Inputs) {
+    const previous = new Map(olds.files.map((file) => [file.key, file]));
+    return news.files.filter((file) => {
+      const old = previous.get(file.key);
+      return (
+        !old ||
+        old.hash !== file.hash ||
+        old.cacheControl !== file.cacheControl ||
+        old.contentType !== file.contentType
+      );
+    });
+  }
+
+  removed(olds: Inputs, news: Inputs) {
+    const keys = new Set(news.files.map((file) => file.key));
+    return olds.files
+      .filter((file) => !keys.has(file.key))
+      .map((file) => file.key);
+  }
+
+  async upload(inputs: Inputs, files: BucketFile[]) {
+    const client = AWS.useClient(S3Client, inputs.region);
+    const queue = [...files];
+    await Promise.all(
+      Array.from({ length: UPLOAD_CONCURRENCY }, async () => {
+        while (queue.length) {
+          const file = queue.shift()!;
+          await client.send(
+            new PutObjectCommand({
+              Bucket: inputs.bucketName,
+              Key: file.key,
+              Body: fs.readFileSync(file.source),
+              CacheControl: file.cacheControl,
+              ContentType: file.contentType,
+            })
+          );
+        }
+      })
+    );
+  }
+
+  async purge(inputs: Inputs, keys: string[]) {
+    const client = AWS.useClient(S3Client, inputs.region);
+    for (let i = 0; i < keys.length; i += DELETE_LIMIT) {
+      await client.send(
+        new DeleteObjectsCommand({
+          Bucket: inputs.bucketName,
+          Delete: {
+            Objects: keys
+              .slice(i, i + DELETE_LIMIT)
+              .map((key) => ({ Key: key })),
+          },
+        })
+      );
+    }
+  }
+}
+
+export class BucketFiles extends dynamic.Resource {
+  public readonly files!: Output<BucketFile[]>;
+
+  constructor(
+    name: string,
+    args: BucketFilesInputs,
+    opts?: CustomResourceOptions
+  ) {
+    super(new Provider(), `${name}-sst.BucketFiles`, args, opts);
+  }
+}
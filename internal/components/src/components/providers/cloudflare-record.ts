@@ -0,0 +1,134 @@
+import { CustomResourceOptions, Input, Output, dynamic } from "@pulumi/pulumi";
+
+const API = "https://api.cloudflare.com/client/v4";
+
+export interface CloudflareRecordInputs {
+  // the zone is looked up from the record name when not set
+  zoneId?: Input<string | undefined>;
+  name: Input<string>;
+  type: Input<string>;
+  content: Input<string>;
+  ttl?: Input<number>;
+}
+
+interface Inputs {
+  zoneId?: string;
+  name: string;
+  type: string;
+  content: string;
+  ttl?: number;
+}
+
+interface Outputs extends Inputs {
+  zoneId: string;
+  recordId: string;
+}
+
+// the token is read when the provider runs so it never ends up in the state
+async function request(method: string, path: string, body?: any) {
+  const token = process.env.CLOUDFLARE_API_TOKEN;
+  if (!token) {
+    throw new Error(
+      `Set CLOUDFLARE_API_TOKEN to manage DNS records on Cloudflare.`
+    );
+  }
+  const response = await fetch(API + path, {
+    method,
+    headers: {
+      Authorization: `Bearer ${token}`,
+      "Content-Type": "application/json",
+    },
+    body: body ? JSON.stringify(body) : undefined,
+  });
+  const json: any = await response.json();
+  if (!json.success) {
+    throw new Error(
+      `Cloudflare ${method} ${path} failed: ${json.errors
+        ?.map((error: any) => error.message)
+        .join(", ")}`
+    );
+  }
+  return json.result;
+}
+
+// walks up the labels of the name until a zone in the account matches
+async function lookupZone(name: string) {
+  const labels = name.replace(/\.$/, "").split(".");
+  for (let i = 0; i < labels.length - 1; i++) {
+    const zone = labels.slice(i).join(".");
+    const result = await request("GET", `/zones?name=${zone}`);
+    if (result.length) return result[0].id as string;
+  }
+  throw new Error(`No Cloudflare zone found for "${name}".`);
+}
+
+class Provider implements dynamic.ResourceProvider {
+  async create(inputs: Inputs): Promise<dynamic.CreateResult> {
+    const zoneId = inputs.zoneId ?? (await lookupZone(inputs.name));
+    const record = await request("POST", `/zones/${zoneId}/dns_records`, {
+      name: inputs.name,
+      type: inputs.type,
+      content: inputs.content,
+      ttl: inputs.ttl ?? 1,
+      // records point straight at AWS so certificates validate and
+      // CloudFront serves the domain
+      proxied: false,
+    });
+    return {
+      id: record.id,
+      outs: { ...inputs, zoneId, recordId: record.id },
+    };
+  }
+
+  async diff(
+    id: string,
+    olds: Outputs,
+    news: Inputs
+  ): Promise<dynamic.DiffResult> {
+    const replaces = [];
+    if (news.zoneId && news.zoneId !== olds.zoneId) replaces.push("zoneId");
+    const changes =
+      replaces.length > 0 ||
+      olds.name !== news.name ||
+      olds.type !== news.type ||
+      olds.content !== news.content ||
+      olds.ttl !== news.ttl;
+    return { changes, replaces };
+  }
+
+  async update(
+    id: string,
+    olds: Outputs,
+    news: Inputs
+  ): Promise<dynamic.UpdateResult> {
+    await request("PUT", `/zones/${olds.zoneId}/dns_records/${id}`, {
+      name: news.name,
+      type: news.type,
+      content: news.content,
+      ttl: news.ttl ?? 1,
+      proxied: false,
+    });
+    return { outs: { ...news, zoneId: olds.zoneId, recordId: id } };
+  }
+
+  async delete(id: string, olds: Outputs) {
+    await request("DELETE", `/zones/${olds.zoneId}/dns_records/${id}`).catch(
+      (e) => {
+        // already removed by hand
+        if (!`${e.message}`.includes("Record does not exist")) throw e;
+      }
+    );
+  }
+}
+
+export class CloudflareRecord extends dynamic.Resource {
+  public readonly zoneId!: Output<string>;
+
+  constructor(
+    name: string,
+    args: CloudflareRecordInputs,
+    opts?: CustomResourceOptions
+  ) {
+    super(new Provider(), `${name}-sst.CloudflareRecord`, args, opts);
+  }
+}
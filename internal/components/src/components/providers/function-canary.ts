@@ -0,0 +1,237 @@
+import fs from "fs";
+import { CustomResourceOptions, Input, Output, dynamic } from "@pulumi/pulumi";
+import {
+  LambdaClient,
+  PublishVersionCommand,
+  CreateAliasCommand,
+  GetAliasCommand,
+  UpdateAliasCommand,
+  DeleteAliasCommand,
+} from "@aws-sdk/client-lambda";
+import {
+  CloudWatchClient,
+  DescribeAlarmsCommand,
+} from "@aws-sdk/client-cloudwatch";
+import { AWS } from "../helpers/aws.js";
+
+export type FunctionCanaryStrategy =
+  | "AllAtOnce"
+  | "Canary10Percent5Minutes"
+  | "Canary10Percent10Minutes"
+  | "Canary10Percent15Minutes"
+  | "Canary10Percent30Minutes"
+  | "Linear10PercentEvery1Minute"
+  | "Linear10PercentEvery2Minutes"
+  | "Linear10PercentEvery3Minutes"
+  | "Linear10PercentEvery10Minutes";
+
+export interface FunctionCanaryInputs {
+  functionName: Input<string>;
+  alias: Input<string>;
+  codeHash: Input<string>;
+  // environment, memory and the like, published versions snapshot them too
+  configHash: Input<string>;
+  strategy: Input<FunctionCanaryStrategy>;
+  alarms: Input<string[]>;
+  urn: Input<string>;
+  statusFile: Input<string>;
+  region?: Input<aws.Region>;
+}
+
+interface Inputs {
+  functionName: string;
+  alias: string;
+  codeHash: string;
+  configHash: string;
+  strategy: FunctionCanaryStrategy;
+  alarms: string[];
+  urn: string;
+  statusFile: string;
+  region?: aws.Region;
+}
+
+interface Phase {
+  weight: number;
+  minutes: number;
+}
+
+function phases(strategy: FunctionCanaryStrategy): Phase[] {
+  const canary = strategy.match(/^Canary(\d+)Percent(\d+)Minutes$/);
+  if (canary) {
+    return [
+      { weight: parseInt(canary[1]) / 100, minutes: parseInt(canary[2]) },
+    ];
+  }
+  const linear = strategy.match(/^Linear(\d+)PercentEvery(\d+)Minutes?$/);
+  if (linear) {
+    const step = parseInt(linear[1]);
+    const result: Phase[] = [];
+    for (let percent = step; percent < 100; percent += step) {
+      result.push({ weight: percent / 100, minutes: parseInt(linear[2]) });
+    }
+    return result;
+  }
+  return [];
+}
+
+// the cli tails this file and renders each line under the function
+function report(inputs: Inputs, message: string) {
+  fs.appendFileSync(
+    inputs.statusFile,
+    JSON.stringify({ urn: inputs.urn, message }) + "\n"
+  );
+}
+
+class Provider implements dynamic.ResourceProvider {
+  async create(inputs: Inputs): Promise<dynamic.CreateResult> {
+    const client = AWS.useClient(LambdaClient, inputs.region);
+    const version = await client.send(
+      new PublishVersionCommand({
+        FunctionName: inputs.functionName,
+      })
+    );
+    await client.send(
+      new CreateAliasCommand({
+        FunctionName: inputs.functionName,
+        Name: inputs.alias,
+        FunctionVersion: version.Version,
+      })
+    );
+    return {
+      id: `${inputs.functionName}:${inputs.alias}`,
+      outs: { ...inputs, version: version.Version },
+    };
+  }
+
+  // urn and statusFile only say where to report progress, they differ
+  // between machines and must not publish a new version
+  async diff(
+    id: string,
+    olds: Inputs,
+    news: Inputs
+  ): Promise<dynamic.DiffResult> {
+    const changes =
+      olds.functionName !== news.functionName ||
+      olds.alias !== news.alias ||
+      olds.codeHash !== news.codeHash ||
+      olds.configHash !== news.configHash ||
+      olds.strategy !== news.strategy ||
+      olds.region !== news.region ||
+      JSON.stringify(olds.alarms) !== JSON.stringify(news.alarms);
+    return {
+      changes,
+      replaces:
+        olds.functionName !== news.functionName || olds.alias !== news.alias
+          ? ["alias"]
+          : [],
+    };
+  }
+
+  async update(
+    id: string,
+    olds: Inputs,
+    news: Inputs
+  ): Promise<dynamic.UpdateResult> {
+    const client = AWS.useClient(LambdaClient, news.region);
+    const current = await client.send(
+      new GetAliasCommand({
+        FunctionName: news.functionName,
+        Name: news.alias,
+      })
+    );
+    const previous = current.FunctionVersion!;
+    const next = (
+      await client.send(
+        new PublishVersionCommand({
+          FunctionName: news.functionName,
+        })
+      )
+    ).Version!;
+
+    for (const phase of phases(news.strategy)) {
+      const percent = Math.round(phase.weight * 100);
+      report(
+        news,
+        `${percent}% of traffic on version ${next} for ${phase.minutes}m`
+      );
+      await client.send(
+        new UpdateAliasCommand({
+          FunctionName: news.functionName,
+          Name: news.alias,
+          FunctionVersion: previous,
+          RoutingConfig: {
+            AdditionalVersionWeights: { [next]: phase.weight },
+          },
+        })
+      );
+
+      const until = Date.now() + phase.minutes * 60 * 1000;
+      while (Date.now() < until) {
+        const alarm = await firingAlarm(news);
+        if (alarm) {
+          report(
+            news,
+            `alarm ${alarm} fired, rolling back to version ${previous}`
+          );
+          await client.send(
+            new UpdateAliasCommand({
+              FunctionName: news.functionName,
+              Name: news.alias,
+              FunctionVersion: previous,
+              RoutingConfig: { AdditionalVersionWeights: {} },
+            })
+          );
+          throw new Error(
+            `Alarm ${alarm} fired while shifting traffic to version ${next}, rolled back to version ${previous}`
+          );
+        }
+        await new Promise((resolve) => setTimeout(resolve, 15000));
+      }
+    }
+
+    report(news, `100% of traffic on version ${next}`);
+    await client.send(
+      new UpdateAliasCommand({
+        FunctionName: news.functionName,
+        Name: news.alias,
+        FunctionVersion: next,
+        RoutingConfig: { AdditionalVersionWeights: {} },
+      })
+    );
+    return { outs: { ...news, version: next } };
+  }
+
+  async delete(id: string, olds: Inputs) {
+    const client = AWS.useClient(LambdaClient, olds.region);
+    await client.send(
+      new DeleteAliasCommand({
+        FunctionName: olds.functionName,
+        Name: olds.alias,
+      })
+    );
+  }
+}
+
+async function firingAlarm(inputs: Inputs) {
+  if (inputs.alarms.length === 0) return;
+  const client = AWS.useClient(CloudWatchClient, inputs.region);
+  const result = await client.send(
+    new DescribeAlarmsCommand({
+      AlarmNames: inputs.alarms,
+      StateValue: "ALARM",
+    })
+  );
+  return result.MetricAlarms?.[0]?.AlarmName;
+}
+
+export class FunctionCanary extends dynamic.Resource {
+  public readonly alias!: Output<string>;
+
+  constructor(
+    name: string,
+    args: FunctionCanaryInputs,
+    opts?: CustomResourceOptions
+  ) {
+    super(new Provider(), `${name}-sst.FunctionCanary`, args, opts);
+  }
+}
@@ -0,0 +1,135 @@
+import { CustomResourceOptions, Input, dynamic } from "@pulumi/pulumi";
+import {
+  LambdaClient,
+  PublishVersionCommand,
+  CreateAliasCommand,
+  UpdateAliasCommand,
+  DeleteAliasCommand,
+  PutProvisionedConcurrencyConfigCommand,
+  DeleteProvisionedConcurrencyConfigCommand,
+  ResourceNotFoundException,
+} from "@aws-sdk/client-lambda";
+import { AWS } from "../helpers/aws.js";
+
+export interface FunctionProvisionedInputs {
+  functionName: Input<string>;
+  alias: Input<string>;
+  codeHash: Input<string>;
+  provisioned: Input<number>;
+  // the canary publishes versions and moves its own alias
+  manageAlias: Input<boolean>;
+  region?: Input<aws.Region>;
+}
+
+interface Inputs {
+  functionName: string;
+  alias: string;
+  codeHash: string;
+  provisioned: number;
+  manageAlias: boolean;
+  region?: aws.Region;
+}
+
+// provisioned concurrency needs a published version, the code updater changes
+// the code after the function is created so versions are published here
+class Provider implements dynamic.ResourceProvider {
+  async create(inputs: Inputs): Promise<dynamic.CreateResult> {
+    if (inputs.manageAlias) {
+      const client = AWS.useClient(LambdaClient, inputs.region);
+      const version = await this.publish(inputs);
+      await client.send(
+        new CreateAliasCommand({
+          FunctionName: inputs.functionName,
+          Name: inputs.alias,
+          FunctionVersion: version,
+        })
+      );
+    }
+    await this.configure(inputs);
+    return {
+      id: `${inputs.functionName}:${inputs.alias}`,
+      outs: inputs,
+    };
+  }
+
+  async update(
+    id: string,
+    olds: Inputs,
+    news: Inputs
+  ): Promise<dynamic.UpdateResult> {
+    if (news.manageAlias && olds.codeHash !== news.codeHash) {
+      const client = AWS.useClient(LambdaClient, news.region);
+      const version = await this.publish(news);
+      await client.send(
+        new UpdateAliasCommand({
+          FunctionName: news.functionName,
+          Name: news.alias,
+          FunctionVersion: version,
+        })
+      );
+    }
+    await this.configure(news);
+    return { outs: news };
+  }
+
+  async delete(id: string, olds: Inputs) {
+    const client = AWS.useClient(LambdaClient, olds.region);
+    await this.configure({ ...olds, provisioned: 0 });
+    if (olds.manageAlias) {
+      await client
+        .send(
+          new DeleteAliasCommand({
+            FunctionName: olds.functionName,
+            Name: olds.alias,
+          })
+        )
+        .catch((e) => {
+          if (!(e instanceof ResourceNotFoundException)) throw e;
+        });
+    }
+  }
+
+  async publish(inputs: Inputs) {
+    const client = AWS.useClient(LambdaClient, inputs.region);
+    const result = await client.send(
+      new PublishVersionCommand({
+        FunctionName: inputs.functionName,
+      })
+    );
+    return result.Version!;
+  }
+
+  async configure(inputs: Inputs) {
+    const client = AWS.useClient(LambdaClient, inputs.region);
+    if (inputs.provisioned > 0) {
+      await client.send(
+        new PutProvisionedConcurrencyConfigCommand({
+          FunctionName: inputs.functionName,
+          Qualifier: inputs.alias,
+          ProvisionedConcurrentExecutions: inputs.provisioned,
+        })
+      );
+      return;
+    }
+    await client
+      .send(
+        new DeleteProvisionedConcurrencyConfigCommand({
+          FunctionName: inputs.functionName,
+          Qualifier: inputs.alias,
+        })
+      )
+      .catch((e) => {
+        if (!(e instanceof ResourceNotFoundException)) throw e;
+      });
+  }
+}
+
+export class FunctionProvisioned extends dynamic.Resource {
+  constructor(
+    name: string,
+    args: FunctionProvisionedInputs,
+    opts?: CustomResourceOptions
+  ) {
+    super(new Provider(), `${name}-sst.FunctionProvisioned`, args, opts);
+  }
+}
@@ -0,0 +1,41 @@
+import { CustomResourceOptions, Input, dynamic } from "@pulumi/pulumi";
+
+export interface InjectedFailureInputs {
+  target: Input<string>;
+  // resolves once every input of the target is known, so the failure happens
+  // exactly when the target would have started
+  ready: Input<boolean>;
+}
+
+interface Inputs {
+  target: string;
+}
+
+// used by `--inject-failure` to test how the cli handles failed updates
+class Provider implements dynamic.ResourceProvider {
+  async diff(): Promise<dynamic.DiffResult> {
+    return { changes: true };
+  }
+
+  async create(inputs: Inputs): Promise<dynamic.CreateResult> {
+    throw new Error(`Injected failure for ${inputs.target}`);
+  }
+
+  async update(
+    id: string,
+    olds: Inputs,
+    news: Inputs
+  ): Promise<dynamic.UpdateResult> {
+    throw new Error(`Injected failure for ${news.target}`);
+  }
+}
+
+export class InjectedFailure extends dynamic.Resource {
+  constructor(
+    name: string,
+    args: InjectedFailureInputs,
+    opts?: CustomResourceOptions
+  ) {
+    super(new Provider(), `${name}-sst.InjectedFailure`, args, opts);
+  }
+}
@@ -3,12 +3,11 @@ import url from "url";
 import fs from "fs";
 import { globSync } from "glob";
 import crypto from "crypto";
-import { execSync } from "child_process";
+import { spawn } from "child_process";
 import {
   Input,
   Output,
   Unwrap,
-  asset,
   output,
   all,
   interpolate,
@@ -19,6 +18,7 @@ import { Distribution, DistributionDomainArgs } from "./distribution.js";
 import { Function, FunctionArgs, FunctionNodeJSArgs } from "./function.js";
 import { Duration, toSeconds } from "./util/duration.js";
 import { DistributionInvalidation } from "./providers/distribution-invalidation.js";
+import { BucketFile, BucketFiles } from "./providers/bucket-files.js";
 import { AWS } from "./helpers/aws.js";
 
 type CloudFrontFunctionConfig = { injections: string[] };
@@ -330,25 +330,62 @@ export function buildApp(
 
       // Run build
       console.debug(`Running "${cmd}" script`);
-      try {
-        execSync(cmd, {
-          cwd: sitePath,
-          stdio: "inherit",
-          env: {
-            SST: "1",
-            ...process.env,
-            ...environment,
-          },
-        });
-      } catch (e) {
-        throw new Error(`There was a problem building the "${name}" site.`);
-      }
-
-      return sitePath;
+      return runBuild(name, cmd, sitePath, environment).then(() => sitePath);
     }
   );
 }
 
+// runBuild streams every line of the build to a file the cli tails and
+// renders under the site, the last lines are kept for the error
+export function runBuild(
+  name: string,
+  cmd: string,
+  sitePath: string,
+  environment?: Record<string, string>
+) {
+  const statusFile = path.join($cli.paths.run, "build.ndjson");
+  const tail: string[] = [];
+  const report = (line: string) => {
+    if (!line.trim()) return;
+    tail.push(line);
+    if (tail.length > 20) tail.shift();
+    fs.appendFileSync(statusFile, JSON.stringify({ name, line }) + "\n");
+  };
+
+  return new Promise<void>((resolve, reject) => {
+    const child = spawn(cmd, {
+      cwd: sitePath,
+      shell: true,
+      env: {
+        SST: "1",
+        ...process.env,
+        ...environment,
+      },
+    });
+    for (const stream of [child.stdout, child.stderr]) {
+      let buffer = "";
+      stream.on("data", (chunk: Buffer) => {
+        const lines = (buffer + chunk.toString()).split(/\r?\n/);
+        buffer = lines.pop() ?? "";
+        lines.forEach(report);
+      });
+      stream.on("end", () => report(buffer));
+    }
+    child.on("error", reject);
+    child.on("close", (code) => {
+      if (code === 0) return resolve();
+      reject(
+        new Error(
+          [
+            `There was a problem building the "${name}" site.`,
+            ...tail,
+          ].join("\n")
+        )
+      );
+    });
+  });
+}
+
 export function createBucket(parent: ComponentResource, name: string) {
   const access = createCloudFrontOriginAccessIdentity();
   const bucket = createS3Bucket();
@@ -436,7 +473,7 @@ export function createServersAndDistribution(
 
     function uploadAssets() {
       return output(args.assets).apply((assets) => {
-        const uploadedObjects: aws.s3.BucketObject[] = [];
+        const files: BucketFile[] = [];
 
         // Define content headers
         const nonVersionedFilesTTL =
@@ -487,36 +524,43 @@ export function createServersAndDistribution(
             // Upload files based on fileOptions
             const filesUploaded: string[] = [];
             for (const fileOption of fileOptions.reverse()) {
-              const files = globSync(fileOption.files, {
+              const matches = globSync(fileOption.files, {
                 cwd: path.resolve(outputPath, from),
                 nodir: true,
                 dot: true,
                 ignore: fileOption.ignore,
               }).filter((file) => !filesUploaded.includes(file));
 
-              for (const file of files) {
-                uploadedObjects.push(
-                  new aws.s3.BucketObject(
-                    `${name}-asset-${from}-${file}`,
-                    {
-                      bucket: bucket.bucket,
-                      source: new asset.FileAsset(
-                        path.resolve(outputPath, from, file)
-                      ),
-                      key: path.posix.join(to, file),
-                      contentType: getContentType(file, "UTF-8"),
-                      cacheControl: fileOption.cacheControl,
-                    },
-                    { parent }
-                  )
-                );
+              for (const file of matches) {
+                const source = path.resolve(outputPath, from, file);
+                files.push({
+                  source,
+                  key: path.posix.join(to, file),
+                  hash: crypto
+                    .createHash("md5")
+                    .update(fs.readFileSync(source))
+                    .digest("hex"),
+                  contentType: getContentType(file, "UTF-8"),
+                  cacheControl: fileOption.cacheControl,
+                });
               }
-              filesUploaded.push(...files);
+              filesUploaded.push(...matches);
             }
           });
         });
 
-        return uploadedObjects;
+        // one resource for every asset makes large sites slow to diff, the
+        // provider only uploads what changed since the last deploy
+        return [
+          new BucketFiles(
+            `${name}-assets`,
+            {
+              bucketName: bucket.bucket,
+              files,
+            },
+            { parent }
+          ),
+        ];
       });
     }
 
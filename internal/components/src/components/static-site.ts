@@ -0,0 +1,336 @@
+import fs from "fs";
+import path from "path";
+import crypto from "crypto";
+import { globSync } from "glob";
+import {
+  ComponentResource,
+  ComponentResourceOptions,
+  Input,
+  Output,
+  all,
+  output,
+} from "@pulumi/pulumi";
+import * as aws from "@pulumi/aws";
+import { Distribution, DistributionDomainArgs } from "./distribution.js";
+import { createBucket, prepare, runBuild } from "./ssr-site.js";
+import { BucketFile, BucketFiles } from "./providers/bucket-files.js";
+import { DistributionInvalidation } from "./providers/distribution-invalidation.js";
+import {
+  Framework,
+  FrameworkName,
+  PageLayout,
+  detectFramework,
+  useFramework,
+} from "./helpers/framework.js";
+
+export interface StaticSiteArgs {
+  /**
+   * Path to the directory where the site is located.
+   * @default "."
+   */
+  path?: Input<string>;
+  /**
+   * The framework of the site, detected from the package.json and the config
+   * files when not set.
+   * @example
+   * ```js
+   * framework: "astro",
+   * ```
+   */
+  framework?: Input<FrameworkName>;
+  /**
+   * The command for building the site.
+   * @default `npm run build`
+   */
+  buildCommand?: Input<string>;
+  /**
+   * The directory the build writes the site to, relative to the path.
+   * @default The output directory of the framework
+   */
+  buildOutput?: Input<string>;
+  /**
+   * Environment variables passed to the build.
+   */
+  environment?: Input<Record<string, Input<string>>>;
+  customDomain?: Input<string | DistributionDomainArgs>;
+}
+
+/**
+ * The `StaticSite` component builds a Next.js static export, Astro, SvelteKit
+ * or Remix SPA site and serves it from S3 through CloudFront.
+ * @example
+ * ```js
+ * new StaticSite("web", {
+ *   path: "packages/web",
+ * });
+ * ```
+ */
+export class StaticSite extends ComponentResource {
+  private bucket: aws.s3.BucketV2;
+  private distribution: Distribution;
+
+  constructor(
+    name: string,
+    args?: StaticSiteArgs,
+    opts?: ComponentResourceOptions
+  ) {
+    super("sst:sst:StaticSite", name, args, opts);
+
+    const parent = this;
+    const { sitePath } = prepare(args || {});
+    const framework = normalizeFramework();
+    const outputPath = buildSite();
+    const { access, bucket } = createBucket(parent, name);
+    const files = uploadFiles();
+    const rewrite = createRewriteFunction();
+    const distribution = createDistribution();
+    createInvalidation();
+
+    this.bucket = bucket;
+    this.distribution = distribution;
+
+    function normalizeFramework() {
+      return all([sitePath, args?.framework]).apply(
+        ([sitePath, framework]): Framework | undefined =>
+          framework
+            ? useFramework(framework, sitePath)
+            : detectFramework(sitePath)
+      );
+    }
+
+    function buildSite() {
+      return all([
+        sitePath,
+        framework,
+        args?.buildCommand,
+        args?.buildOutput,
+        args?.environment,
+      ]).apply(
+        async ([
+          sitePath,
+          framework,
+          buildCommand,
+          buildOutput,
+          environment,
+        ]) => {
+          const cmd =
+            buildCommand ?? framework?.buildCommand ?? "npm run build";
+          if (!process.env.SKIP) {
+            await runBuild(name, cmd, sitePath, environment);
+          }
+
+          const outputPath = path.resolve(
+            sitePath,
+            buildOutput ?? framework?.outputDir ?? "dist"
+          );
+          if (!fs.existsSync(outputPath)) {
+            if (framework?.name === "nextjs") {
+              throw new Error(
+                `No static export found at "${outputPath}". Set \`output: "export"\` in next.config.js, or use the Nextjs component for server rendering, ISR and image optimization.`
+              );
+            }
+            throw new Error(
+              `No build output found at "${outputPath}", set "buildOutput" to the directory the build writes to.`
+            );
+          }
+          return outputPath;
+        }
+      );
+    }
+
+    function uploadFiles() {
+      return new BucketFiles(
+        `${name}-assets`,
+        {
+          bucketName: bucket.bucket,
+          files: all([outputPath, framework]).apply(([outputPath, framework]) =>
+            globSync("**", { cwd: outputPath, nodir: true, dot: true }).map(
+              (file): BucketFile => {
+                const source = path.join(outputPath, file);
+                const versioned =
+                  framework?.versionedDir &&
+                  file.startsWith(framework.versionedDir + "/");
+                return {
+                  source,
+                  key: file.split(path.sep).join("/"),
+                  hash: crypto
+                    .createHash("md5")
+                    .update(fs.readFileSync(source))
+                    .digest("hex"),
+                  cacheControl: versioned
+                    ? "public,max-age=31536000,immutable"
+                    : `public,max-age=0,s-maxage=86400,stale-while-revalidate=8640`,
+                  contentType: contentType(file),
+                };
+              }
+            )
+          ),
+        },
+        { parent }
+      );
+    }
+
+    // CloudFront only serves index.html for the root, pages are rewritten to
+    // the file the framework wrote for them, trailingSlash settings change the
+    // layout so it is read from the output when there are nested pages
+    function createRewriteFunction() {
+      const layout = all([outputPath, framework]).apply(
+        ([outputPath, framework]): PageLayout | undefined => {
+          const errorPages = framework?.errorPages ?? ["404.html"];
+          const pages = globSync("**/*.html", { cwd: outputPath })
+            .map((file) => file.split(path.sep).join("/"))
+            .filter((page) => !errorPages.includes(page));
+          if (pages.some((page) => page.endsWith("/index.html")))
+            return "directory";
+          if (pages.some((page) => page !== "index.html")) return "file";
+          return framework?.pages;
+        }
+      );
+      return layout.apply((layout) => {
+        if (!layout) return;
+        return new aws.cloudfront.Function(
+          `${name}-cloudfront-function-rewrite`,
+          {
+            runtime: "cloudfront-js-1.0",
+            code: `
+function handler(event) {
+  var request = event.request;
+  var uri = request.uri;
+  var last = uri.substring(uri.lastIndexOf("/") + 1);
+  if (uri === "/" || last.indexOf(".") !== -1) return request;
+  if (last === "") uri = uri.substring(0, uri.length - 1);
+  request.uri = uri + ${JSON.stringify(
+    layout === "directory" ? "/index.html" : ".html"
+  )};
+  return request;
+}`,
+          },
+          { parent }
+        );
+      });
+    }
+
+    function createDistribution() {
+      const errorPage = all([outputPath, framework]).apply(
+        ([outputPath, framework]) =>
+          (framework?.errorPages ?? ["404.html"]).find((page) =>
+            fs.existsSync(path.join(outputPath, page))
+          )
+      );
+      return new Distribution(
+        `${name}-distribution`,
+        {
+          customDomain: args?.customDomain,
+          nodes: {
+            distribution: {
+              origins: [
+                {
+                  originId: "s3",
+                  domainName: bucket.bucketRegionalDomainName,
+                  s3OriginConfig: {
+                    originAccessIdentity: access.cloudfrontAccessIdentityPath,
+                  },
+                },
+              ],
+              defaultRootObject: "index.html",
+              defaultCacheBehavior: {
+                targetOriginId: "s3",
+                viewerProtocolPolicy: "redirect-to-https",
+                allowedMethods: ["GET", "HEAD", "OPTIONS"],
+                cachedMethods: ["GET", "HEAD"],
+                compress: true,
+                // CloudFront's managed CachingOptimized policy
+                cachePolicyId: "658327ea-f89d-4fab-a63d-7e88639e58f6",
+                functionAssociations: rewrite.apply((rewrite) =>
+                  rewrite
+                    ? [
+                        {
+                          eventType: "viewer-request",
+                          functionArn: rewrite.arn,
+                        },
+                      ]
+                    : []
+                ),
+              },
+              customErrorResponses: errorPage.apply((errorPage) =>
+                errorPage
+                  ? [403, 404].map((errorCode) => ({
+                      errorCode,
+                      // client side routed apps render their own not found page
+                      responseCode: errorPage === "404.html" ? 404 : 200,
+                      responsePagePath: `/${errorPage}`,
+                    }))
+                  : []
+              ),
+              enabled: true,
+              restrictions: {
+                geoRestriction: {
+                  restrictionType: "none",
+                },
+              },
+              waitForDeployment: false,
+            },
+          },
+        },
+        // create distribution after s3 upload finishes
+        { dependsOn: [files], parent }
+      );
+    }
+
+    function createInvalidation() {
+      // only invalidate when the uploaded content changed
+      const version = output(files.files).apply((files: BucketFile[]) => {
+        const hash = crypto.createHash("md5");
+        files.forEach((file) => hash.update(file.key + file.hash));
+        return hash.digest("hex");
+      });
+      new DistributionInvalidation(
+        `${name}-invalidation`,
+        {
+          distributionId: distribution.nodes.distribution.id,
+          paths: ["/*"],
+          version,
+        },
+        { parent }
+      );
+    }
+
+    function contentType(file: string) {
+      const types: Record<string, string> = {
+        ".html": "text/html; charset=UTF-8",
+        ".css": "text/css; charset=UTF-8",
+        ".js": "application/javascript; charset=UTF-8",
+        ".mjs": "application/javascript; charset=UTF-8",
+        ".json": "application/json; charset=UTF-8",
+        ".txt": "text/plain; charset=UTF-8",
+        ".xml": "application/xml; charset=UTF-8",
+        ".svg": "image/svg+xml",
+        ".png": "image/png",
+        ".jpg": "image/jpeg",
+        ".jpeg": "image/jpeg",
+        ".gif": "image/gif",
+        ".webp": "image/webp",
+        ".ico": "image/x-icon",
+        ".woff": "font/woff",
+        ".woff2": "font/woff2",
+        ".wasm": "application/wasm",
+      };
+      return types[path.extname(file).toLowerCase()];
+    }
+  }
+
+  public get url() {
+    return this.distribution.url;
+  }
+
+  public get customDomainUrl() {
+    return this.distribution.customDomainUrl;
+  }
+
+  public get nodes() {
+    return {
+      bucket: this.bucket,
+      distribution: this.distribution,
+    };
+  }
+}
@@ -1,8 +1,190 @@
 import type { ProviderArgs as AWS } from "@pulumi/aws";
 
+export interface FunctionConfig {
+  /**
+   * Environment variables merged into the function's environment
+   */
+  environment?: Record<string, string>;
+  /**
+   * Outputs returned from `run()` to expose as `SST_LINK_<name>` environment
+   * variables, the deploy fails if one of them does not exist
+   */
+  link?: string[];
+  /**
+   * Keep instances of the function initialized, personal stages (stages not
+   * in `protect`) skip this unless `personal` is set
+   */
+  warm?: WarmConfig;
+}
+
+export interface WarmConfig {
+  /**
+   * Instances kept initialized with provisioned concurrency
+   */
+  provisioned?: number;
+  /**
+   * Invoke the function on a schedule to keep it warm, like `"5 minutes"`
+   */
+  schedule?: string;
+  /**
+   * Number of instances each scheduled invocation warms, at most 5
+   * @default 1
+   */
+  concurrency?: number;
+  /**
+   * Also keep the function warm on personal stages
+   * @default false
+   */
+  personal?: boolean;
+}
+
+export interface FreezeWindow {
+  /**
+   * Either weekly like `"Fri 16:00"` or a date like `"2024-12-20 00:00"`
+   */
+  start: string;
+  end: string;
+  /**
+   * @default "UTC"
+   */
+  timezone?: string;
+}
+
+export interface HealthCheck {
+  /**
+   * Name of the output returned from `run()` that holds the url to check
+   */
+  output: string;
+  path?: string;
+  /**
+   * @default 200
+   */
+  status?: number;
+  /**
+   * Maximum response time in milliseconds
+   */
+  latency?: number;
+}
+
+export interface FanoutTarget {
+  account?: string;
+  region: string;
+  profile?: string;
+  /**
+   * Name of the role to assume in `account`
+   */
+  role?: string;
+}
+
 export interface App {
   name: string;
   removalPolicy?: "remove" | "retain" | "retain-all";
+  protect?: string[];
+  functions?: Record<string, FunctionConfig>;
+  /**
+   * Free form settings like feature flags or memory sizes, developers can
+   * override them per stage in `.sst/local.config`, along with the
+   * environment of functions
+   */
+  vars?: Record<string, any>;
+  /**
+   * Periods per stage during which deploys are refused unless
+   * `--override-freeze` is passed
+   */
+  freeze?: Record<string, FreezeWindow[]>;
+  /**
+   * Checks that must pass after `sst deploy` for the deploy to succeed
+   */
+  health?: {
+    checks: HealthCheck[];
+    /**
+     * Seconds to wait for every check to pass
+     * @default 120
+     */
+    timeout?: number;
+    /**
+     * Redeploy the commit of the previous successful update if the checks fail
+     */
+    rollback?: boolean;
+  };
+  tests?: {
+    /**
+     * Command run after every successful `sst deploy`, outputs are available
+     * as `SST_OUTPUT_<NAME>` environment variables. A non-zero exit fails the
+     * deploy.
+     */
+    smoke?: string;
+    /**
+     * Redeploy the commit of the previous successful update if the smoke test
+     * fails
+     */
+    rollback?: boolean;
+  };
+  /**
+   * Accounts and regions that `sst deploy --fanout` deploys the stage to
+   */
+  fanout?: {
+    targets?: FanoutTarget[];
+    /**
+     * Deploy to every active account of the AWS organization, the credentials
+     * must be from the management account
+     */
+    organization?: {
+      regions: string[];
+      /**
+       * @default "OrganizationAccountAccessRole"
+       */
+      role?: string;
+      exclude?: string[];
+    };
+    /**
+     * Maximum number of targets updated at the same time
+     */
+    concurrency?: number;
+  };
+  /**
+   * Compare what a deploy creates against the account's service quotas before
+   * it starts, either warning or failing when one would be exceeded. This runs
+   * an extra preview before every deploy.
+   * @default "off"
+   */
+  quotas?: "warn" | "fail" | "off";
+  /**
+   * Database migrations applied by `sst deploy` once the resources are up,
+   * the applied ones are recorded in the state of the stage. The database
+   * must be reachable from where `sst deploy` runs.
+   */
+  migrations?: {
+    /**
+     * Directory with one file per migration, pending files are applied in
+     * lexical order
+     */
+    directory?: string;
+    /**
+     * Command that applies a migration, run once per pending file which is
+     * passed as `SST_MIGRATION`, like `psql "$SST_OUTPUT_DATABASE" -f "$SST_MIGRATION"`.
+     * Without a directory the command runs on every deploy, for tools like
+     * `prisma migrate deploy` that track migrations themselves.
+     */
+    command: string;
+  };
+  /**
+   * Commands that fill a new personal stage with data, they run after its
+   * first successful deploy and with `sst seed`
+   */
+  seed?: {
+    commands: string[];
+    /**
+     * Outputs returned from `run()` to expose as `SST_LINK_<name>`
+     * environment variables
+     */
+    link?: string[];
+  };
+  /**
+   * Show a desktop notification when `sst deploy` or `sst remove` finishes,
+   * `--notify=false` turns it off for a single run
+   */
+  notify?: boolean;
   providers?: {
     aws?: AWS;
   };
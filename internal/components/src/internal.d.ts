@@ -5,8 +5,34 @@ export declare global {
       home: string;
       root: string;
       work: string;
+      state: string;
+      /**
+       * Files written while the program runs, like function zips and the
+       * status streams the cli tails
+       */
+      run: string;
     };
     backend: string;
     env: Record<string, string>;
+    git?: {
+      branch: string;
+      commit: string;
+      dirty: boolean;
+      author: string;
+    };
+    drift: string[];
+    target: string[];
+    /**
+     * "personal" for stages not in `protect`, "off" after `sst warm down`
+     */
+    warm: "on" | "personal" | "off";
+    /**
+     * Largest allowed function zip in bytes from `--fail-on-size`, 0 if unset
+     */
+    bundleLimit: number;
+    /**
+     * Urn, name or type of the resource `--inject-failure` fails
+     */
+    injectFailure?: string;
   };
 }
@@ -5,6 +5,7 @@ import esbuild, { BuildOptions, BuildResult } from "esbuild";
 import pulumi from "@pulumi/pulumi";
 import { existsAsync, findAbove } from "../util/fs.js";
 import { HandlerFunctionArgs } from "../components/handler-function.js";
+import { metafilePath } from "../components/helpers/bundle.js";
 
 export async function build(
   name: string,
@@ -100,6 +101,9 @@ export async function build(
 
   try {
     const result = await ctx.rebuild();
+    const metafile = metafilePath(name);
+    await fs.mkdir(path.dirname(metafile), { recursive: true });
+    await fs.writeFile(metafile, JSON.stringify(result.metafile));
 
     // Install node_modules
     const installPackages = [
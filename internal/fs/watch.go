@@ -0,0 +1,84 @@
+package fs
+
+import (
+	"io/fs"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watch emits the path of a changed file once the tree has been quiet for
+// the debounce interval, so saving several files at once triggers a single
+// change. Directories matching one of the ignored names are skipped entirely.
+func Watch(root string, debounce time.Duration, ignore []string) (<-chan string, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	// fsnotify is not recursive, every directory is watched on its own
+	add := func(dir string) {
+		filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || !d.IsDir() {
+				return nil
+			}
+			if path != root && slices.Contains(ignore, d.Name()) {
+				return filepath.SkipDir
+			}
+			err = watcher.Add(path)
+			if err != nil {
+				slog.Warn("failed to watch", "path", path, "err", err)
+			}
+			return nil
+		})
+	}
+	add(root)
+
+	out := make(chan string)
+	go func() {
+		defer watcher.Close()
+		changed := ""
+		var quiet <-chan time.Time
+		for {
+			select {
+			case evt, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if ignored(root, evt.Name, ignore) || evt.Op == fsnotify.Chmod {
+					continue
+				}
+				if evt.Op.Has(fsnotify.Create) {
+					add(evt.Name)
+				}
+				changed = evt.Name
+				quiet = time.After(debounce)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				slog.Warn("watcher error", "err", err)
+			case <-quiet:
+				out <- changed
+				quiet = nil
+			}
+		}
+	}()
+	return out, nil
+}
+
+// ignored is true if the path is inside one of the ignored directories
+func ignored(root string, path string, ignore []string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	for dir := rel; dir != "." && dir != string(filepath.Separator); dir = filepath.Dir(dir) {
+		if slices.Contains(ignore, filepath.Base(dir)) {
+			return true
+		}
+	}
+	return false
+}
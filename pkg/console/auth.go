@@ -0,0 +1,273 @@
+package console
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sst/ion/pkg/global"
+)
+
+const DEFAULT_URL = "https://console.sst.dev"
+
+const clientID = "sst-cli"
+
+// Session is the login of this machine to the console, nothing is synced
+// without one
+type Session struct {
+	Url          string    `json:"url"`
+	AccessToken  string    `json:"accessToken"`
+	RefreshToken string    `json:"refreshToken,omitempty"`
+	Expires      time.Time `json:"expires"`
+	Email        string    `json:"email"`
+	Workspace    string    `json:"workspace"`
+}
+
+type DeviceCode struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+
+	url string
+}
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	Error        string `json:"error"`
+}
+
+func pathSession() string {
+	return filepath.Join(global.ConfigDir(), "console", "session.json")
+}
+
+func consoleUrl() string {
+	if value := os.Getenv("SST_CONSOLE_URL"); value != "" {
+		return strings.TrimSuffix(value, "/")
+	}
+	return DEFAULT_URL
+}
+
+var client = http.Client{Timeout: 10 * time.Second}
+
+// Load returns nil when logged out
+func Load() (*Session, error) {
+	data, err := os.ReadFile(pathSession())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var result Session
+	err = json.Unmarshal(data, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (s *Session) save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(pathSession()), 0700)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(pathSession(), data, 0600)
+}
+
+// StartLogin begins the device code flow, the user approves the code in a
+// browser while Wait polls for the token
+func StartLogin() (*DeviceCode, error) {
+	base := consoleUrl()
+	result := &DeviceCode{url: base}
+	err := postForm(base+"/oauth/device/code", url.Values{"client_id": {clientID}}, result)
+	if err != nil {
+		return nil, fmt.Errorf("Could not reach the console at %v: %w", base, err)
+	}
+	if result.Interval <= 0 {
+		result.Interval = 5
+	}
+	return result, nil
+}
+
+func (d *DeviceCode) Wait() (*Session, error) {
+	interval := time.Duration(d.Interval) * time.Second
+	deadline := time.Now().Add(time.Duration(d.ExpiresIn) * time.Second)
+	for time.Now().Before(deadline) {
+		time.Sleep(interval)
+		var token tokenResponse
+		err := postForm(d.url+"/oauth/token", url.Values{
+			"client_id":   {clientID},
+			"device_code": {d.DeviceCode},
+			"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+		}, &token)
+		if err != nil && token.Error == "" {
+			return nil, err
+		}
+		switch token.Error {
+		case "":
+		case "authorization_pending":
+			continue
+		case "slow_down":
+			interval += 5 * time.Second
+			continue
+		case "access_denied":
+			return nil, fmt.Errorf("Login was denied")
+		case "expired_token":
+			return nil, fmt.Errorf("Login code expired, run sst login again")
+		default:
+			return nil, fmt.Errorf("Login failed: %v", token.Error)
+		}
+
+		session := &Session{
+			Url:          d.url,
+			AccessToken:  token.AccessToken,
+			RefreshToken: token.RefreshToken,
+			Expires:      time.Now().Add(time.Duration(token.ExpiresIn) * time.Second),
+		}
+		err = session.identify()
+		if err != nil {
+			return nil, err
+		}
+		return session, session.save()
+	}
+	return nil, fmt.Errorf("Login code expired, run sst login again")
+}
+
+func (s *Session) identify() error {
+	var identity struct {
+		Email     string `json:"email"`
+		Workspace string `json:"workspace"`
+	}
+	err := s.request("GET", "/api/whoami", nil, &identity)
+	if err != nil {
+		return err
+	}
+	s.Email = identity.Email
+	s.Workspace = identity.Workspace
+	return nil
+}
+
+// Whoami checks the session against the console, when it can not be reached
+// the cached identity is returned with the error
+func Whoami() (*Session, error) {
+	session, err := Load()
+	if err != nil || session == nil {
+		return session, err
+	}
+	err = session.identify()
+	if err != nil {
+		return session, err
+	}
+	return session, session.save()
+}
+
+// Logout revokes the token if the console can be reached and forgets the
+// session and its unsent events either way
+func Logout() error {
+	session, err := Load()
+	if err != nil || session == nil {
+		return err
+	}
+	err = postForm(session.Url+"/oauth/revoke", url.Values{
+		"client_id": {clientID},
+		"token":     {session.AccessToken},
+	}, nil)
+	if err != nil {
+		slog.Info("could not revoke console token", "err", err)
+	}
+	os.Remove(pathQueue())
+	return os.Remove(pathSession())
+}
+
+func (s *Session) refresh() error {
+	if s.RefreshToken == "" {
+		return fmt.Errorf("Console session expired, run sst login again")
+	}
+	var token tokenResponse
+	err := postForm(s.Url+"/oauth/token", url.Values{
+		"client_id":     {clientID},
+		"refresh_token": {s.RefreshToken},
+		"grant_type":    {"refresh_token"},
+	}, &token)
+	if err != nil {
+		return err
+	}
+	s.AccessToken = token.AccessToken
+	if token.RefreshToken != "" {
+		s.RefreshToken = token.RefreshToken
+	}
+	s.Expires = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	return s.save()
+}
+
+func (s *Session) request(method string, path string, body interface{}, out interface{}) error {
+	if time.Now().After(s.Expires.Add(-time.Minute)) {
+		err := s.refresh()
+		if err != nil {
+			return err
+		}
+	}
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, s.Url+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.AccessToken)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized {
+		return fmt.Errorf("Console session is no longer valid, run sst login again")
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("Console returned %v", resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// postForm decodes the body into out for error responses too, oauth reports
+// pending logins as errors
+func postForm(endpoint string, values url.Values, out interface{}) error {
+	resp, err := client.PostForm(endpoint, values)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		json.NewDecoder(resp.Body).Decode(out)
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("Console returned %v", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package console
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sst/ion/pkg/global"
+)
+
+// Event is synced to the console when logged in, like finished updates and
+// audit entries
+type Event struct {
+	Type  string      `json:"type"`
+	App   string      `json:"app"`
+	Stage string      `json:"stage"`
+	Time  time.Time   `json:"time"`
+	Data  interface{} `json:"data"`
+}
+
+func pathQueue() string {
+	return filepath.Join(global.ConfigDir(), "console", "queue.ndjson")
+}
+
+// Track queues the event locally so it survives being offline, a later Flush
+// sends it. Nothing is recorded while logged out.
+func Track(kind string, app string, stage string, data interface{}) {
+	session, err := Load()
+	if err != nil || session == nil {
+		return
+	}
+	line, err := json.Marshal(Event{
+		Type:  kind,
+		App:   app,
+		Stage: stage,
+		Time:  time.Now().UTC(),
+		Data:  data,
+	})
+	if err != nil {
+		return
+	}
+	if os.MkdirAll(filepath.Dir(pathQueue()), 0700) != nil {
+		return
+	}
+	file, err := os.OpenFile(pathQueue(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	file.Write(append(line, '\n'))
+}
+
+// Flush sends the queued events, events that fail to send stay queued for the
+// next run
+func Flush() {
+	session, err := Load()
+	if err != nil || session == nil {
+		return
+	}
+	requeueOrphans()
+	sending := pathQueue() + "." + time.Now().UTC().Format(queueTimeFormat)
+	err = os.Rename(pathQueue(), sending)
+	if err != nil {
+		return
+	}
+	events, err := readQueue(sending)
+	if err == nil && len(events) > 0 {
+		err = session.request("POST", "/api/events", events, nil)
+	}
+	if err != nil {
+		slog.Info("console events not sent", "err", err)
+		requeue(sending)
+		return
+	}
+	os.Remove(sending)
+}
+
+func readQueue(path string) ([]json.RawMessage, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	result := []json.RawMessage{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 16*1024*1024)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		result = append(result, json.RawMessage(append([]byte{}, scanner.Bytes()...)))
+	}
+	return result, scanner.Err()
+}
+
+const queueTimeFormat = "20060102150405.000000"
+
+// requeueOrphans picks up batches left behind by a process that exited while
+// sending them, recent ones may still be in flight in another process
+func requeueOrphans() {
+	matches, _ := filepath.Glob(pathQueue() + ".*")
+	for _, match := range matches {
+		taken, err := time.Parse(queueTimeFormat, strings.TrimPrefix(match, pathQueue()+"."))
+		if err != nil || time.Since(taken) < time.Minute {
+			continue
+		}
+		requeue(match)
+	}
+}
+
+func requeue(path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	file, err := os.OpenFile(pathQueue(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	_, err = file.Write(data)
+	if err == nil {
+		os.Remove(path)
+	}
+}
+
+// Stream posts progress to the console while an update runs. Once a batch
+// fails to send the stream stops trying, only the queued summary is kept.
+type Stream struct {
+	session *Session
+	app     string
+	stage   string
+	lock    sync.Mutex
+	pending []interface{}
+	failed  bool
+	done    chan struct{}
+	closed  sync.WaitGroup
+}
+
+// Live returns nil while logged out
+func Live(app string, stage string) *Stream {
+	session, err := Load()
+	if err != nil || session == nil {
+		return nil
+	}
+	result := &Stream{
+		session: session,
+		app:     app,
+		stage:   stage,
+		done:    make(chan struct{}),
+	}
+	result.closed.Add(1)
+	go result.loop()
+	return result
+}
+
+func (s *Stream) Send(item interface{}) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.failed {
+		return
+	}
+	s.pending = append(s.pending, item)
+}
+
+func (s *Stream) loop() {
+	defer s.closed.Done()
+	for {
+		select {
+		case <-s.done:
+			s.flush()
+			return
+		case <-time.After(2 * time.Second):
+			s.flush()
+		}
+	}
+}
+
+func (s *Stream) flush() {
+	s.lock.Lock()
+	batch := s.pending
+	s.pending = nil
+	s.lock.Unlock()
+	if len(batch) == 0 {
+		return
+	}
+	err := s.session.request("POST", "/api/live", map[string]interface{}{
+		"app":    s.app,
+		"stage":  s.stage,
+		"events": batch,
+	}, nil)
+	if err != nil {
+		slog.Info("console progress not sent", "err", err)
+		s.lock.Lock()
+		s.failed = true
+		s.lock.Unlock()
+	}
+}
+
+// Track queues an event for the app and stage of the stream
+func (s *Stream) Track(kind string, data interface{}) {
+	Track(kind, s.app, s.stage, data)
+}
+
+// Close sends what is left and stops the stream
+func (s *Stream) Close() {
+	close(s.done)
+	s.closed.Wait()
+}
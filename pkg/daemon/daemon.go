@@ -0,0 +1,307 @@
+package daemon
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"github.com/sst/ion/pkg/project"
+)
+
+type Request struct {
+	Command string `json:"command"`
+}
+
+// Header is the first line of every response, the body only follows if
+// Error is empty
+type Header struct {
+	Error string `json:"error,omitempty"`
+}
+
+// Info is what a client needs to decide if it can forward a command or has to
+// run it itself
+type Info struct {
+	App       *project.App `json:"app"`
+	Protected bool         `json:"protected"`
+	Dirty     bool         `json:"dirty"`
+	Pending   int          `json:"pending"`
+}
+
+// Deployed is returned after a successful deploy
+type Deployed struct {
+	Outputs       map[string]interface{} `json:"outputs"`
+	TypesOutdated bool                   `json:"typesOutdated"`
+}
+
+func SocketPath(root string) string {
+	return filepath.Join(root, ".sst", "daemon.sock")
+}
+
+// set while a stack command runs, interrupts only make sense then
+var running atomic.Bool
+
+// Interrupt cancels the running command gracefully, it returns false if the
+// daemon is idle
+func Interrupt(p *project.Project) bool {
+	if !running.Load() {
+		return false
+	}
+	err := p.Stack.Interrupt()
+	if err != nil {
+		slog.Error("failed to interrupt", "err", err)
+	}
+	return true
+}
+
+// Serve keeps the project, and with it the node process, alive between
+// commands. Requests are handled one at a time since they share the same
+// node process, except for interrupts.
+func Serve(p *project.Project) error {
+	path := SocketPath(p.PathRoot())
+	os.Remove(path)
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+	// responses include the resolved provider credentials
+	err = os.Chmod(path, 0600)
+	if err != nil {
+		return err
+	}
+	slog.Info("daemon listening", "socket", path)
+
+	lock := sync.Mutex{}
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			err := handle(p, conn, &lock)
+			if err != nil {
+				slog.Error("daemon request failed", "err", err)
+			}
+		}()
+	}
+}
+
+func handle(p *project.Project, conn net.Conn, lock *sync.Mutex) error {
+	reader := bufio.NewReader(conn)
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		// Dial only checks that the socket accepts connections
+		if errors.Is(err, io.EOF) && len(line) == 0 {
+			return nil
+		}
+		return err
+	}
+	var req Request
+	err = json.Unmarshal(line, &req)
+	if err != nil {
+		return err
+	}
+	slog.Info("daemon request", "command", req.Command)
+
+	encoder := json.NewEncoder(conn)
+	respond := func(body interface{}, err error) error {
+		if err != nil {
+			encoder.Encode(Header{Error: err.Error()})
+			return err
+		}
+		err = encoder.Encode(Header{})
+		if err != nil {
+			return err
+		}
+		return encoder.Encode(body)
+	}
+
+	if req.Command == "interrupt" {
+		return respond(Interrupt(p), nil)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	switch req.Command {
+	case "info":
+		// pick up changes to sst.config.ts since the last command
+		err := p.Reload()
+		if err != nil {
+			return respond(nil, err)
+		}
+		info := Info{App: p.App(), Protected: p.IsProtected()}
+		if git := p.Git(); git != nil {
+			info.Dirty = git.Dirty
+		}
+		pending, err := p.Stack.Pending()
+		if err != nil {
+			return respond(nil, err)
+		}
+		info.Pending = len(pending)
+		return respond(info, nil)
+	case "outputs":
+		return respond(p.Stack.Outputs())
+	case "deployed":
+		err := p.Stack.ClearDrift()
+		if err != nil {
+			return respond(nil, err)
+		}
+		var result Deployed
+		result.Outputs, err = p.Stack.Outputs()
+		if err != nil {
+			return respond(nil, err)
+		}
+		result.TypesOutdated, err = p.TypesOutdated()
+		return respond(result, err)
+	case "removed":
+		return respond(true, p.MarkRemoved())
+	}
+
+	var events project.StackEventStream
+	switch req.Command {
+	case "up":
+		events, err = p.Stack.Deploy()
+	case "destroy":
+		events, err = p.Stack.Remove()
+	case "refresh":
+		events, err = p.Stack.Refresh()
+	case "cancel":
+		events, err = p.Stack.Cancel()
+	default:
+		err = fmt.Errorf("Unknown command: %v", req.Command)
+	}
+	if err != nil {
+		return respond(nil, err)
+	}
+	running.Store(true)
+	defer running.Store(false)
+
+	err = encoder.Encode(Header{})
+	for evt := range events {
+		if err != nil {
+			// drain so the node process is ready for the next request
+			continue
+		}
+		err = encoder.Encode(evt)
+	}
+	return err
+}
+
+type Client struct {
+	root string
+}
+
+// Dial returns an error if no daemon is running for the project
+func Dial(root string) (*Client, error) {
+	conn, err := net.Dial("unix", SocketPath(root))
+	if err != nil {
+		return nil, err
+	}
+	conn.Close()
+	return &Client{root: root}, nil
+}
+
+// send writes the request and reads the header of the response, the
+// returned decoder reads the body
+func (c *Client) send(command string) (net.Conn, *json.Decoder, error) {
+	conn, err := net.Dial("unix", SocketPath(c.root))
+	if err != nil {
+		return nil, nil, err
+	}
+	err = json.NewEncoder(conn).Encode(Request{Command: command})
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	decoder := json.NewDecoder(conn)
+	var header Header
+	err = decoder.Decode(&header)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	if header.Error != "" {
+		conn.Close()
+		return nil, nil, fmt.Errorf("%v", header.Error)
+	}
+	return conn, decoder, nil
+}
+
+func (c *Client) call(command string, out interface{}) error {
+	conn, decoder, err := c.send(command)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return decoder.Decode(out)
+}
+
+// Info reloads the config in the daemon and describes the stage
+func (c *Client) Info() (*Info, error) {
+	var info Info
+	err := c.call("info", &info)
+	if err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+func (c *Client) Outputs() (map[string]interface{}, error) {
+	result := map[string]interface{}{}
+	err := c.call("outputs", &result)
+	return result, err
+}
+
+// Deployed clears the drift after a successful deploy and returns the new
+// outputs
+func (c *Client) Deployed() (*Deployed, error) {
+	var result Deployed
+	err := c.call("deployed", &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// MarkRemoved records that the stage was removed after a successful remove
+func (c *Client) MarkRemoved() error {
+	var ok bool
+	return c.call("removed", &ok)
+}
+
+// Interrupt cancels the command the daemon is running gracefully
+func (c *Client) Interrupt() error {
+	var ok bool
+	return c.call("interrupt", &ok)
+}
+
+func (c *Client) Run(command string) (project.StackEventStream, error) {
+	conn, decoder, err := c.send(command)
+	if err != nil {
+		return nil, err
+	}
+	out := make(project.StackEventStream)
+	go func() {
+		defer conn.Close()
+		defer close(out)
+		for {
+			var evt project.StackEvent
+			err := decoder.Decode(&evt)
+			if err != nil {
+				return
+			}
+			out <- evt
+		}
+	}()
+	return out, nil
+}
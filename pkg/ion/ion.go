@@ -0,0 +1,330 @@
+// Package ion runs the stack operations of an sst app from Go, for tools that
+// would otherwise shell out to the CLI and scrape its output.
+//
+//	stack, err := ion.Open(ion.Options{Config: "/path/to/sst.config.ts", Stage: "production"})
+//	if err != nil {
+//		return err
+//	}
+//	defer stack.Close()
+//	result, err := stack.Deploy(ctx, func(evt project.StackEvent) {
+//		// raw engine events, nil to ignore them
+//	})
+//
+// A Stack runs one operation at a time since all of them share the node
+// process that evaluates sst.config.ts.
+//
+// Deploy applies the same gates as `sst deploy`: it refuses frozen stages
+// unless Options.OverrideFreeze is set, and after a successful update it
+// applies migrations, seeds personal stages and runs the health checks and
+// smoke test, reporting failures in Result.Errors. Unlike the CLI it never
+// rolls back on a failed check.
+package ion
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sst/ion/pkg/project"
+)
+
+type Options struct {
+	// Path to sst.config.ts, discovered from the working directory if empty
+	Config string
+	// Stage to operate on, defaults to the personal stage of the app
+	Stage string
+	// Version of the sst package to install, defaults to the installed one
+	Version string
+	// Reason recorded in the audit log when deploying during a freeze window,
+	// frozen stages are refused if empty
+	OverrideFreeze string
+}
+
+type Stack struct {
+	project *project.Project
+	options Options
+	lock    sync.Mutex
+}
+
+// Change is a resource the operation created, updated, deleted or replaced
+type Change struct {
+	URN  string `json:"urn"`
+	Type string `json:"type"`
+	Op   string `json:"op"`
+}
+
+// Error is a resource or program error reported by the engine
+type Error struct {
+	URN     string `json:"urn,omitempty"`
+	Message string `json:"message"`
+}
+
+type Result struct {
+	Command string   `json:"command"`
+	App     string   `json:"app"`
+	Stage   string   `json:"stage"`
+	Changes []Change `json:"changes"`
+	Errors  []Error  `json:"errors"`
+	// like deploying uncommitted changes to a protected stage
+	Warnings []string               `json:"warnings"`
+	Outputs  map[string]interface{} `json:"outputs,omitempty"`
+}
+
+func (r *Result) Failed() bool {
+	return len(r.Errors) > 0
+}
+
+func Open(opts Options) (*Stack, error) {
+	cfgPath := opts.Config
+	if cfgPath == "" {
+		var err error
+		cfgPath, err = project.Discover()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !project.CheckDeps(opts.Version, cfgPath) {
+		err := project.InstallDeps(opts.Version, cfgPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	p, err := project.New(opts.Version, cfgPath)
+	if err != nil {
+		return nil, err
+	}
+
+	app := p.App()
+	if opts.Stage != "" {
+		app.Stage = opts.Stage
+	}
+	if app.Stage == "" {
+		p.LoadPersonalStage()
+	}
+	if app.Stage == "" {
+		p.Stack.Kill()
+		return nil, fmt.Errorf("No stage set for %v and no personal stage to fall back to", app.Name)
+	}
+	slog.Info("opened stack", "app", app.Name, "stage", app.Stage)
+	return &Stack{project: p, options: opts}, nil
+}
+
+func (s *Stack) App() *project.App {
+	return s.project.App()
+}
+
+// Project is the underlying project for operations this package does not
+// wrap
+func (s *Stack) Project() *project.Project {
+	return s.project
+}
+
+// Close stops the node process, the Stack can not be used afterwards
+func (s *Stack) Close() {
+	s.project.Stack.Kill()
+}
+
+func (s *Stack) Deploy(ctx context.Context, onEvent func(project.StackEvent)) (*Result, error) {
+	window, err := s.project.Frozen(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if window != nil {
+		if s.options.OverrideFreeze == "" {
+			return nil, fmt.Errorf("Stage %v is frozen (%v), set OverrideFreeze to deploy anyway", s.App().Stage, window)
+		}
+		err = s.project.Audit("override-freeze", s.options.OverrideFreeze)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result, err := s.run(ctx, "up", onEvent)
+	if err != nil || result.Failed() {
+		return result, err
+	}
+	if s.project.IsProtected() {
+		if git := s.project.Git(); git != nil && git.Dirty {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("Deployed uncommitted changes to protected stage %v", result.Stage))
+		}
+	}
+	return result, s.afterDeploy(result)
+}
+
+// afterDeploy runs the post deploy gates of `sst deploy` in the same order,
+// stopping at the first one that fails
+func (s *Stack) afterDeploy(result *Result) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	err := s.project.Stack.ClearDrift()
+	if err != nil {
+		return err
+	}
+	if s.App().Migrations != nil {
+		_, err := s.project.Migrate(func(name string) {
+			slog.Info("migrating", "name", name)
+		})
+		if err != nil {
+			result.Errors = append(result.Errors, Error{Message: err.Error()})
+			return nil
+		}
+	}
+	seed, err := s.project.ShouldSeed()
+	if err != nil {
+		return err
+	}
+	if seed {
+		err := s.project.Seed(func(command string) {
+			slog.Info("seeding", "command", command)
+		})
+		if err != nil {
+			result.Errors = append(result.Errors, Error{Message: err.Error()})
+			return nil
+		}
+	}
+	health, ok, err := s.project.CheckHealth()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		for _, check := range health {
+			if !check.Healthy() {
+				result.Errors = append(result.Errors, Error{Message: fmt.Sprintf("Health check %v failed: %v", check.Check.Output, check.Error)})
+			}
+		}
+		return nil
+	}
+	code, err := s.project.Smoke()
+	if err != nil {
+		return err
+	}
+	if code > 0 {
+		result.Errors = append(result.Errors, Error{Message: fmt.Sprintf("Smoke test failed with exit code %v", code)})
+	}
+	return nil
+}
+
+// Preview computes the changes Deploy would make without making them
+func (s *Stack) Preview(ctx context.Context, onEvent func(project.StackEvent)) (*Result, error) {
+	return s.run(ctx, "preview", onEvent)
+}
+
+func (s *Stack) Remove(ctx context.Context, onEvent func(project.StackEvent)) (*Result, error) {
+	result, err := s.run(ctx, "destroy", onEvent)
+	if err != nil || result.Failed() {
+		return result, err
+	}
+	return result, s.project.MarkRemoved()
+}
+
+func (s *Stack) Refresh(ctx context.Context, onEvent func(project.StackEvent)) (*Result, error) {
+	return s.run(ctx, "refresh", onEvent)
+}
+
+// Outputs returns the outputs of the last deploy with secret values
+// replaced by [secret]
+func (s *Stack) Outputs() (map[string]interface{}, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.project.Stack.RedactedOutputs()
+}
+
+// Secrets returns the names of the outputs that are secret, their values are
+// never returned
+func (s *Stack) Secrets() ([]string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.project.Stack.Secrets()
+}
+
+// run cancels the update gracefully when ctx is done, resources already in
+// flight still finish
+func (s *Stack) run(ctx context.Context, cmd string, onEvent func(project.StackEvent)) (*Result, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var events project.StackEventStream
+	var err error
+	switch cmd {
+	case "up":
+		events, err = s.project.Stack.Deploy()
+	case "preview":
+		events, err = s.project.Stack.Preview()
+	case "destroy":
+		events, err = s.project.Stack.Remove()
+	case "refresh":
+		events, err = s.project.Stack.Refresh()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			slog.Info("interrupting stack", "cmd", cmd)
+			s.project.Stack.Interrupt()
+		case <-done:
+		}
+	}()
+
+	app := s.project.App()
+	result := &Result{
+		Command:  cmd,
+		App:      app.Name,
+		Stage:    app.Stage,
+		Changes:  []Change{},
+		Errors:   []Error{},
+		Warnings: []string{},
+	}
+	for evt := range events {
+		if onEvent != nil {
+			onEvent(evt)
+		}
+		collect(result, evt)
+	}
+
+	if ctx.Err() != nil {
+		return result, ctx.Err()
+	}
+	if cmd == "up" && !result.Failed() {
+		result.Outputs, err = s.project.Stack.RedactedOutputs()
+		if err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+func collect(result *Result, evt project.StackEvent) {
+	if evt.ConcurrentUpdateEvent != nil {
+		result.Errors = append(result.Errors, Error{
+			Message: "Another update is already running for this stage",
+		})
+	}
+	if evt.ResOutputsEvent != nil {
+		step := evt.ResOutputsEvent.Metadata
+		if step.Type == "pulumi:pulumi:Stack" || step.Op == "same" || step.Op == "read" || step.Op == "refresh" {
+			return
+		}
+		result.Changes = append(result.Changes, Change{
+			URN:  step.URN,
+			Type: step.Type,
+			Op:   string(step.Op),
+		})
+	}
+	if evt.DiagnosticEvent != nil && evt.DiagnosticEvent.Severity == "error" {
+		result.Errors = append(result.Errors, Error{
+			URN:     evt.DiagnosticEvent.URN,
+			Message: strings.TrimSpace(evt.DiagnosticEvent.Message),
+		})
+	}
+}
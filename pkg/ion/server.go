@@ -0,0 +1,208 @@
+package ion
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/sst/ion/pkg/project"
+)
+
+// ServerEvent is one line of the ndjson stream returned by the operation
+// endpoints, every event but the last carries Event and the last one carries
+// Result or Error
+type ServerEvent struct {
+	Event  *project.StackEvent `json:"event,omitempty"`
+	Result *Result             `json:"result,omitempty"`
+	Error  string              `json:"error,omitempty"`
+}
+
+// AppInfo is what GET /app returns, the provider config is left out since it
+// holds the resolved credentials
+type AppInfo struct {
+	Name          string   `json:"name"`
+	Stage         string   `json:"stage"`
+	RemovalPolicy string   `json:"removalPolicy"`
+	Protect       []string `json:"protect"`
+}
+
+// NewToken returns a random token to authenticate requests to the server
+func NewToken() (string, error) {
+	data := make([]byte, 32)
+	_, err := rand.Read(data)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(data), nil
+}
+
+// Handler exposes the stack over http for tooling that is not written in Go.
+// Every request needs an `Authorization: Bearer <token>` header.
+//
+//	POST /deploy, /preview, /remove, /refresh   ndjson stream of ServerEvent
+//	GET  /app, /outputs, /secrets               json
+//
+// Requests that would run an operation while another one is in progress
+// wait for it to finish.
+func Handler(stack *Stack, token string) http.Handler {
+	mux := http.NewServeMux()
+	operations := map[string]func(*http.Request, func(project.StackEvent)) (*Result, error){
+		"/deploy": func(r *http.Request, onEvent func(project.StackEvent)) (*Result, error) {
+			return stack.Deploy(r.Context(), onEvent)
+		},
+		"/preview": func(r *http.Request, onEvent func(project.StackEvent)) (*Result, error) {
+			return stack.Preview(r.Context(), onEvent)
+		},
+		"/remove": func(r *http.Request, onEvent func(project.StackEvent)) (*Result, error) {
+			return stack.Remove(r.Context(), onEvent)
+		},
+		"/refresh": func(r *http.Request, onEvent func(project.StackEvent)) (*Result, error) {
+			return stack.Refresh(r.Context(), onEvent)
+		},
+	}
+	for path, operation := range operations {
+		operation := operation
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			slog.Info("server request", "path", r.URL.Path)
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			w.WriteHeader(http.StatusOK)
+			flusher, _ := w.(http.Flusher)
+			encoder := json.NewEncoder(w)
+			write := func(evt ServerEvent) {
+				encoder.Encode(evt)
+				if flusher != nil {
+					flusher.Flush()
+				}
+			}
+			result, err := operation(r, func(evt project.StackEvent) {
+				write(ServerEvent{Event: &evt})
+			})
+			if err != nil {
+				write(ServerEvent{Result: result, Error: err.Error()})
+				return
+			}
+			write(ServerEvent{Result: result})
+		})
+	}
+
+	mux.HandleFunc("/app", func(w http.ResponseWriter, r *http.Request) {
+		app := stack.App()
+		writeJSON(w, AppInfo{
+			Name:          app.Name,
+			Stage:         app.Stage,
+			RemovalPolicy: app.RemovalPolicy,
+			Protect:       app.Protect,
+		}, nil)
+	})
+	mux.HandleFunc("/outputs", func(w http.ResponseWriter, r *http.Request) {
+		outputs, err := stack.Outputs()
+		writeJSON(w, outputs, err)
+	})
+	mux.HandleFunc("/secrets", func(w http.ResponseWriter, r *http.Request) {
+		secrets, err := stack.Secrets()
+		writeJSON(w, secrets, err)
+	})
+	return authorize(token, mux)
+}
+
+func authorize(token string, next http.Handler) http.Handler {
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// loopbackOnly rejects requests a browser could be tricked into sending,
+// through dns rebinding or from another origin
+func loopbackOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isLoopback(r.Host) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" {
+			parsed, err := url.Parse(origin)
+			if err != nil || !isLoopback(parsed.Host) {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func isLoopback(host string) bool {
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	host = strings.Trim(host, "[]")
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func writeJSON(w http.ResponseWriter, body interface{}, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	json.NewEncoder(w).Encode(body)
+}
+
+// Serve listens on addr, which has to be a loopback address or a unix socket
+// path. Requests need the token, and over tcp they also have to be addressed
+// to a loopback host without a foreign Origin.
+func Serve(stack *Stack, addr string, token string) error {
+	network := "tcp"
+	if strings.HasPrefix(addr, "unix:") {
+		network = "unix"
+		addr = strings.TrimPrefix(addr, "unix:")
+		os.Remove(addr)
+	}
+	if network == "tcp" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return err
+		}
+		if !isLoopback(host) {
+			return fmt.Errorf("Refusing to listen on %v, use a loopback address or a unix socket", addr)
+		}
+	}
+	listener, err := net.Listen(network, addr)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+	if network == "unix" {
+		err = os.Chmod(addr, 0600)
+		if err != nil {
+			return err
+		}
+	}
+	slog.Info("server listening", "network", network, "addr", addr)
+	handler := Handler(stack, token)
+	if network == "tcp" {
+		handler = loopbackOnly(handler)
+	}
+	return http.Serve(listener, handler)
+}
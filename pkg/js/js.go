@@ -73,6 +73,10 @@ const LOOP = `
   rl.on("close", () => {
     process.exit(0)
   })
+
+  // pulumi receives the same interrupt and finishes in-flight operations,
+  // stay alive to report them
+  process.on("SIGINT", () => {})
 `
 
 func Start(dir string) (*Process, error) {
@@ -95,6 +99,7 @@ func Start(dir string) (*Process, error) {
 	}
 	cmd := exec.Command("node", "--no-warnings", "--input-type=module", "-e", LOOP)
 	cmd.Dir = dir
+	configure(cmd)
 
 	stdIn, err := cmd.StdinPipe()
 	if err != nil {
@@ -148,6 +153,17 @@ func (p *Process) Scan() (Command, string) {
 	return CommandDone, ""
 }
 
+// Interrupt asks the running pulumi operation to stop gracefully
+func (p *Process) Interrupt() error {
+	slog.Info("interrupting process")
+	return interrupt(p.cmd)
+}
+
+func (p *Process) Kill() error {
+	slog.Info("killing process")
+	return kill(p.cmd)
+}
+
 type Message struct {
 }
 
@@ -0,0 +1,24 @@
+//go:build !windows
+
+package js
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// node and the pulumi processes it spawns get their own process group so an
+// interrupt can be forwarded to all of them at once
+func configure(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Setpgid: true,
+	}
+}
+
+func interrupt(cmd *exec.Cmd) error {
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGINT)
+}
+
+func kill(cmd *exec.Cmd) error {
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
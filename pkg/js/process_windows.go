@@ -0,0 +1,19 @@
+//go:build windows
+
+package js
+
+import (
+	"os"
+	"os/exec"
+)
+
+func configure(cmd *exec.Cmd) {
+}
+
+func interrupt(cmd *exec.Cmd) error {
+	return cmd.Process.Signal(os.Interrupt)
+}
+
+func kill(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,20 @@
+package migrate
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+)
+
+func LoadConfig(profile, region string) (aws.Config, error) {
+	return config.LoadDefaultConfig(context.Background(), func(lo *config.LoadOptions) error {
+		if profile != "" {
+			lo.SharedConfigProfile = profile
+		}
+		if region != "" {
+			lo.Region = region
+		}
+		return nil
+	})
+}
@@ -0,0 +1,289 @@
+package migrate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"gopkg.in/yaml.v3"
+)
+
+type cfnResource struct {
+	LogicalResourceId  string
+	PhysicalResourceId string
+	ResourceType       string
+	ResourceStatus     string
+}
+
+type cfnTemplate struct {
+	Resources map[string]struct {
+		Type           string                 `json:"Type" yaml:"Type"`
+		Properties     map[string]interface{} `json:"Properties" yaml:"Properties"`
+		DeletionPolicy string                 `json:"DeletionPolicy" yaml:"DeletionPolicy"`
+	} `json:"Resources" yaml:"Resources"`
+}
+
+type CfnResult struct {
+	*Result
+	Stack string
+	// the original template with DeletionPolicy set to Retain on every
+	// resource, nil if the template is not JSON and has to be edited by hand
+	Retain []byte
+	// parameter keys of the stack, kept as they are when updating it
+	Parameters []string
+}
+
+// Commands are the steps to hand the resources over, the stack is only
+// deleted once every resource is retained. Without a retain template the
+// DeletionPolicy has to be set by hand first, so the steps stop at the deploy.
+func (r *CfnResult) Commands(retainPath string) []string {
+	result := []string{}
+	if r.Retain != nil {
+		update := fmt.Sprintf(
+			"aws cloudformation update-stack --stack-name %v --region %v --template-body file://%v --capabilities CAPABILITY_IAM CAPABILITY_NAMED_IAM CAPABILITY_AUTO_EXPAND",
+			r.Stack, r.Region, retainPath,
+		)
+		if len(r.Parameters) > 0 {
+			update += " --parameters"
+			for _, key := range r.Parameters {
+				update += fmt.Sprintf(" ParameterKey=%v,UsePreviousValue=true", key)
+			}
+		}
+		result = append(result, update)
+		result = append(result, fmt.Sprintf("aws cloudformation wait stack-update-complete --stack-name %v --region %v", r.Stack, r.Region))
+	}
+	result = append(result, "sst deploy")
+	if r.Retain != nil {
+		result = append(result, fmt.Sprintf("aws cloudformation delete-stack --stack-name %v --region %v", r.Stack, r.Region))
+	}
+	return result
+}
+
+// WriteRetain saves the retain template next to the generated config
+func (r *CfnResult) WriteRetain(dir string) (string, error) {
+	if r.Retain == nil {
+		return "", nil
+	}
+	path := filepath.Join(dir, r.Stack+".retain.json")
+	return path, os.WriteFile(path, r.Retain, 0644)
+}
+
+func Cfn(cfg aws.Config, stack string) (*CfnResult, error) {
+	slog.Info("migrating cloudformation stack", "stack", stack, "region", cfg.Region)
+	ctx := context.TODO()
+	client := cloudformation.NewFromConfig(cfg)
+
+	resources := []cfnResource{}
+	pages := cloudformation.NewListStackResourcesPaginator(client, &cloudformation.ListStackResourcesInput{
+		StackName: aws.String(stack),
+	})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, summary := range page.StackResourceSummaries {
+			resources = append(resources, cfnResource{
+				LogicalResourceId:  aws.ToString(summary.LogicalResourceId),
+				PhysicalResourceId: aws.ToString(summary.PhysicalResourceId),
+				ResourceType:       aws.ToString(summary.ResourceType),
+				ResourceStatus:     string(summary.ResourceStatus),
+			})
+		}
+	}
+
+	described, err := client.DescribeStacks(ctx, &cloudformation.DescribeStacksInput{
+		StackName: aws.String(stack),
+	})
+	if err != nil {
+		return nil, err
+	}
+	parameters := []string{}
+	for _, item := range described.Stacks {
+		for _, parameter := range item.Parameters {
+			parameters = append(parameters, aws.ToString(parameter.ParameterKey))
+		}
+	}
+
+	out, err := client.GetTemplate(ctx, &cloudformation.GetTemplateInput{
+		StackName: aws.String(stack),
+	})
+	if err != nil {
+		return nil, err
+	}
+	body := []byte(aws.ToString(out.TemplateBody))
+	template := cfnTemplate{}
+	isJSON := json.Valid(body)
+	if isJSON {
+		err = json.Unmarshal(body, &template)
+	} else {
+		err = yaml.Unmarshal(body, &template)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse template for stack %v: %w", stack, err)
+	}
+
+	result := &CfnResult{
+		Result: &Result{
+			App:    appName(stack),
+			Region: cfg.Region,
+		},
+		Stack:      stack,
+		Parameters: parameters,
+	}
+	for _, resource := range resources {
+		if resource.ResourceType == "AWS::CDK::Metadata" {
+			continue
+		}
+		if strings.HasSuffix(resource.ResourceStatus, "_FAILED") || resource.PhysicalResourceId == "" {
+			result.Unmapped = append(result.Unmapped, Unmapped{
+				Name:   resource.LogicalResourceId,
+				Type:   resource.ResourceType,
+				Reason: "resource is in state " + resource.ResourceStatus,
+			})
+			continue
+		}
+		mapped, reason := mapCfn(resource, template.Resources[resource.LogicalResourceId].Properties)
+		if mapped == nil {
+			result.Unmapped = append(result.Unmapped, Unmapped{
+				Name:   resource.LogicalResourceId,
+				Type:   resource.ResourceType,
+				Reason: reason,
+			})
+			continue
+		}
+		result.Resources = append(result.Resources, *mapped)
+	}
+
+	if isJSON {
+		raw := map[string]interface{}{}
+		err = json.Unmarshal(body, &raw)
+		if err != nil {
+			return nil, err
+		}
+		if items, ok := raw["Resources"].(map[string]interface{}); ok {
+			for _, item := range items {
+				if resource, ok := item.(map[string]interface{}); ok {
+					resource["DeletionPolicy"] = "Retain"
+				}
+			}
+		}
+		result.Retain, err = json.MarshalIndent(raw, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func mapCfn(resource cfnResource, props map[string]interface{}) (*Resource, string) {
+	id := resource.PhysicalResourceId
+	result := &Resource{
+		Name:   resource.LogicalResourceId,
+		ID:     id,
+		Inputs: map[string]interface{}{},
+	}
+	switch resource.ResourceType {
+	case "AWS::S3::Bucket":
+		result.Type = "aws:s3/bucketV2:BucketV2"
+		result.Constructor = "aws.s3.BucketV2"
+		result.Inputs["bucket"] = id
+	case "AWS::DynamoDB::Table":
+		result.Type = "aws:dynamodb/table:Table"
+		result.Constructor = "aws.dynamodb.Table"
+		result.Inputs["name"] = id
+		attributes := []interface{}{}
+		for _, item := range list(props["AttributeDefinitions"]) {
+			attributes = append(attributes, map[string]interface{}{
+				"name": item["AttributeName"],
+				"type": item["AttributeType"],
+			})
+		}
+		result.Inputs["attributes"] = attributes
+		for _, item := range list(props["KeySchema"]) {
+			switch item["KeyType"] {
+			case "HASH":
+				result.Inputs["hashKey"] = item["AttributeName"]
+			case "RANGE":
+				result.Inputs["rangeKey"] = item["AttributeName"]
+			}
+		}
+		billing, _ := props["BillingMode"].(string)
+		if billing == "" {
+			billing = "PROVISIONED"
+		}
+		result.Inputs["billingMode"] = billing
+		if throughput, ok := props["ProvisionedThroughput"].(map[string]interface{}); ok {
+			result.Inputs["readCapacity"] = throughput["ReadCapacityUnits"]
+			result.Inputs["writeCapacity"] = throughput["WriteCapacityUnits"]
+		}
+		if len(list(props["GlobalSecondaryIndexes"])) > 0 || len(list(props["LocalSecondaryIndexes"])) > 0 {
+			result.Review = "copy the secondary indexes from the template"
+		}
+	case "AWS::SQS::Queue":
+		result.Type = "aws:sqs/queue:Queue"
+		result.Constructor = "aws.sqs.Queue"
+		result.Inputs["name"] = id[strings.LastIndex(id, "/")+1:]
+		if fifo, ok := props["FifoQueue"]; ok {
+			result.Inputs["fifoQueue"] = fifo
+		}
+	case "AWS::SNS::Topic":
+		result.Type = "aws:sns/topic:Topic"
+		result.Constructor = "aws.sns.Topic"
+		result.Inputs["name"] = id[strings.LastIndex(id, ":")+1:]
+	case "AWS::Logs::LogGroup":
+		result.Type = "aws:cloudwatch/logGroup:LogGroup"
+		result.Constructor = "aws.cloudwatch.LogGroup"
+		result.Inputs["name"] = id
+		if retention, ok := props["RetentionInDays"]; ok {
+			result.Inputs["retentionInDays"] = retention
+		}
+	case "AWS::IAM::Role":
+		result.Type = "aws:iam/role:Role"
+		result.Constructor = "aws.iam.Role"
+		result.Inputs["name"] = id
+		policy := props["AssumeRolePolicyDocument"]
+		if hasIntrinsics(policy) {
+			result.Review = "the assume role policy references other resources"
+		}
+		data, _ := json.Marshal(policy)
+		result.Inputs["assumeRolePolicy"] = string(data)
+		if path, ok := props["Path"]; ok {
+			result.Inputs["path"] = path
+		}
+	case "AWS::Lambda::Function":
+		return nil, "functions are rebuilt from source, recreate it with sst.aws.Function"
+	case "AWS::CloudFront::Distribution":
+		return nil, "recreate it with the sst component that serves the site"
+	default:
+		return nil, "no mapping for this resource type"
+	}
+	if result.Review == "" && hasIntrinsics(props) {
+		result.Review = "the template references other resources, check the inputs"
+	}
+	return result, ""
+}
+
+func list(input interface{}) []map[string]interface{} {
+	result := []map[string]interface{}{}
+	items, _ := input.([]interface{})
+	for _, item := range items {
+		if value, ok := item.(map[string]interface{}); ok {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+var nonAlphanumeric = regexp.MustCompile(`[^a-z0-9-]+`)
+
+func appName(input string) string {
+	return strings.Trim(nonAlphanumeric.ReplaceAllString(strings.ToLower(input), "-"), "-")
+}
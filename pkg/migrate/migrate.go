@@ -0,0 +1,148 @@
+package migrate
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Resource is an existing cloud resource declared in the generated config
+// with the import option, so the first deploy adopts it instead of creating
+// a new one
+type Resource struct {
+	Name        string
+	Type        string
+	Constructor string
+	ID          string
+	Inputs      map[string]interface{}
+	// set when the inputs could not be fully derived and need a manual look
+	Review string
+}
+
+type Unmapped struct {
+	Name   string
+	Type   string
+	Reason string
+}
+
+type Result struct {
+	App    string
+	Region string
+	// resources were already imported into the stage, so the config only
+	// declares them
+	Imported  bool
+	Resources []Resource
+	Unmapped  []Unmapped
+	// free form code placed in run(), used for components that are recreated
+	// rather than imported
+	Code []string
+}
+
+func (r *Result) Config() string {
+	var b strings.Builder
+	b.WriteString("/// <reference path=\"./.sst/src/global.d.ts\" />\n\n")
+	b.WriteString("export default $config({\n")
+	b.WriteString("  app(input) {\n")
+	b.WriteString("    return {\n")
+	fmt.Fprintf(&b, "      name: %q,\n", r.App)
+	if r.Region != "" {
+		b.WriteString("      providers: {\n")
+		fmt.Fprintf(&b, "        aws: { region: %q },\n", r.Region)
+		b.WriteString("      },\n")
+	}
+	b.WriteString("    };\n")
+	b.WriteString("  },\n")
+	b.WriteString("  async run() {\n")
+	if len(r.Unmapped) > 0 {
+		b.WriteString("    // not migrated:\n")
+		for _, item := range r.Unmapped {
+			fmt.Fprintf(&b, "    // - %v (%v): %v\n", item.Name, item.Type, item.Reason)
+		}
+		b.WriteString("\n")
+	}
+	for _, resource := range r.Resources {
+		if resource.Review != "" {
+			fmt.Fprintf(&b, "    // review: %v\n", resource.Review)
+		}
+		fmt.Fprintf(&b, "    new %v(\n", resource.Constructor)
+		fmt.Fprintf(&b, "      %q,\n", resource.Name)
+		fmt.Fprintf(&b, "      %v,\n", jsValue(resource.Inputs, "      "))
+		if r.Imported {
+			b.WriteString("      { retainOnDelete: true }\n")
+		} else {
+			fmt.Fprintf(&b, "      { import: %q, retainOnDelete: true }\n", resource.ID)
+		}
+		b.WriteString("    );\n")
+	}
+	for index, code := range r.Code {
+		if index > 0 || len(r.Resources) > 0 {
+			b.WriteString("\n")
+		}
+		for _, line := range strings.Split(code, "\n") {
+			b.WriteString("    " + line + "\n")
+		}
+	}
+	b.WriteString("  },\n")
+	b.WriteString("});\n")
+	return b.String()
+}
+
+// Write saves the config to sst.config.ts in dir, or next to it when one
+// already exists. It returns the path that was written.
+func (r *Result) Write(dir string) (string, error) {
+	path := filepath.Join(dir, "sst.config.ts")
+	if _, err := os.Stat(path); err == nil {
+		path = filepath.Join(dir, "sst.config.migrated.ts")
+	}
+	return path, os.WriteFile(path, []byte(r.Config()), 0644)
+}
+
+// ImportFile is the resource list in the format read by pulumi import --file
+func (r *Result) ImportFile() ([]byte, error) {
+	type importResource struct {
+		Type string `json:"type"`
+		Name string `json:"name"`
+		ID   string `json:"id"`
+	}
+	resources := []importResource{}
+	for _, resource := range r.Resources {
+		resources = append(resources, importResource{
+			Type: resource.Type,
+			Name: resource.Name,
+			ID:   resource.ID,
+		})
+	}
+	return json.MarshalIndent(map[string]interface{}{
+		"resources": resources,
+	}, "", "  ")
+}
+
+func jsValue(input interface{}, indent string) string {
+	data, err := json.MarshalIndent(input, indent, "  ")
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
+
+// hasIntrinsics is true if a CloudFormation value references other resources
+// or parameters, which can not be resolved when generating the config
+func hasIntrinsics(input interface{}) bool {
+	switch value := input.(type) {
+	case map[string]interface{}:
+		for key, item := range value {
+			if key == "Ref" || strings.HasPrefix(key, "Fn::") || hasIntrinsics(item) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, item := range value {
+			if hasIntrinsics(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
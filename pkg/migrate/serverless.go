@@ -0,0 +1,398 @@
+package migrate
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type slsConfig struct {
+	Service  string `yaml:"service"`
+	Provider struct {
+		Name        string                 `yaml:"name"`
+		Runtime     string                 `yaml:"runtime"`
+		Region      string                 `yaml:"region"`
+		MemorySize  int                    `yaml:"memorySize"`
+		Timeout     int                    `yaml:"timeout"`
+		Environment map[string]interface{} `yaml:"environment"`
+	} `yaml:"provider"`
+	Plugins   []string               `yaml:"plugins"`
+	Functions map[string]slsFunction `yaml:"functions"`
+}
+
+type slsFunction struct {
+	Handler     string                   `yaml:"handler"`
+	Runtime     string                   `yaml:"runtime"`
+	MemorySize  int                      `yaml:"memorySize"`
+	Timeout     int                      `yaml:"timeout"`
+	Description string                   `yaml:"description"`
+	Environment map[string]interface{}   `yaml:"environment"`
+	Events      []map[string]interface{} `yaml:"events"`
+}
+
+type ServerlessResult struct {
+	*Result
+	Functions int
+}
+
+// plugins that have a replacement, everything else is flagged as unsupported
+var slsPlugins = map[string]string{
+	"serverless-esbuild":                "functions are not bundled, build each handler into its bundle directory",
+	"serverless-webpack":                "functions are not bundled, build each handler into its bundle directory",
+	"serverless-plugin-typescript":      "functions are not bundled, build each handler into its bundle directory",
+	"serverless-offline":                "run the functions against the deployed stage instead",
+	"serverless-dotenv-plugin":          "environment variables from .env are read through process.env",
+	"serverless-prune-plugin":           "old versions are not kept, nothing to prune",
+	"serverless-iam-roles-per-function": "every function already gets its own role, use policies",
+}
+
+var slsEnvVariable = regexp.MustCompile(`^\$\{env:([A-Za-z_][A-Za-z0-9_]*)\}$`)
+
+func Serverless(file string) (*ServerlessResult, error) {
+	slog.Info("migrating serverless config", "path", file)
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	config := slsConfig{}
+	err = yaml.Unmarshal(data, &config)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse %v: %w", file, err)
+	}
+	if config.Provider.Name != "" && config.Provider.Name != "aws" {
+		return nil, fmt.Errorf("Only the aws provider is supported, found %v", config.Provider.Name)
+	}
+
+	result := &ServerlessResult{
+		Result: &Result{
+			App:    appName(config.Service),
+			Region: config.Provider.Region,
+		},
+	}
+	for _, plugin := range config.Plugins {
+		reason, ok := slsPlugins[plugin]
+		if !ok {
+			reason = "plugin is not supported"
+		}
+		result.Unmapped = append(result.Unmapped, Unmapped{
+			Name:   plugin,
+			Type:   "plugin",
+			Reason: reason,
+		})
+	}
+
+	names := make([]string, 0, len(config.Functions))
+	for name := range config.Functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	api := false
+	outputs := []string{}
+	for _, name := range names {
+		fn := config.Functions[name]
+		id := pascalCase(name)
+		variable := camelCase(name) + "Function"
+		result.Functions++
+
+		var b strings.Builder
+		fmt.Fprintf(&b, "const %v = new sst.Function(%q, {\n", variable, id)
+		if fn.Description != "" {
+			fmt.Fprintf(&b, "  description: %q,\n", fn.Description)
+		}
+		bundle, handler := path.Split(fn.Handler)
+		if bundle == "" {
+			bundle = "."
+		}
+		fmt.Fprintf(&b, "  bundle: %q,\n", strings.TrimSuffix(bundle, "/"))
+		fmt.Fprintf(&b, "  handler: %q,\n", handler)
+
+		runtime := firstString(fn.Runtime, config.Provider.Runtime)
+		switch runtime {
+		case "", "nodejs18.x", "nodejs20.x":
+			if runtime != "" {
+				fmt.Fprintf(&b, "  runtime: %q,\n", runtime)
+			}
+		default:
+			result.Unmapped = append(result.Unmapped, Unmapped{
+				Name:   name,
+				Type:   "runtime",
+				Reason: runtime + " is not supported, the function uses the default node runtime",
+			})
+		}
+		if timeout := firstInt(fn.Timeout, config.Provider.Timeout); timeout != 0 {
+			fmt.Fprintf(&b, "  timeout: \"%v seconds\",\n", timeout)
+		}
+		if memory := firstInt(fn.MemorySize, config.Provider.MemorySize); memory != 0 {
+			fmt.Fprintf(&b, "  memory: \"%v MB\",\n", memory)
+		}
+
+		environment := map[string]interface{}{}
+		for key, value := range config.Provider.Environment {
+			environment[key] = value
+		}
+		for key, value := range fn.Environment {
+			environment[key] = value
+		}
+		if len(environment) > 0 {
+			b.WriteString("  environment: {\n")
+			for _, key := range sortedKeys(environment) {
+				value, ok := environment[key].(string)
+				if !ok {
+					if _, nested := environment[key].(map[string]interface{}); nested {
+						fmt.Fprintf(&b, "    // review: %v references another resource\n", key)
+						continue
+					}
+					value = fmt.Sprint(environment[key])
+				}
+				if match := slsEnvVariable.FindStringSubmatch(value); match != nil {
+					fmt.Fprintf(&b, "    %v: process.env.%v!,\n", key, match[1])
+					continue
+				}
+				if strings.Contains(value, "${") {
+					fmt.Fprintf(&b, "    // review: serverless variable in %v\n", key)
+				}
+				fmt.Fprintf(&b, "    %v: %q,\n", key, value)
+			}
+			b.WriteString("  },\n")
+		}
+
+		queues := []string{}
+		for _, event := range fn.Events {
+			if arn := slsQueueArn(event["sqs"]); arn != "" {
+				queues = append(queues, arn)
+			}
+		}
+		if len(queues) > 0 {
+			b.WriteString("  policies: [\n")
+			b.WriteString("    {\n")
+			b.WriteString("      name: \"sqs\",\n")
+			b.WriteString("      policy: JSON.stringify({\n")
+			b.WriteString("        Version: \"2012-10-17\",\n")
+			b.WriteString("        Statement: [\n")
+			b.WriteString("          {\n")
+			b.WriteString("            Effect: \"Allow\",\n")
+			b.WriteString("            Action: [\"sqs:ReceiveMessage\", \"sqs:DeleteMessage\", \"sqs:GetQueueAttributes\"],\n")
+			resources, _ := json.Marshal(queues)
+			fmt.Fprintf(&b, "            Resource: %v,\n", string(resources))
+			b.WriteString("          },\n")
+			b.WriteString("        ],\n")
+			b.WriteString("      }),\n")
+			b.WriteString("    },\n")
+			b.WriteString("  ],\n")
+		}
+		b.WriteString("});")
+
+		integration := false
+		for index, event := range fn.Events {
+			suffix := fmt.Sprintf("%v%v", id, index)
+			switch {
+			case event["http"] != nil || event["httpApi"] != nil:
+				route := slsRoute(event["http"])
+				if route == "" {
+					route = slsRoute(event["httpApi"])
+				}
+				if route == "" {
+					result.Unmapped = append(result.Unmapped, Unmapped{Name: name, Type: "http", Reason: "could not read the method and path"})
+					continue
+				}
+				api = true
+				if !integration {
+					integration = true
+					fmt.Fprintf(&b, "\nconst %vIntegration = new aws.apigatewayv2.Integration(%q, {\n", variable, id+"Integration")
+					b.WriteString("  apiId: api.id,\n")
+					b.WriteString("  integrationType: \"AWS_PROXY\",\n")
+					fmt.Fprintf(&b, "  integrationUri: %v.nodes.function.arn,\n", variable)
+					b.WriteString("  payloadFormatVersion: \"2.0\",\n")
+					b.WriteString("});\n")
+					fmt.Fprintf(&b, "new aws.lambda.Permission(%q, {\n", id+"ApiPermission")
+					b.WriteString("  action: \"lambda:InvokeFunction\",\n")
+					fmt.Fprintf(&b, "  function: %v.nodes.function.name,\n", variable)
+					b.WriteString("  principal: \"apigateway.amazonaws.com\",\n")
+					b.WriteString("  sourceArn: util.interpolate`${api.executionArn}/*/*`,\n")
+					b.WriteString("});")
+				}
+				fmt.Fprintf(&b, "\nnew aws.apigatewayv2.Route(%q, {\n", suffix+"Route")
+				b.WriteString("  apiId: api.id,\n")
+				fmt.Fprintf(&b, "  routeKey: %q,\n", route)
+				fmt.Fprintf(&b, "  target: util.interpolate`integrations/${%vIntegration.id}`,\n", variable)
+				b.WriteString("});")
+			case event["schedule"] != nil:
+				expression := slsSchedule(event["schedule"])
+				if expression == "" {
+					result.Unmapped = append(result.Unmapped, Unmapped{Name: name, Type: "schedule", Reason: "could not read the schedule expression"})
+					continue
+				}
+				fmt.Fprintf(&b, "\nconst %vSchedule%v = new aws.cloudwatch.EventRule(%q, {\n", variable, index, suffix+"Schedule")
+				fmt.Fprintf(&b, "  scheduleExpression: %q,\n", expression)
+				b.WriteString("});\n")
+				fmt.Fprintf(&b, "new aws.cloudwatch.EventTarget(%q, {\n", suffix+"ScheduleTarget")
+				fmt.Fprintf(&b, "  rule: %vSchedule%v.name,\n", variable, index)
+				fmt.Fprintf(&b, "  arn: %v.nodes.function.arn,\n", variable)
+				b.WriteString("});\n")
+				fmt.Fprintf(&b, "new aws.lambda.Permission(%q, {\n", suffix+"SchedulePermission")
+				b.WriteString("  action: \"lambda:InvokeFunction\",\n")
+				fmt.Fprintf(&b, "  function: %v.nodes.function.name,\n", variable)
+				b.WriteString("  principal: \"events.amazonaws.com\",\n")
+				fmt.Fprintf(&b, "  sourceArn: %vSchedule%v.arn,\n", variable, index)
+				b.WriteString("});")
+			case event["sqs"] != nil:
+				arn := slsQueueArn(event["sqs"])
+				if arn == "" {
+					result.Unmapped = append(result.Unmapped, Unmapped{Name: name, Type: "sqs", Reason: "queue arn references another resource, wire it up by hand"})
+					continue
+				}
+				fmt.Fprintf(&b, "\nnew aws.lambda.EventSourceMapping(%q, {\n", suffix+"Queue")
+				fmt.Fprintf(&b, "  eventSourceArn: %q,\n", arn)
+				fmt.Fprintf(&b, "  functionName: %v.nodes.function.name,\n", variable)
+				if settings, ok := event["sqs"].(map[string]interface{}); ok && settings["batchSize"] != nil {
+					fmt.Fprintf(&b, "  batchSize: %v,\n", settings["batchSize"])
+				}
+				b.WriteString("});")
+			default:
+				for kind := range event {
+					result.Unmapped = append(result.Unmapped, Unmapped{Name: name, Type: kind, Reason: "event type is not supported"})
+				}
+			}
+		}
+		result.Code = append(result.Code, b.String())
+	}
+
+	if api {
+		var b strings.Builder
+		b.WriteString("const api = new aws.apigatewayv2.Api(\"Api\", {\n")
+		b.WriteString("  protocolType: \"HTTP\",\n")
+		b.WriteString("});\n")
+		b.WriteString("new aws.apigatewayv2.Stage(\"ApiStage\", {\n")
+		b.WriteString("  apiId: api.id,\n")
+		b.WriteString("  name: \"$default\",\n")
+		b.WriteString("  autoDeploy: true,\n")
+		b.WriteString("});")
+		result.Code = append([]string{b.String()}, result.Code...)
+		outputs = append(outputs, "api: api.apiEndpoint,")
+	}
+	if len(outputs) > 0 {
+		result.Code = append(result.Code, "return {\n  "+strings.Join(outputs, "\n  ")+"\n};")
+	}
+	return result, nil
+}
+
+func slsRoute(input interface{}) string {
+	switch value := input.(type) {
+	case string:
+		if value == "*" {
+			return "$default"
+		}
+		parts := strings.Fields(value)
+		if len(parts) == 2 {
+			return slsRouteKey(parts[0], parts[1])
+		}
+	case map[string]interface{}:
+		method, _ := value["method"].(string)
+		route, _ := value["path"].(string)
+		if method != "" && route != "" {
+			return slsRouteKey(method, route)
+		}
+	}
+	return ""
+}
+
+func slsRouteKey(method, route string) string {
+	method = strings.ToUpper(method)
+	if method == "*" {
+		method = "ANY"
+	}
+	if !strings.HasPrefix(route, "/") {
+		route = "/" + route
+	}
+	return method + " " + route
+}
+
+func slsSchedule(input interface{}) string {
+	switch value := input.(type) {
+	case string:
+		return value
+	case map[string]interface{}:
+		switch rate := value["rate"].(type) {
+		case string:
+			return rate
+		case []interface{}:
+			if len(rate) > 0 {
+				expression, _ := rate[0].(string)
+				return expression
+			}
+		}
+	}
+	return ""
+}
+
+// slsQueueArn is empty when the arn is not a literal, like Fn::GetAtt to a
+// queue defined under resources
+func slsQueueArn(input interface{}) string {
+	switch value := input.(type) {
+	case string:
+		if strings.HasPrefix(value, "arn:") {
+			return value
+		}
+	case map[string]interface{}:
+		if arn, ok := value["arn"].(string); ok && strings.HasPrefix(arn, "arn:") {
+			return arn
+		}
+	}
+	return ""
+}
+
+var nonWord = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+func pascalCase(input string) string {
+	var b strings.Builder
+	for _, part := range nonWord.Split(input, -1) {
+		if part == "" {
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+func camelCase(input string) string {
+	result := pascalCase(input)
+	if result == "" {
+		return result
+	}
+	return strings.ToLower(result[:1]) + result[1:]
+}
+
+func firstString(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func firstInt(values ...int) int {
+	for _, value := range values {
+		if value != 0 {
+			return value
+		}
+	}
+	return 0
+}
+
+func sortedKeys[T any](input map[string]T) []string {
+	result := make([]string, 0, len(input))
+	for key := range input {
+		result = append(result, key)
+	}
+	sort.Strings(result)
+	return result
+}
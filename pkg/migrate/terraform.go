@@ -0,0 +1,204 @@
+package migrate
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+type tfState struct {
+	Version   int `json:"version"`
+	Resources []struct {
+		Module    string `json:"module"`
+		Mode      string `json:"mode"`
+		Type      string `json:"type"`
+		Name      string `json:"name"`
+		Provider  string `json:"provider"`
+		Instances []struct {
+			IndexKey   interface{}            `json:"index_key"`
+			Attributes map[string]interface{} `json:"attributes"`
+		} `json:"instances"`
+	} `json:"resources"`
+}
+
+type tfMapping struct {
+	Type        string
+	Constructor string
+	Inputs      map[string]string
+}
+
+// terraform and pulumi resources share import ids, the inputs map pulumi
+// input names to terraform attribute names
+var tfMappings = map[string]tfMapping{
+	"aws_s3_bucket": {
+		Type:        "aws:s3/bucketV2:BucketV2",
+		Constructor: "aws.s3.BucketV2",
+		Inputs:      map[string]string{"bucket": "bucket"},
+	},
+	"aws_dynamodb_table": {
+		Type:        "aws:dynamodb/table:Table",
+		Constructor: "aws.dynamodb.Table",
+		Inputs: map[string]string{
+			"name":          "name",
+			"hashKey":       "hash_key",
+			"rangeKey":      "range_key",
+			"billingMode":   "billing_mode",
+			"readCapacity":  "read_capacity",
+			"writeCapacity": "write_capacity",
+		},
+	},
+	"aws_sqs_queue": {
+		Type:        "aws:sqs/queue:Queue",
+		Constructor: "aws.sqs.Queue",
+		Inputs:      map[string]string{"name": "name", "fifoQueue": "fifo_queue"},
+	},
+	"aws_sns_topic": {
+		Type:        "aws:sns/topic:Topic",
+		Constructor: "aws.sns.Topic",
+		Inputs:      map[string]string{"name": "name"},
+	},
+	"aws_iam_role": {
+		Type:        "aws:iam/role:Role",
+		Constructor: "aws.iam.Role",
+		Inputs: map[string]string{
+			"name":             "name",
+			"path":             "path",
+			"assumeRolePolicy": "assume_role_policy",
+		},
+	},
+	"aws_cloudwatch_log_group": {
+		Type:        "aws:cloudwatch/logGroup:LogGroup",
+		Constructor: "aws.cloudwatch.LogGroup",
+		Inputs:      map[string]string{"name": "name", "retentionInDays": "retention_in_days"},
+	},
+	"aws_vpc": {
+		Type:        "aws:ec2/vpc:Vpc",
+		Constructor: "aws.ec2.Vpc",
+		Inputs:      map[string]string{"cidrBlock": "cidr_block"},
+	},
+	"aws_subnet": {
+		Type:        "aws:ec2/subnet:Subnet",
+		Constructor: "aws.ec2.Subnet",
+		Inputs: map[string]string{
+			"vpcId":            "vpc_id",
+			"cidrBlock":        "cidr_block",
+			"availabilityZone": "availability_zone",
+		},
+	},
+}
+
+func Terraform(path string) (*Result, error) {
+	slog.Info("migrating terraform state", "path", path)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	state := tfState{}
+	err = json.Unmarshal(data, &state)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse terraform state: %w", err)
+	}
+	if state.Version != 4 {
+		return nil, fmt.Errorf("Unsupported terraform state version %v, run terraform init with a recent version first", state.Version)
+	}
+
+	result := &Result{
+		Imported: true,
+	}
+	for _, resource := range state.Resources {
+		if resource.Mode != "managed" {
+			continue
+		}
+		for _, instance := range resource.Instances {
+			name := tfName(resource.Module, resource.Name, instance.IndexKey)
+			address := tfAddress(resource.Module, resource.Type, resource.Name, instance.IndexKey)
+			if !strings.Contains(resource.Provider, "hashicorp/aws") {
+				result.Unmapped = append(result.Unmapped, Unmapped{
+					Name:   address,
+					Type:   resource.Type,
+					Reason: "only aws resources can be imported",
+				})
+				continue
+			}
+			mapping, ok := tfMappings[resource.Type]
+			if !ok {
+				result.Unmapped = append(result.Unmapped, Unmapped{
+					Name:   address,
+					Type:   resource.Type,
+					Reason: "no mapping for this resource type",
+				})
+				continue
+			}
+			id, _ := instance.Attributes["id"].(string)
+			if id == "" {
+				result.Unmapped = append(result.Unmapped, Unmapped{
+					Name:   address,
+					Type:   resource.Type,
+					Reason: "resource has no id in the state",
+				})
+				continue
+			}
+			mapped := Resource{
+				Name:        name,
+				Type:        mapping.Type,
+				Constructor: mapping.Constructor,
+				ID:          id,
+				Inputs:      map[string]interface{}{},
+			}
+			for input, attribute := range mapping.Inputs {
+				value, ok := instance.Attributes[attribute]
+				if !ok || value == nil || value == "" {
+					continue
+				}
+				mapped.Inputs[input] = value
+			}
+			if resource.Type == "aws_dynamodb_table" {
+				attributes := []interface{}{}
+				for _, item := range list(instance.Attributes["attribute"]) {
+					attributes = append(attributes, map[string]interface{}{
+						"name": item["name"],
+						"type": item["type"],
+					})
+				}
+				mapped.Inputs["attributes"] = attributes
+				if len(list(instance.Attributes["global_secondary_index"])) > 0 {
+					mapped.Review = "copy the secondary indexes from the terraform config"
+				}
+			}
+			result.Resources = append(result.Resources, mapped)
+		}
+	}
+	return result, nil
+}
+
+func tfName(module, name string, index interface{}) string {
+	parts := []string{}
+	if module != "" {
+		for _, item := range strings.Split(module, ".") {
+			if item != "module" {
+				parts = append(parts, item)
+			}
+		}
+	}
+	parts = append(parts, name)
+	if index != nil {
+		parts = append(parts, fmt.Sprint(index))
+	}
+	return appName(strings.Join(parts, "-"))
+}
+
+func tfAddress(module, kind, name string, index interface{}) string {
+	address := kind + "." + name
+	if module != "" {
+		address = module + "." + address
+	}
+	switch value := index.(type) {
+	case string:
+		address += fmt.Sprintf("[%q]", value)
+	case float64:
+		address += fmt.Sprintf("[%v]", value)
+	}
+	return address
+}
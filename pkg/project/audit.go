@@ -0,0 +1,52 @@
+package project
+
+import (
+	"encoding/json"
+	"os"
+	"os/user"
+	"path/filepath"
+	"time"
+
+	"github.com/sst/ion/pkg/console"
+)
+
+type AuditEntry struct {
+	Time    time.Time `json:"time"`
+	Event   string    `json:"event"`
+	Stage   string    `json:"stage"`
+	User    string    `json:"user"`
+	Message string    `json:"message,omitempty"`
+}
+
+func (p *Project) PathAudit() string {
+	return filepath.Join(p.PathTemp(), "audit.ndjson")
+}
+
+// Audit appends an entry to the project's audit log
+func (p *Project) Audit(event string, message string) error {
+	entry := AuditEntry{
+		Time:    time.Now().UTC(),
+		Event:   event,
+		Stage:   p.app.Stage,
+		Message: message,
+	}
+	if name := p.gitUser(); name != "" {
+		entry.User = name
+	} else if current, err := user.Current(); err == nil {
+		entry.User = current.Username
+	}
+
+	console.Track("audit", p.app.Name, p.app.Stage, entry)
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(p.PathAudit(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(append(data, '\n'))
+	return err
+}
@@ -0,0 +1,39 @@
+package project
+
+import (
+	"fmt"
+
+	"github.com/sst/ion/pkg/project/provider"
+)
+
+func (p *Project) aws() (*provider.AwsProvider, error) {
+	aws, ok := p.backend.(*provider.AwsProvider)
+	if !ok {
+		return nil, fmt.Errorf("Bootstrap is only supported for aws")
+	}
+	return aws, nil
+}
+
+func (p *Project) BootstrapStatus() (*provider.BootstrapStatus, error) {
+	aws, err := p.aws()
+	if err != nil {
+		return nil, err
+	}
+	return aws.BootstrapStatus()
+}
+
+func (p *Project) UpgradeBootstrap() (*provider.BootstrapStatus, error) {
+	aws, err := p.aws()
+	if err != nil {
+		return nil, err
+	}
+	return aws.UpgradeBootstrap()
+}
+
+func (p *Project) RemoveBootstrap() error {
+	aws, err := p.aws()
+	if err != nil {
+		return err
+	}
+	return aws.RemoveBootstrap()
+}
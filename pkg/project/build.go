@@ -0,0 +1,27 @@
+package project
+
+import (
+	"encoding/json"
+	"path/filepath"
+)
+
+// BuildEvent is a line of output from a site build, builds run inside the
+// program so their output is streamed through a file like phases
+type BuildEvent struct {
+	Name string `json:"name"`
+	Line string `json:"line"`
+}
+
+func (p *Project) pathBuild() string {
+	return filepath.Join(p.PathRun(), "build.ndjson")
+}
+
+func (s *stack) tailBuild(emit func(StackEvent), done <-chan struct{}) {
+	tailFile(s.project.pathBuild(), done, func(line []byte) {
+		var evt BuildEvent
+		if json.Unmarshal(line, &evt) != nil {
+			return
+		}
+		emit(StackEvent{BuildEvent: &evt})
+	})
+}
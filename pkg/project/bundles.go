@@ -0,0 +1,90 @@
+package project
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type BundleModule struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+}
+
+// BundleEvent is reported by every function after its code is zipped
+type BundleEvent struct {
+	Name     string `json:"name"`
+	Size     int64  `json:"size"`
+	Unzipped int64  `json:"unzipped"`
+	// largest node_modules packages, bundled or installed, by bytes in the
+	// output
+	Modules []BundleModule `json:"modules"`
+}
+
+// ColdStart is a rough estimate of the init time the bundle adds, lambda has
+// to download and unzip the code and node has to parse it
+func (b *BundleEvent) ColdStart() time.Duration {
+	const mb = 1024 * 1024
+	download := time.Duration(b.Size) * 25 * time.Millisecond / mb
+	parse := time.Duration(b.Unzipped) * 10 * time.Millisecond / mb
+	return download + parse
+}
+
+func (p *Project) pathBundles() string {
+	return filepath.Join(p.PathRun(), "bundles.ndjson")
+}
+
+func (s *stack) tailBundles(emit func(StackEvent), done <-chan struct{}) {
+	tailFile(s.project.pathBundles(), done, func(line []byte) {
+		var evt BundleEvent
+		if json.Unmarshal(line, &evt) != nil {
+			return
+		}
+		emit(StackEvent{BundleEvent: &evt})
+	})
+}
+
+// SetBundleLimit makes functions fail to build when their zip is larger than
+// limit bytes, 0 disables the check
+func (p *Project) SetBundleLimit(limit int64) {
+	p.bundleLimit = limit
+}
+
+var sizeRegex = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*(B|KB|MB|GB)?$`)
+
+// ParseSize reads sizes like 50MB, units are powers of 1024
+func ParseSize(input string) (int64, error) {
+	match := sizeRegex.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(input)))
+	if match == nil {
+		return 0, fmt.Errorf("Invalid size %q, use a number with B, KB, MB or GB", input)
+	}
+	value, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return 0, err
+	}
+	switch match[2] {
+	case "KB":
+		value *= 1024
+	case "MB":
+		value *= 1024 * 1024
+	case "GB":
+		value *= 1024 * 1024 * 1024
+	}
+	return int64(value), nil
+}
+
+func FormatSize(size int64) string {
+	switch {
+	case size >= 1024*1024*1024:
+		return fmt.Sprintf("%.1f GB", float64(size)/(1024*1024*1024))
+	case size >= 1024*1024:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1024*1024))
+	case size >= 1024:
+		return fmt.Sprintf("%.1f KB", float64(size)/1024)
+	}
+	return fmt.Sprintf("%v B", size)
+}
@@ -0,0 +1,120 @@
+package project
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// how long Stop waits for in-flight operations of the other process
+const stopTimeout = 15 * time.Minute
+
+func (s *stack) pathPid() string {
+	return filepath.Join(s.project.PathState(), "update", s.project.app.Stage+".pid")
+}
+
+// updatePid is written while an update runs so another sst process can stop it
+type updatePid struct {
+	Pid     int    `json:"pid"`
+	Started string `json:"started"`
+}
+
+func (s *stack) writePid() error {
+	err := os.MkdirAll(filepath.Dir(s.pathPid()), 0755)
+	if err != nil {
+		return err
+	}
+	started, _ := processStarted(os.Getpid())
+	data, err := json.Marshal(updatePid{Pid: os.Getpid(), Started: started})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.pathPid(), data, 0644)
+}
+
+func (s *stack) removePid() {
+	os.Remove(s.pathPid())
+}
+
+// Interrupt asks the update running in this process to stop scheduling new
+// resources, in-flight operations finish and the checkpoint is written
+func (s *stack) Interrupt() error {
+	return s.project.process.Interrupt()
+}
+
+// Kill terminates the running update immediately, the lock and any pending
+// operations are left behind
+func (s *stack) Kill() {
+	s.project.process.Kill()
+	s.removePid()
+}
+
+// Stop gracefully cancels an update of this stage running in another sst
+// process, waits for it to finish and then releases the lock. It returns false
+// if no update was running.
+func (s *stack) Stop() (bool, error) {
+	data, err := os.ReadFile(s.pathPid())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	var owner updatePid
+	err = json.Unmarshal(data, &owner)
+	if err != nil {
+		slog.Info("unreadable pid file, removing it", "err", err)
+		s.removePid()
+		return false, nil
+	}
+	// the pid may have been reused since the file was written
+	started, ok := processStarted(owner.Pid)
+	if !ok || owner.Started == "" || started != owner.Started {
+		slog.Info("update is not running, removing stale pid file", "pid", owner.Pid)
+		s.removePid()
+		return false, nil
+	}
+
+	slog.Info("stopping update", "pid", owner.Pid)
+	process, err := os.FindProcess(owner.Pid)
+	if err != nil {
+		s.removePid()
+		return false, nil
+	}
+	err = process.Signal(os.Interrupt)
+	if err != nil {
+		slog.Info("update is not running, removing stale pid file", "err", err)
+		s.removePid()
+		return false, nil
+	}
+
+	// the file is removed when the update finishes, a daemon keeps running
+	// afterwards so the process exiting is not enough to wait for
+	deadline := time.Now().Add(stopTimeout)
+	for {
+		_, err := os.Stat(s.pathPid())
+		if os.IsNotExist(err) {
+			break
+		}
+		if process.Signal(syscall.Signal(0)) != nil {
+			s.removePid()
+			break
+		}
+		if time.Now().After(deadline) {
+			return false, fmt.Errorf("Update did not stop within %v, run `sst cancel --force` to release the lock anyway", stopTimeout)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+
+	return true, s.project.backend.Cancel(s.project.app.Name, s.project.app.Stage)
+}
+
+// Unlock releases the lock of the stage after an update in this process was
+// stopped before it could release it
+func (s *stack) Unlock() error {
+	return s.project.backend.Cancel(s.project.app.Name, s.project.app.Stage)
+}
@@ -0,0 +1,103 @@
+package project
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+func (s *stack) pathCheckpoint() string {
+	return filepath.Join(s.project.PathState(), ".pulumi", "stacks", s.project.app.Name, s.project.app.Stage+".json")
+}
+
+// Checkpoint returns nil if the stage has never been deployed
+func (s *stack) Checkpoint() (*apitype.CheckpointV3, error) {
+	checkpoint, err := readCheckpoint(s.pathCheckpoint())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return checkpoint, nil
+}
+
+// Outputs returns the stack outputs of the last update, internal outputs
+// prefixed with _ are left out
+func (s *stack) Outputs() (map[string]interface{}, error) {
+	checkpoint, err := s.Checkpoint()
+	if err != nil {
+		return nil, err
+	}
+	return checkpointOutputs(checkpoint), nil
+}
+
+func readCheckpoint(path string) (*apitype.CheckpointV3, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var versioned apitype.VersionedCheckpoint
+	err = json.Unmarshal(data, &versioned)
+	if err != nil {
+		return nil, err
+	}
+
+	var checkpoint apitype.CheckpointV3
+	err = json.Unmarshal(versioned.Checkpoint, &checkpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &checkpoint, nil
+}
+
+func checkpointOutputs(checkpoint *apitype.CheckpointV3) map[string]interface{} {
+	result := map[string]interface{}{}
+	if checkpoint == nil || checkpoint.Latest == nil {
+		return result
+	}
+	for _, resource := range checkpoint.Latest.Resources {
+		if resource.Type != "pulumi:pulumi:Stack" {
+			continue
+		}
+		for k, v := range resource.Outputs {
+			if strings.HasPrefix(k, "_") {
+				continue
+			}
+			result[k] = v
+		}
+	}
+	return result
+}
+
+// Secrets returns the names of stack outputs that are stored encrypted
+func (s *stack) Secrets() ([]string, error) {
+	outputs, err := s.Outputs()
+	if err != nil {
+		return nil, err
+	}
+	result := []string{}
+	for _, key := range sortedKeys(outputs) {
+		value, ok := outputs[key].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if _, ok := value[secretSignature]; ok {
+			result = append(result, key)
+		}
+	}
+	return result, nil
+}
+
+// RedactedOutputs is Outputs with secret values replaced by [secret]
+func (s *stack) RedactedOutputs() (map[string]interface{}, error) {
+	outputs, err := s.Outputs()
+	if err != nil {
+		return nil, err
+	}
+	return redactSecrets(outputs), nil
+}
@@ -0,0 +1,129 @@
+package project
+
+import (
+	"log/slog"
+	"sort"
+	"strings"
+)
+
+type DomainRecord struct {
+	Name  string
+	Type  string
+	Value string
+	// Status is the ACM validation status for validation records
+	Status string
+	// Managed is true if the record is created by the app in Route 53 or
+	// Cloudflare, otherwise it has to be added to the external DNS by hand
+	Managed bool
+}
+
+type Domain struct {
+	Name        string
+	Aliases     []string
+	Certificate string
+	Status      string
+	Failure     string
+	Validation  []DomainRecord
+	Routing     []DomainRecord
+}
+
+// Domains lists the certificates of the stage with their live validation
+// status and the DNS records every domain needs
+func (p *Project) Domains() ([]Domain, error) {
+	checkpoint, err := p.Stack.Checkpoint()
+	if err != nil {
+		return nil, err
+	}
+	if checkpoint == nil || checkpoint.Latest == nil {
+		return []Domain{}, nil
+	}
+
+	managed := map[string]bool{}
+	targets := map[string]string{}
+	certificates := []map[string]interface{}{}
+	for _, resource := range checkpoint.Latest.Resources {
+		outputs := resource.Outputs
+		switch {
+		case resource.Type == "aws:acm/certificate:Certificate":
+			certificates = append(certificates, outputs)
+		case resource.Type == "aws:route53/record:Record":
+			managed[domainKey(outputs["name"])] = true
+			managed[domainKey(outputs["fqdn"])] = true
+		case strings.HasSuffix(string(resource.URN), "sst.CloudflareRecord"):
+			managed[domainKey(outputs["name"])] = true
+		case resource.Type == "aws:cloudfront/distribution:Distribution":
+			aliases, _ := outputs["aliases"].([]interface{})
+			for _, alias := range aliases {
+				targets[domainKey(alias)], _ = outputs["domainName"].(string)
+			}
+		}
+	}
+
+	aws, err := p.aws()
+	if err != nil {
+		return nil, err
+	}
+	result := []Domain{}
+	for _, outputs := range certificates {
+		domain := Domain{}
+		domain.Name, _ = outputs["domainName"].(string)
+		domain.Certificate, _ = outputs["arn"].(string)
+		domain.Status, _ = outputs["status"].(string)
+		names, _ := outputs["subjectAlternativeNames"].([]interface{})
+		for _, name := range names {
+			if value, ok := name.(string); ok && value != domain.Name {
+				domain.Aliases = append(domain.Aliases, value)
+			}
+		}
+
+		live, err := aws.DescribeCertificate(domain.Certificate)
+		if err != nil {
+			// a certificate removed outside of sst still shows the state
+			slog.Warn("failed to describe certificate", "arn", domain.Certificate, "err", err)
+		}
+		if live != nil {
+			domain.Status = live.Status
+			domain.Failure = live.FailureReason
+			// a domain and its wildcard share the same validation record
+			seen := map[string]bool{}
+			for _, option := range live.DomainValidationOptions {
+				record := option.ResourceRecord
+				if record.Name == "" || seen[record.Name] {
+					continue
+				}
+				seen[record.Name] = true
+				domain.Validation = append(domain.Validation, DomainRecord{
+					Name:    record.Name,
+					Type:    record.Type,
+					Value:   record.Value,
+					Status:  option.ValidationStatus,
+					Managed: managed[domainKey(record.Name)],
+				})
+			}
+		}
+
+		for _, name := range append([]string{domain.Name}, domain.Aliases...) {
+			target, ok := targets[domainKey(name)]
+			if !ok {
+				continue
+			}
+			domain.Routing = append(domain.Routing, DomainRecord{
+				Name:    name,
+				Type:    "CNAME",
+				Value:   target,
+				Managed: managed[domainKey(name)],
+			})
+		}
+		result = append(result, domain)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+func domainKey(input interface{}) string {
+	value, _ := input.(string)
+	return strings.TrimSuffix(strings.ToLower(value), ".")
+}
@@ -0,0 +1,129 @@
+package project
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+type CheckpointSnapshot []byte
+
+// Snapshot captures the current checkpoint so individual resources can be
+// put back with Restore after a refresh
+func (s *stack) Snapshot() (CheckpointSnapshot, error) {
+	data, err := os.ReadFile(s.pathCheckpoint())
+	if err != nil {
+		// a stage that was never deployed has nothing to restore
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+// Restore replaces the given resources in the current checkpoint with their
+// state from the snapshot
+func (s *stack) Restore(snapshot CheckpointSnapshot, urns []string) error {
+	if len(urns) == 0 || snapshot == nil {
+		return nil
+	}
+
+	previous := map[string]interface{}{}
+	err := json.Unmarshal(snapshot, &previous)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(s.pathCheckpoint())
+	if err != nil {
+		return err
+	}
+	current := map[string]interface{}{}
+	err = json.Unmarshal(data, &current)
+	if err != nil {
+		return err
+	}
+
+	old := map[string]interface{}{}
+	for _, resource := range checkpointResources(previous) {
+		fields, _ := resource.(map[string]interface{})
+		urn, _ := fields["urn"].(string)
+		old[urn] = resource
+	}
+	resources := checkpointResources(current)
+	for i, resource := range resources {
+		fields, _ := resource.(map[string]interface{})
+		urn, _ := fields["urn"].(string)
+		if !slices.Contains(urns, urn) {
+			continue
+		}
+		if prev, ok := old[urn]; ok {
+			resources[i] = prev
+		}
+	}
+
+	data, err = json.MarshalIndent(current, "", "    ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.pathCheckpoint(), data, 0644)
+}
+
+func checkpointResources(versioned map[string]interface{}) []interface{} {
+	checkpoint, _ := versioned["checkpoint"].(map[string]interface{})
+	latest, _ := checkpoint["latest"].(map[string]interface{})
+	resources, _ := latest["resources"].([]interface{})
+	return resources
+}
+
+func (s *stack) pathDrift() string {
+	return filepath.Join(s.project.PathState(), "drift", s.project.app.Stage+".json")
+}
+
+// Drift returns resources marked for correction, they are refreshed right
+// before the next deploy so it reverts them to match the config
+func (s *stack) Drift() ([]string, error) {
+	data, err := os.ReadFile(s.pathDrift())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+	result := []string{}
+	err = json.Unmarshal(data, &result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *stack) MarkDrift(urns []string) error {
+	existing, err := s.Drift()
+	if err != nil {
+		return err
+	}
+	for _, urn := range urns {
+		if !slices.Contains(existing, urn) {
+			existing = append(existing, urn)
+		}
+	}
+	data, err := json.Marshal(existing)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(s.pathDrift()), 0755)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.pathDrift(), data, 0644)
+}
+
+func (s *stack) ClearDrift() error {
+	err := os.Remove(s.pathDrift())
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
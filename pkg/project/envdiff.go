@@ -0,0 +1,74 @@
+package project
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+// EnvChange is a changed environment variable of a function, values are never
+// included since they can be secrets
+type EnvChange struct {
+	Key string
+	Op  OutputDiffOp
+}
+
+// environment.variables.KEY or environment.variables["KEY-WITH-DASHES"]
+var envPathRegex = regexp.MustCompile(`^environment\.variables(?:\.([^.\[]+)|\["([^"]+)"\])`)
+
+// EnvDiff lists the environment variables a step changes. The engine masks
+// secrets in the inputs so the detailed diff is preferred, it still reports
+// secret values that changed.
+func EnvDiff(step apitype.StepEventMetadata) []EnvChange {
+	if step.Type != "aws:lambda/function:Function" {
+		return nil
+	}
+	switch step.Op {
+	case apitype.OpUpdate, apitype.OpReplace, apitype.OpCreateReplacement:
+	default:
+		return nil
+	}
+
+	changes := map[string]OutputDiffOp{}
+	whole := len(step.DetailedDiff) == 0
+	for path, diff := range step.DetailedDiff {
+		match := envPathRegex.FindStringSubmatch(path)
+		if match == nil {
+			if path == "environment" || path == "environment.variables" {
+				whole = true
+			}
+			continue
+		}
+		key := match[1] + match[2]
+		switch {
+		case strings.HasPrefix(string(diff.Kind), "add"):
+			changes[key] = OutputAdded
+		case strings.HasPrefix(string(diff.Kind), "delete"):
+			changes[key] = OutputRemoved
+		default:
+			changes[key] = OutputChanged
+		}
+	}
+	if whole && step.Old != nil && step.New != nil {
+		for _, diff := range DiffOutputs(envVariables(step.Old.Inputs), envVariables(step.New.Inputs)) {
+			changes[diff.Key] = diff.Op
+		}
+	}
+
+	result := []EnvChange{}
+	for _, key := range sortedKeys(changes) {
+		result = append(result, EnvChange{Key: key, Op: changes[key]})
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Op < result[j].Op
+	})
+	return result
+}
+
+func envVariables(inputs map[string]interface{}) map[string]interface{} {
+	environment, _ := inputs["environment"].(map[string]interface{})
+	variables, _ := environment["variables"].(map[string]interface{})
+	return variables
+}
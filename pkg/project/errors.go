@@ -0,0 +1,29 @@
+package project
+
+// ConfigError is returned when sst.config.ts can not be found, evaluated or
+// is invalid
+type ConfigError struct {
+	err error
+}
+
+func (e *ConfigError) Error() string {
+	return e.err.Error()
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.err
+}
+
+// CredentialsError is returned when the credentials for the stage can not be
+// loaded
+type CredentialsError struct {
+	err error
+}
+
+func (e *CredentialsError) Error() string {
+	return e.err.Error()
+}
+
+func (e *CredentialsError) Unwrap() error {
+	return e.err
+}
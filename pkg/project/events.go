@@ -0,0 +1,69 @@
+package project
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+type recorder struct {
+	file    *os.File
+	encoder *json.Encoder
+}
+
+func (r *recorder) Write(evt StackEvent) {
+	r.encoder.Encode(evt)
+}
+
+func (r *recorder) Close() error {
+	return r.file.Close()
+}
+
+func (p *Project) PathEvents(cmd string) string {
+	return filepath.Join(p.PathRun(), "events", cmd+".ndjson")
+}
+
+// record persists the raw event stream of an operation so it can be fed
+// back through the progress renderer with sst replay
+func (s *stack) record(cmd string) (*recorder, error) {
+	path := s.project.PathEvents(cmd)
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &recorder{
+		file:    file,
+		encoder: json.NewEncoder(file),
+	}, nil
+}
+
+func Replay(path string) (StackEventStream, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(file)
+	const maxCapacity = 1024 * 1024 // 1 MB
+	scanner.Buffer(make([]byte, maxCapacity), maxCapacity)
+
+	out := make(StackEventStream)
+	go func() {
+		defer file.Close()
+		defer close(out)
+		for scanner.Scan() {
+			var evt StackEvent
+			err := json.Unmarshal(scanner.Bytes(), &evt)
+			if err != nil {
+				continue
+			}
+			out <- evt
+		}
+	}()
+	return out, nil
+}
@@ -0,0 +1,119 @@
+package project
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type FanoutConfig struct {
+	Targets      []FanoutTarget      `json:"targets,omitempty"`
+	Organization *FanoutOrganization `json:"organization,omitempty"`
+	// maximum number of targets updated at the same time, 0 for all of them
+	Concurrency int `json:"concurrency,omitempty"`
+}
+
+// FanoutOrganization deploys to every active account of the AWS organization
+// by assuming a role in each of them
+type FanoutOrganization struct {
+	Regions []string `json:"regions"`
+	// defaults to OrganizationAccountAccessRole
+	Role    string   `json:"role,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+type FanoutTarget struct {
+	Account string `json:"account,omitempty"`
+	Region  string `json:"region"`
+	Profile string `json:"profile,omitempty"`
+	// name of the role to assume in the account
+	Role string `json:"role,omitempty"`
+}
+
+func (t FanoutTarget) Name() string {
+	parts := []string{}
+	for _, part := range []string{t.Account, t.Profile, t.Region} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+func (t FanoutTarget) apply(aws map[string]string) {
+	aws["region"] = t.Region
+	if t.Profile != "" {
+		aws["profile"] = t.Profile
+	}
+	if t.Role != "" && t.Account != "" {
+		aws["assumeRole"] = fmt.Sprintf("arn:aws:iam::%v:role/%v", t.Account, t.Role)
+	}
+}
+
+func validateFanout(fanout *FanoutConfig) error {
+	if fanout == nil {
+		return nil
+	}
+	for _, target := range fanout.Targets {
+		if target.Region == "" {
+			return fmt.Errorf("Fanout target %v is missing a region", target.Name())
+		}
+		if target.Role != "" && target.Account == "" {
+			return fmt.Errorf("Fanout target %v has a role but no account", target.Name())
+		}
+	}
+	if fanout.Organization != nil && len(fanout.Organization.Regions) == 0 {
+		return fmt.Errorf("Fanout organization needs at least one region")
+	}
+	return nil
+}
+
+// FanoutTargets lists the configured targets, resolving the accounts of the
+// organization if one is configured
+func (p *Project) FanoutTargets() ([]FanoutTarget, error) {
+	if p.app.Fanout == nil {
+		return nil, fmt.Errorf("No fanout targets are configured")
+	}
+	result := append([]FanoutTarget{}, p.app.Fanout.Targets...)
+
+	org := p.app.Fanout.Organization
+	if org != nil {
+		aws, err := p.aws()
+		if err != nil {
+			return nil, err
+		}
+		accounts, err := aws.ListAccounts()
+		if err != nil {
+			return nil, err
+		}
+		role := org.Role
+		if role == "" {
+			role = "OrganizationAccountAccessRole"
+		}
+		for _, account := range accounts {
+			if slices.Contains(org.Exclude, account) {
+				continue
+			}
+			for _, region := range org.Regions {
+				result = append(result, FanoutTarget{
+					Account: account,
+					Region:  region,
+					Role:    role,
+				})
+			}
+		}
+	}
+	return result, nil
+}
+
+// Target loads a copy of the project that deploys the same stage to the
+// given target, with its own js process and state
+func (p *Project) Target(target FanoutTarget) (*Project, error) {
+	result, err := newProject(p.version, p.PathConfig(), &target)
+	if err != nil {
+		return nil, err
+	}
+	result.app.Stage = p.app.Stage
+	result.injectFailure = p.injectFailure
+	return result, nil
+}
@@ -0,0 +1,117 @@
+package project
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// FreezeWindow is either weekly, like "Fri 16:00" to "Mon 08:00", or a one
+// off period like "2024-12-20 00:00" to "2025-01-06 00:00"
+type FreezeWindow struct {
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	Timezone string `json:"timezone,omitempty"`
+}
+
+const freezeDateLayout = "2006-01-02 15:04"
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+func (w FreezeWindow) String() string {
+	result := w.Start + " – " + w.End
+	if w.Timezone != "" {
+		result += " " + w.Timezone
+	}
+	return result
+}
+
+func (w FreezeWindow) location() (*time.Location, error) {
+	if w.Timezone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(w.Timezone)
+}
+
+// minutes since the start of the week for weekly windows
+func parseWeekly(input string) (int, bool) {
+	parts := strings.Fields(input)
+	if len(parts) != 2 {
+		return 0, false
+	}
+	day, ok := weekdays[strings.ToLower(parts[0])[:min(3, len(parts[0]))]]
+	if !ok {
+		return 0, false
+	}
+	clock, err := time.Parse("15:04", parts[1])
+	if err != nil {
+		return 0, false
+	}
+	return int(day)*24*60 + clock.Hour()*60 + clock.Minute(), true
+}
+
+func (w FreezeWindow) Contains(now time.Time) (bool, error) {
+	loc, err := w.location()
+	if err != nil {
+		return false, err
+	}
+	now = now.In(loc)
+
+	start, startOk := parseWeekly(w.Start)
+	end, endOk := parseWeekly(w.End)
+	if startOk && endOk {
+		current := int(now.Weekday())*24*60 + now.Hour()*60 + now.Minute()
+		if start <= end {
+			return current >= start && current < end, nil
+		}
+		return current >= start || current < end, nil
+	}
+
+	startTime, err := time.ParseInLocation(freezeDateLayout, w.Start, loc)
+	if err != nil {
+		return false, fmt.Errorf("Invalid freeze window start %q", w.Start)
+	}
+	endTime, err := time.ParseInLocation(freezeDateLayout, w.End, loc)
+	if err != nil {
+		return false, fmt.Errorf("Invalid freeze window end %q", w.End)
+	}
+	return !now.Before(startTime) && now.Before(endTime), nil
+}
+
+// Frozen returns the freeze window the current stage is in, if any
+func (p *Project) Frozen(now time.Time) (*FreezeWindow, error) {
+	return p.app.Frozen(now)
+}
+
+func (a *App) Frozen(now time.Time) (*FreezeWindow, error) {
+	for _, window := range a.Freeze[a.Stage] {
+		inside, err := window.Contains(now)
+		if err != nil {
+			return nil, err
+		}
+		if inside {
+			return &window, nil
+		}
+	}
+	return nil, nil
+}
+
+func validateFreeze(freeze map[string][]FreezeWindow) error {
+	for stage, windows := range freeze {
+		for _, window := range windows {
+			_, err := window.Contains(time.Now())
+			if err != nil {
+				return fmt.Errorf("Freeze window for stage %v: %w", stage, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package project
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var envNameRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// lambda sets these itself and rejects functions that try to override them
+var reservedEnv = []string{
+	"_HANDLER",
+	"_X_AMZN_TRACE_ID",
+	"AWS_DEFAULT_REGION",
+	"AWS_REGION",
+	"AWS_EXECUTION_ENV",
+	"AWS_LAMBDA_FUNCTION_NAME",
+	"AWS_LAMBDA_FUNCTION_MEMORY_SIZE",
+	"AWS_LAMBDA_FUNCTION_VERSION",
+	"AWS_LAMBDA_INITIALIZATION_TYPE",
+	"AWS_LAMBDA_LOG_GROUP_NAME",
+	"AWS_LAMBDA_LOG_STREAM_NAME",
+	"AWS_ACCESS_KEY",
+	"AWS_ACCESS_KEY_ID",
+	"AWS_SECRET_ACCESS_KEY",
+	"AWS_SESSION_TOKEN",
+	"AWS_LAMBDA_RUNTIME_API",
+	"LAMBDA_TASK_ROOT",
+	"LAMBDA_RUNTIME_DIR",
+}
+
+func validateFunctions(functions map[string]FunctionConfig) error {
+	for name, fn := range functions {
+		for key := range fn.Environment {
+			if !envNameRegex.MatchString(key) {
+				return fmt.Errorf("Function %v: invalid environment variable name %q", name, key)
+			}
+			for _, reserved := range reservedEnv {
+				if strings.EqualFold(key, reserved) {
+					return fmt.Errorf("Function %v: environment variable %v is reserved by Lambda", name, key)
+				}
+			}
+		}
+		for _, link := range fn.Link {
+			if !envNameRegex.MatchString(link) {
+				return fmt.Errorf("Function %v: cannot link to %q, link targets must be valid environment variable names", name, link)
+			}
+		}
+		err := validateWarm(name, fn.Warm)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package project
+
+import (
+	"log/slog"
+	"os/exec"
+	"strings"
+)
+
+type Git struct {
+	Branch string `json:"branch"`
+	Commit string `json:"commit"`
+	Dirty  bool   `json:"dirty"`
+	Author string `json:"author"`
+}
+
+func (g *Git) Short() string {
+	result := g.Commit
+	if len(result) > 7 {
+		result = result[:7]
+	}
+	if g.Dirty {
+		result += " (dirty)"
+	}
+	return result
+}
+
+func (p *Project) git(args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = p.root
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Git returns nil when the project is not inside a git repository
+func (p *Project) Git() *Git {
+	commit, err := p.git("rev-parse", "HEAD")
+	if err != nil {
+		slog.Info("not a git repository", "err", err)
+		return nil
+	}
+	result := &Git{
+		Commit: commit,
+	}
+	result.Branch, _ = p.git("rev-parse", "--abbrev-ref", "HEAD")
+	result.Author, _ = p.git("log", "-1", "--format=%an <%ae>")
+	status, _ := p.git("status", "--porcelain")
+	result.Dirty = status != ""
+	return result
+}
+
+// gitUser is the configured git identity of whoever runs sst, not the author
+// of HEAD, empty when none is configured
+func (p *Project) gitUser() string {
+	name, _ := p.git("config", "user.name")
+	email, _ := p.git("config", "user.email")
+	switch {
+	case name != "" && email != "":
+		return name + " <" + email + ">"
+	case name != "":
+		return name
+	}
+	return email
+}
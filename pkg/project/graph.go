@@ -0,0 +1,250 @@
+package project
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type GraphNode struct {
+	URN  string `json:"urn"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// Component is the urn of the closest sst component the resource belongs to
+	Component string `json:"component,omitempty"`
+	// Op is the planned operation when the graph is built from a plan
+	Op string `json:"op,omitempty"`
+}
+
+type GraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type Graph struct {
+	Nodes      []GraphNode `json:"nodes"`
+	Edges      []GraphEdge `json:"edges"`
+	Components []GraphNode `json:"components"`
+}
+
+// Graph builds the dependency graph of the deployed resources, sst components
+// become groups instead of nodes
+func (p *Project) Graph() (*Graph, error) {
+	checkpoint, err := p.Stack.Checkpoint()
+	if err != nil {
+		return nil, err
+	}
+	builder := newGraphBuilder()
+	if checkpoint == nil || checkpoint.Latest == nil {
+		return builder.build(), nil
+	}
+	for _, resource := range checkpoint.Latest.Resources {
+		dependencies := []string{}
+		for _, dependency := range resource.Dependencies {
+			dependencies = append(dependencies, string(dependency))
+		}
+		builder.add(string(resource.URN), string(resource.Type), string(resource.Parent), "", dependencies)
+	}
+	return builder.build(), nil
+}
+
+// PlanGraph builds the graph from a preview, the engine does not report
+// dependencies for planned resources so only the component grouping is known
+func PlanGraph(plan []PlannedResource) *Graph {
+	builder := newGraphBuilder()
+	for _, resource := range plan {
+		builder.add(resource.URN, resource.Type, resource.Parent, string(resource.Op), nil)
+	}
+	return builder.build()
+}
+
+type graphResource struct {
+	urn          string
+	typ          string
+	parent       string
+	op           string
+	dependencies []string
+}
+
+type graphBuilder struct {
+	resources map[string]*graphResource
+	order     []string
+}
+
+func newGraphBuilder() *graphBuilder {
+	return &graphBuilder{resources: map[string]*graphResource{}}
+}
+
+func (b *graphBuilder) add(urn string, typ string, parent string, op string, dependencies []string) {
+	if _, ok := b.resources[urn]; !ok {
+		b.order = append(b.order, urn)
+	}
+	b.resources[urn] = &graphResource{urn, typ, parent, op, dependencies}
+}
+
+func graphHidden(typ string) bool {
+	return typ == "pulumi:pulumi:Stack" || strings.HasPrefix(typ, "pulumi:providers:")
+}
+
+func graphComponent(typ string) bool {
+	return strings.HasPrefix(typ, "sst:")
+}
+
+// component walks up the parents until it finds an sst component
+func (b *graphBuilder) component(urn string) string {
+	resource, ok := b.resources[urn]
+	for ok && resource.parent != "" {
+		parent, found := b.resources[resource.parent]
+		if !found {
+			return ""
+		}
+		if graphComponent(parent.typ) {
+			return parent.urn
+		}
+		resource = parent
+	}
+	return ""
+}
+
+// target drops dependencies on the stack and providers, a dependency on a
+// component points at the whole group
+func (b *graphBuilder) target(urn string) (string, bool) {
+	resource, ok := b.resources[urn]
+	if !ok || graphHidden(resource.typ) {
+		return "", false
+	}
+	return urn, true
+}
+
+func (b *graphBuilder) build() *Graph {
+	result := &Graph{
+		Nodes:      []GraphNode{},
+		Edges:      []GraphEdge{},
+		Components: []GraphNode{},
+	}
+	seen := map[GraphEdge]bool{}
+	for _, urn := range b.order {
+		resource := b.resources[urn]
+		if graphHidden(resource.typ) {
+			continue
+		}
+		node := GraphNode{
+			URN:       urn,
+			Name:      urnName(urn),
+			Type:      resource.typ,
+			Component: b.component(urn),
+			Op:        resource.op,
+		}
+		if graphComponent(resource.typ) {
+			result.Components = append(result.Components, node)
+			continue
+		}
+		result.Nodes = append(result.Nodes, node)
+		for _, dependency := range resource.dependencies {
+			to, ok := b.target(dependency)
+			if !ok || to == urn {
+				continue
+			}
+			edge := GraphEdge{From: urn, To: to}
+			if !seen[edge] {
+				seen[edge] = true
+				result.Edges = append(result.Edges, edge)
+			}
+		}
+	}
+	sort.Slice(result.Edges, func(i, j int) bool {
+		if result.Edges[i].From != result.Edges[j].From {
+			return result.Edges[i].From < result.Edges[j].From
+		}
+		return result.Edges[i].To < result.Edges[j].To
+	})
+	return result
+}
+
+func urnName(urn string) string {
+	return urn[strings.LastIndex(urn, "::")+2:]
+}
+
+// Dot renders the graph for graphviz with a cluster per component
+func (g *Graph) Dot() string {
+	ids := g.ids()
+	var b strings.Builder
+	b.WriteString("digraph sst {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box, fontname=\"Helvetica\"];\n")
+	var write func(component string, depth int)
+	write = func(component string, depth int) {
+		indent := strings.Repeat("  ", depth)
+		for _, child := range g.Components {
+			if child.Component != component {
+				continue
+			}
+			fmt.Fprintf(&b, "%vsubgraph cluster_%v {\n", indent, ids[child.URN])
+			fmt.Fprintf(&b, "%v  label=%q;\n", indent, child.Name+" ("+child.Type+")")
+			write(child.URN, depth+1)
+			fmt.Fprintf(&b, "%v}\n", indent)
+		}
+		for _, node := range g.Nodes {
+			if node.Component != component {
+				continue
+			}
+			fmt.Fprintf(&b, "%v%v [label=%q];\n", indent, ids[node.URN], node.Name+"\n"+node.Type)
+		}
+	}
+	write("", 1)
+	for _, edge := range g.Edges {
+		// graphviz cannot point an edge at a cluster
+		if strings.HasPrefix(ids[edge.To], "c") {
+			continue
+		}
+		fmt.Fprintf(&b, "  %v -> %v;\n", ids[edge.From], ids[edge.To])
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// Mermaid renders the graph as a flowchart with a subgraph per component
+func (g *Graph) Mermaid() string {
+	ids := g.ids()
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	var write func(component string, depth int)
+	write = func(component string, depth int) {
+		indent := strings.Repeat("  ", depth)
+		for _, child := range g.Components {
+			if child.Component != component {
+				continue
+			}
+			fmt.Fprintf(&b, "%vsubgraph %v[\"%v\"]\n", indent, ids[child.URN], mermaidLabel(child.Name))
+			write(child.URN, depth+1)
+			fmt.Fprintf(&b, "%vend\n", indent)
+		}
+		for _, node := range g.Nodes {
+			if node.Component != component {
+				continue
+			}
+			fmt.Fprintf(&b, "%v%v[\"%v<br/><small>%v</small>\"]\n", indent, ids[node.URN], mermaidLabel(node.Name), mermaidLabel(node.Type))
+		}
+	}
+	write("", 1)
+	for _, edge := range g.Edges {
+		fmt.Fprintf(&b, "  %v --> %v\n", ids[edge.From], ids[edge.To])
+	}
+	return b.String()
+}
+
+func mermaidLabel(input string) string {
+	return strings.ReplaceAll(input, "\"", "#quot;")
+}
+
+// ids are short identifiers that are valid in both dot and mermaid
+func (g *Graph) ids() map[string]string {
+	result := map[string]string{}
+	for i, node := range g.Components {
+		result[node.URN] = fmt.Sprintf("c%v", i)
+	}
+	for i, node := range g.Nodes {
+		result[node.URN] = fmt.Sprintf("r%v", i)
+	}
+	return result
+}
@@ -0,0 +1,131 @@
+package project
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type HealthConfig struct {
+	Checks []HealthCheck `json:"checks"`
+	// seconds to wait for every check to pass, defaults to 120
+	Timeout int `json:"timeout,omitempty"`
+	// redeploy the previous commit if the checks do not pass
+	Rollback bool `json:"rollback,omitempty"`
+}
+
+type HealthCheck struct {
+	// name of the stack output holding the url to check
+	Output string `json:"output"`
+	Path   string `json:"path,omitempty"`
+	// defaults to 200
+	Status int `json:"status,omitempty"`
+	// maximum response time in milliseconds, unlimited if not set
+	Latency int `json:"latency,omitempty"`
+}
+
+type HealthResult struct {
+	Check   HealthCheck
+	URL     string
+	Status  int
+	Latency time.Duration
+	Error   string
+}
+
+func (r *HealthResult) Healthy() bool {
+	return r.Error == ""
+}
+
+func validateHealth(health *HealthConfig) error {
+	if health == nil {
+		return nil
+	}
+	for _, check := range health.Checks {
+		if check.Output == "" {
+			return fmt.Errorf("Health check is missing an output")
+		}
+	}
+	return nil
+}
+
+func (c HealthCheck) run(client *http.Client, outputs map[string]interface{}) *HealthResult {
+	result := &HealthResult{Check: c}
+	base, ok := outputs[c.Output].(string)
+	if !ok || base == "" {
+		result.Error = fmt.Sprintf("output %q is not a url", c.Output)
+		return result
+	}
+	result.URL = strings.TrimSuffix(base, "/") + "/" + strings.TrimPrefix(c.Path, "/")
+	if c.Path == "" {
+		result.URL = base
+	}
+
+	expected := c.Status
+	if expected == 0 {
+		expected = http.StatusOK
+	}
+
+	start := time.Now()
+	resp, err := client.Get(result.URL)
+	result.Latency = time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	resp.Body.Close()
+	result.Status = resp.StatusCode
+
+	if resp.StatusCode != expected {
+		result.Error = fmt.Sprintf("expected status %v, got %v", expected, resp.StatusCode)
+		return result
+	}
+	if c.Latency > 0 && result.Latency > time.Duration(c.Latency)*time.Millisecond {
+		result.Error = fmt.Sprintf("took %v, expected under %vms", result.Latency, c.Latency)
+	}
+	return result
+}
+
+// CheckHealth polls the configured health checks against the outputs of the
+// last update until all of them pass or the timeout is reached. It returns
+// the last result of every check.
+func (p *Project) CheckHealth() ([]*HealthResult, bool, error) {
+	health := p.app.Health
+	if health == nil || len(health.Checks) == 0 {
+		return nil, true, nil
+	}
+	outputs, err := p.Stack.Outputs()
+	if err != nil {
+		return nil, false, err
+	}
+
+	timeout := time.Duration(health.Timeout) * time.Second
+	if timeout == 0 {
+		timeout = 2 * time.Minute
+	}
+	deadline := time.Now().Add(timeout)
+	client := &http.Client{Timeout: 30 * time.Second}
+	results := make([]*HealthResult, len(health.Checks))
+
+	for {
+		healthy := true
+		for i, check := range health.Checks {
+			if results[i] != nil && results[i].Healthy() {
+				continue
+			}
+			results[i] = check.run(client, outputs)
+			slog.Info("health check", "url", results[i].URL, "status", results[i].Status, "err", results[i].Error)
+			if !results[i].Healthy() {
+				healthy = false
+			}
+		}
+		if healthy {
+			return results, true, nil
+		}
+		if time.Now().After(deadline) {
+			return results, false, nil
+		}
+		time.Sleep(5 * time.Second)
+	}
+}
@@ -0,0 +1,94 @@
+package project
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+type Update struct {
+	// sequential, starting at 1 for the first update of the stage
+	Version         int            `json:"-"`
+	Kind            string         `json:"kind"`
+	Message         string         `json:"message"`
+	Result          string         `json:"result"`
+	StartTime       int64          `json:"startTime"`
+	EndTime         int64          `json:"endTime"`
+	ResourceChanges map[string]int `json:"resourceChanges"`
+
+	checkpoint string
+}
+
+func (u *Update) Started() time.Time {
+	return time.Unix(u.StartTime, 0)
+}
+
+func (u *Update) Duration() time.Duration {
+	return time.Duration(u.EndTime-u.StartTime) * time.Second
+}
+
+func (s *stack) pathHistory() string {
+	return filepath.Join(s.project.PathState(), ".pulumi", "history", s.project.app.Name, s.project.app.Stage)
+}
+
+// History returns the recorded updates for the current stage, newest first
+func (s *stack) History() ([]*Update, error) {
+	entries, err := os.ReadDir(s.pathHistory())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []*Update{}, nil
+		}
+		return nil, err
+	}
+
+	result := []*Update{}
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".history.json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(s.pathHistory(), entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var update Update
+		err = json.Unmarshal(data, &update)
+		if err != nil {
+			return nil, err
+		}
+		update.checkpoint = filepath.Join(s.pathHistory(), strings.TrimSuffix(entry.Name(), ".history.json")+".checkpoint.json")
+		result = append(result, &update)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].StartTime < result[j].StartTime
+	})
+	for i, update := range result {
+		update.Version = i + 1
+	}
+	slices.Reverse(result)
+	return result, nil
+}
+
+// OutputsAt returns the stack outputs as they were after the given update
+func (s *stack) OutputsAt(version int) (map[string]interface{}, error) {
+	updates, err := s.History()
+	if err != nil {
+		return nil, err
+	}
+	for _, update := range updates {
+		if update.Version != version {
+			continue
+		}
+		checkpoint, err := readCheckpoint(update.checkpoint)
+		if err != nil {
+			return nil, err
+		}
+		return checkpointOutputs(checkpoint), nil
+	}
+	return nil, fmt.Errorf("Version %v not found", version)
+}
@@ -0,0 +1,402 @@
+package project
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+type iamActions struct {
+	Read   []string
+	Create []string
+	Update []string
+	Delete []string
+}
+
+// the actions pulumi needs for every operation on a resource type, reads are
+// always needed since resources are read back after they change
+var iamResourceActions = map[string]iamActions{
+	"aws:s3/bucketV2:BucketV2": {
+		Read:   []string{"s3:GetBucket*", "s3:ListBucket", "s3:GetAccelerateConfiguration", "s3:GetLifecycleConfiguration", "s3:GetReplicationConfiguration", "s3:GetEncryptionConfiguration"},
+		Create: []string{"s3:CreateBucket", "s3:PutBucketTagging"},
+		Update: []string{"s3:PutBucketTagging"},
+		Delete: []string{"s3:DeleteBucket"},
+	},
+	"aws:s3/bucketObject:BucketObject": {
+		Read:   []string{"s3:GetObject", "s3:GetObjectTagging"},
+		Create: []string{"s3:PutObject", "s3:PutObjectTagging"},
+		Update: []string{"s3:PutObject", "s3:PutObjectTagging"},
+		Delete: []string{"s3:DeleteObject"},
+	},
+	"aws:s3/bucketPolicy:BucketPolicy": {
+		Read:   []string{"s3:GetBucketPolicy"},
+		Create: []string{"s3:PutBucketPolicy"},
+		Update: []string{"s3:PutBucketPolicy"},
+		Delete: []string{"s3:DeleteBucketPolicy"},
+	},
+	"aws:s3/bucketPublicAccessBlock:BucketPublicAccessBlock": {
+		Read:   []string{"s3:GetBucketPublicAccessBlock"},
+		Create: []string{"s3:PutBucketPublicAccessBlock"},
+		Update: []string{"s3:PutBucketPublicAccessBlock"},
+		Delete: []string{"s3:DeleteBucketPublicAccessBlock"},
+	},
+	"aws:s3/bucketWebsiteConfigurationV2:BucketWebsiteConfigurationV2": {
+		Read:   []string{"s3:GetBucketWebsite"},
+		Create: []string{"s3:PutBucketWebsite"},
+		Update: []string{"s3:PutBucketWebsite"},
+		Delete: []string{"s3:DeleteBucketWebsite"},
+	},
+	"aws:lambda/function:Function": {
+		Read:   []string{"lambda:GetFunction", "lambda:GetFunctionCodeSigningConfig", "lambda:ListVersionsByFunction"},
+		Create: []string{"lambda:CreateFunction", "lambda:TagResource", "iam:PassRole"},
+		Update: []string{"lambda:UpdateFunctionCode", "lambda:UpdateFunctionConfiguration", "lambda:TagResource", "lambda:UntagResource", "iam:PassRole"},
+		Delete: []string{"lambda:DeleteFunction"},
+	},
+	"aws:lambda/functionUrl:FunctionUrl": {
+		Read:   []string{"lambda:GetFunctionUrlConfig"},
+		Create: []string{"lambda:CreateFunctionUrlConfig"},
+		Update: []string{"lambda:UpdateFunctionUrlConfig"},
+		Delete: []string{"lambda:DeleteFunctionUrlConfig"},
+	},
+	"aws:lambda/eventSourceMapping:EventSourceMapping": {
+		Read:   []string{"lambda:GetEventSourceMapping"},
+		Create: []string{"lambda:CreateEventSourceMapping"},
+		Update: []string{"lambda:UpdateEventSourceMapping"},
+		Delete: []string{"lambda:DeleteEventSourceMapping"},
+	},
+	"aws:lambda/invocation:Invocation": {
+		Create: []string{"lambda:InvokeFunction"},
+		Update: []string{"lambda:InvokeFunction"},
+	},
+	"aws:lambda/permission:Permission": {
+		Read:   []string{"lambda:GetPolicy"},
+		Create: []string{"lambda:AddPermission"},
+		Delete: []string{"lambda:RemovePermission"},
+	},
+	"aws:iam/role:Role": {
+		Read:   []string{"iam:GetRole", "iam:GetRolePolicy", "iam:ListRolePolicies", "iam:ListAttachedRolePolicies", "iam:ListInstanceProfilesForRole"},
+		Create: []string{"iam:CreateRole", "iam:PutRolePolicy", "iam:TagRole"},
+		Update: []string{"iam:UpdateRole", "iam:UpdateAssumeRolePolicy", "iam:PutRolePolicy", "iam:DeleteRolePolicy", "iam:TagRole", "iam:UntagRole"},
+		Delete: []string{"iam:DeleteRole", "iam:DeleteRolePolicy", "iam:DetachRolePolicy"},
+	},
+	"aws:iam/policy:Policy": {
+		Read:   []string{"iam:GetPolicy", "iam:GetPolicyVersion", "iam:ListPolicyVersions"},
+		Create: []string{"iam:CreatePolicy"},
+		Update: []string{"iam:CreatePolicyVersion", "iam:DeletePolicyVersion"},
+		Delete: []string{"iam:DeletePolicy", "iam:DeletePolicyVersion"},
+	},
+	"aws:iam/rolePolicyAttachment:RolePolicyAttachment": {
+		Read:   []string{"iam:ListAttachedRolePolicies"},
+		Create: []string{"iam:AttachRolePolicy"},
+		Delete: []string{"iam:DetachRolePolicy"},
+	},
+	"aws:dynamodb/table:Table": {
+		Read:   []string{"dynamodb:DescribeTable", "dynamodb:DescribeContinuousBackups", "dynamodb:DescribeTimeToLive", "dynamodb:ListTagsOfResource"},
+		Create: []string{"dynamodb:CreateTable", "dynamodb:TagResource"},
+		Update: []string{"dynamodb:UpdateTable", "dynamodb:UpdateTimeToLive", "dynamodb:TagResource", "dynamodb:UntagResource"},
+		Delete: []string{"dynamodb:DeleteTable"},
+	},
+	"aws:sqs/queue:Queue": {
+		Read:   []string{"sqs:GetQueueAttributes", "sqs:GetQueueUrl", "sqs:ListQueueTags"},
+		Create: []string{"sqs:CreateQueue", "sqs:TagQueue"},
+		Update: []string{"sqs:SetQueueAttributes", "sqs:TagQueue", "sqs:UntagQueue"},
+		Delete: []string{"sqs:DeleteQueue"},
+	},
+	"aws:sns/topic:Topic": {
+		Read:   []string{"sns:GetTopicAttributes", "sns:ListTagsForResource"},
+		Create: []string{"sns:CreateTopic", "sns:TagResource"},
+		Update: []string{"sns:SetTopicAttributes", "sns:TagResource", "sns:UntagResource"},
+		Delete: []string{"sns:DeleteTopic"},
+	},
+	"aws:cloudfront/distribution:Distribution": {
+		Read:   []string{"cloudfront:GetDistribution", "cloudfront:ListTagsForResource"},
+		Create: []string{"cloudfront:CreateDistribution", "cloudfront:TagResource"},
+		Update: []string{"cloudfront:UpdateDistribution", "cloudfront:TagResource", "cloudfront:UntagResource"},
+		Delete: []string{"cloudfront:DeleteDistribution", "cloudfront:UpdateDistribution"},
+	},
+	"aws:cloudfront/function:Function": {
+		Read:   []string{"cloudfront:DescribeFunction", "cloudfront:GetFunction"},
+		Create: []string{"cloudfront:CreateFunction", "cloudfront:PublishFunction"},
+		Update: []string{"cloudfront:UpdateFunction", "cloudfront:PublishFunction"},
+		Delete: []string{"cloudfront:DeleteFunction"},
+	},
+	"aws:cloudfront/cachePolicy:CachePolicy": {
+		Read:   []string{"cloudfront:GetCachePolicy"},
+		Create: []string{"cloudfront:CreateCachePolicy"},
+		Update: []string{"cloudfront:UpdateCachePolicy"},
+		Delete: []string{"cloudfront:DeleteCachePolicy"},
+	},
+	"aws:cloudfront/originAccessIdentity:OriginAccessIdentity": {
+		Read:   []string{"cloudfront:GetCloudFrontOriginAccessIdentity"},
+		Create: []string{"cloudfront:CreateCloudFrontOriginAccessIdentity"},
+		Update: []string{"cloudfront:UpdateCloudFrontOriginAccessIdentity"},
+		Delete: []string{"cloudfront:DeleteCloudFrontOriginAccessIdentity"},
+	},
+	"aws:acm/certificate:Certificate": {
+		Read:   []string{"acm:DescribeCertificate", "acm:ListTagsForCertificate"},
+		Create: []string{"acm:RequestCertificate", "acm:AddTagsToCertificate"},
+		Update: []string{"acm:AddTagsToCertificate", "acm:RemoveTagsFromCertificate"},
+		Delete: []string{"acm:DeleteCertificate"},
+	},
+	"aws:acm/certificateValidation:CertificateValidation": {
+		Read:   []string{"acm:DescribeCertificate"},
+		Create: []string{"acm:DescribeCertificate"},
+	},
+	"aws:route53/record:Record": {
+		Read:   []string{"route53:ListResourceRecordSets", "route53:GetChange"},
+		Create: []string{"route53:ChangeResourceRecordSets"},
+		Update: []string{"route53:ChangeResourceRecordSets"},
+		Delete: []string{"route53:ChangeResourceRecordSets"},
+	},
+	"aws:cloudwatch/eventRule:EventRule": {
+		Read:   []string{"events:DescribeRule", "events:ListTagsForResource"},
+		Create: []string{"events:PutRule", "events:TagResource"},
+		Update: []string{"events:PutRule", "events:TagResource", "events:UntagResource"},
+		Delete: []string{"events:DeleteRule"},
+	},
+	"aws:cloudwatch/eventTarget:EventTarget": {
+		Read:   []string{"events:ListTargetsByRule"},
+		Create: []string{"events:PutTargets"},
+		Update: []string{"events:PutTargets"},
+		Delete: []string{"events:RemoveTargets"},
+	},
+	"aws:cloudwatch/logGroup:LogGroup": {
+		Read:   []string{"logs:DescribeLogGroups", "logs:ListTagsLogGroup"},
+		Create: []string{"logs:CreateLogGroup", "logs:PutRetentionPolicy", "logs:TagLogGroup"},
+		Update: []string{"logs:PutRetentionPolicy", "logs:DeleteRetentionPolicy", "logs:TagLogGroup"},
+		Delete: []string{"logs:DeleteLogGroup"},
+	},
+	"aws:cloudwatch/metricAlarm:MetricAlarm": {
+		Read:   []string{"cloudwatch:DescribeAlarms", "cloudwatch:ListTagsForResource"},
+		Create: []string{"cloudwatch:PutMetricAlarm", "cloudwatch:TagResource"},
+		Update: []string{"cloudwatch:PutMetricAlarm", "cloudwatch:TagResource"},
+		Delete: []string{"cloudwatch:DeleteAlarms"},
+	},
+}
+
+// dynamic providers in internal/components call the aws sdk directly
+var iamDynamicActions = map[string][]string{
+	"FunctionCodeUpdater":      {"lambda:UpdateFunctionCode", "lambda:GetFunction"},
+	"FunctionCanary":           {"lambda:PublishVersion", "lambda:CreateAlias", "lambda:GetAlias", "lambda:UpdateAlias", "lambda:DeleteAlias", "cloudwatch:DescribeAlarms"},
+	"LogGroup":                 {"logs:CreateLogGroup", "logs:PutRetentionPolicy", "logs:DeleteRetentionPolicy", "logs:DeleteLogGroup"},
+	"DistributionInvalidation": {"cloudfront:CreateInvalidation"},
+	"BucketFiles":              {"s3:PutObject", "s3:DeleteObject"},
+	"CloudflareRecord":         {},
+	"InjectedFailure":          {},
+	"FunctionProvisioned":      {"lambda:PublishVersion", "lambda:CreateAlias", "lambda:UpdateAlias", "lambda:DeleteAlias", "lambda:PutProvisionedConcurrencyConfig", "lambda:DeleteProvisionedConcurrencyConfig"},
+}
+
+// needed by every deploy for state, credentials and the quota pre-flight
+var iamBaseActions = []string{
+	"sts:GetCallerIdentity",
+	"ssm:GetParameter",
+	"servicequotas:GetServiceQuota",
+	"servicequotas:GetAWSDefaultServiceQuota",
+	"lambda:GetAccountSettings",
+	"ec2:DescribeVpcs",
+	"ec2:DescribeAddresses",
+	"cloudfront:ListDistributions",
+}
+
+var iamStateActions = []string{
+	"s3:GetObject",
+	"s3:PutObject",
+	"s3:DeleteObject",
+	"s3:ListBucket",
+}
+
+var iamDynamicName = regexp.MustCompile(`sst\.(\w+)$`)
+
+type PlannedResource struct {
+	URN    string
+	Type   string
+	Op     apitype.OpType
+	Parent string
+}
+
+type IamStatement struct {
+	Sid      string   `json:"Sid,omitempty"`
+	Effect   string   `json:"Effect"`
+	Action   []string `json:"Action"`
+	Resource []string `json:"Resource"`
+}
+
+type IamPolicy struct {
+	Version   string         `json:"Version"`
+	Statement []IamStatement `json:"Statement"`
+}
+
+type IamReport struct {
+	Policy  IamPolicy
+	Actions []string
+	// resource types without a known mapping, they get every action of their
+	// service which is broader than needed
+	Unknown []string
+}
+
+// Plan previews a deploy and returns every resource with the operation the
+// deploy would perform on it
+func (s *stack) Plan() ([]PlannedResource, error) {
+	events, err := s.Preview()
+	if err != nil {
+		return nil, err
+	}
+	result := []PlannedResource{}
+	errors := []string{}
+	for evt := range events {
+		if evt.ResourcePreEvent != nil {
+			metadata := evt.ResourcePreEvent.Metadata
+			planned := PlannedResource{
+				URN:  metadata.URN,
+				Type: metadata.Type,
+				Op:   metadata.Op,
+			}
+			if metadata.New != nil {
+				planned.Parent = metadata.New.Parent
+			} else if metadata.Old != nil {
+				planned.Parent = metadata.Old.Parent
+			}
+			result = append(result, planned)
+		}
+		if evt.DiagnosticEvent != nil && evt.DiagnosticEvent.Severity == "error" {
+			errors = append(errors, strings.TrimSpace(evt.DiagnosticEvent.Message))
+		}
+	}
+	if len(errors) > 0 {
+		return nil, fmt.Errorf("Preview failed: %v", strings.Join(errors, "\n"))
+	}
+	return result, nil
+}
+
+func NewIamReport(plan []PlannedResource) *IamReport {
+	actions := map[string]bool{}
+	unknown := map[string]bool{}
+	add := func(items []string) {
+		for _, item := range items {
+			actions[item] = true
+		}
+	}
+	add(iamBaseActions)
+
+	for _, resource := range plan {
+		if resource.Type == "pulumi:pulumi:Stack" || strings.HasPrefix(resource.Type, "pulumi:providers:") || strings.HasPrefix(resource.Type, "sst:") {
+			continue
+		}
+		if resource.Type == "pulumi-nodejs:dynamic:Resource" {
+			match := iamDynamicName.FindStringSubmatch(resource.URN)
+			if match == nil {
+				unknown[resource.Type] = true
+				continue
+			}
+			dynamic, ok := iamDynamicActions[match[1]]
+			if !ok {
+				unknown["sst."+match[1]] = true
+				continue
+			}
+			add(dynamic)
+			continue
+		}
+		mapping, ok := iamResourceActions[resource.Type]
+		if !ok {
+			if service := iamService(resource.Type); service != "" {
+				actions[service+":*"] = true
+			}
+			unknown[resource.Type] = true
+			continue
+		}
+		add(mapping.Read)
+		switch resource.Op {
+		case apitype.OpCreate:
+			add(mapping.Create)
+		case apitype.OpUpdate:
+			add(mapping.Update)
+		case apitype.OpReplace, apitype.OpCreateReplacement, apitype.OpDeleteReplaced:
+			add(mapping.Create)
+			add(mapping.Delete)
+		case apitype.OpDelete:
+			add(mapping.Delete)
+		}
+	}
+
+	report := &IamReport{
+		Actions: sortedKeys(actions),
+		Unknown: sortedKeys(unknown),
+	}
+	report.Policy = IamPolicy{
+		Version: "2012-10-17",
+		Statement: []IamStatement{
+			{
+				Sid:      "Resources",
+				Effect:   "Allow",
+				Action:   report.Actions,
+				Resource: []string{"*"},
+			},
+			{
+				Sid:      "State",
+				Effect:   "Allow",
+				Action:   iamStateActions,
+				Resource: []string{"arn:aws:s3:::sst-bootstrap-*", "arn:aws:s3:::sst-bootstrap-*/*"},
+			},
+		},
+	}
+	return report
+}
+
+// iamService is the IAM prefix of a pulumi aws type, like s3 for
+// aws:s3/bucket:Bucket
+func iamService(resourceType string) string {
+	parts := strings.Split(resourceType, ":")
+	if len(parts) < 3 || parts[0] != "aws" {
+		return ""
+	}
+	module := strings.Split(parts[1], "/")[0]
+	switch module {
+	case "apigatewayv2":
+		return "apigateway"
+	case "cloudwatch":
+		return ""
+	}
+	return module
+}
+
+// Preflight simulates every statement of the report's policy against the
+// resources it is scoped to and returns the actions the current credentials
+// are not allowed to perform. Wildcard actions are skipped since the
+// simulator only evaluates exact action names.
+func (p *Project) Preflight(report *IamReport) ([]string, error) {
+	aws, err := p.aws()
+	if err != nil {
+		return nil, err
+	}
+	denied := map[string]bool{}
+	for _, statement := range report.Policy.Statement {
+		actions := []string{}
+		for _, action := range statement.Action {
+			if strings.Contains(action, "*") {
+				slog.Info("skipping wildcard action in preflight", "action", action)
+				continue
+			}
+			actions = append(actions, action)
+		}
+		if len(actions) == 0 {
+			continue
+		}
+		decisions, err := aws.Simulate(actions, statement.Resource)
+		if err != nil {
+			return nil, err
+		}
+		for _, action := range actions {
+			if allowed, ok := decisions[action]; ok && !allowed {
+				denied[action] = true
+			}
+		}
+	}
+	result := sortedKeys(denied)
+	if len(result) > 0 {
+		return result, &CredentialsError{fmt.Errorf("Credentials are not allowed to perform %v actions the deploy needs", len(result))}
+	}
+	return result, nil
+}
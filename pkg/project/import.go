@@ -0,0 +1,68 @@
+package project
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/sst/ion/pkg/global"
+)
+
+func (s *stack) pathImport() string {
+	return filepath.Join(s.project.PathState(), "import")
+}
+
+// Import adopts existing resources into the state of the stage. The file is
+// a list of resources in the format read by pulumi import --file.
+func (s *stack) Import(file []byte) error {
+	dir := s.pathImport()
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	err = os.WriteFile(filepath.Join(dir, "resources.json"), file, 0644)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(filepath.Join(dir, "Pulumi.yaml"), []byte(fmt.Sprintf(
+		"name: %v\nruntime: nodejs\nbackend:\n  url: %v\n",
+		s.project.app.Name,
+		s.project.backend.Url(),
+	)), 0644)
+	if err != nil {
+		return err
+	}
+
+	env, err := s.project.backend.Env()
+	if err != nil {
+		return err
+	}
+	environ := append(os.Environ(),
+		"PULUMI_HOME="+global.ConfigDir(),
+		"PULUMI_CONFIG_PASSPHRASE=",
+		"PULUMI_SKIP_UPDATE_CHECK=true",
+	)
+	for key, value := range env {
+		environ = append(environ, key+"="+value)
+	}
+
+	commands := [][]string{
+		{"stack", "select", "--create", s.project.app.Stage},
+		{"import", "--file", "resources.json", "--stack", s.project.app.Stage, "--yes", "--skip-preview", "--generate-code=false"},
+	}
+	for _, args := range commands {
+		slog.Info("running pulumi", "args", args)
+		cmd := exec.Command("pulumi", args...)
+		cmd.Dir = dir
+		cmd.Env = environ
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("Import failed: %v", strings.TrimSpace(string(output)))
+		}
+	}
+	return nil
+}
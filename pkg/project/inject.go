@@ -0,0 +1,8 @@
+package project
+
+// InjectFailure makes the next update fail right before it reaches the
+// resource with this urn, name or type. It is used to test how rollback,
+// retries, locks and exit codes behave without a real cloud failure.
+func (p *Project) InjectFailure(target string) {
+	p.injectFailure = target
+}
@@ -0,0 +1,215 @@
+package project
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/sst/ion/pkg/global"
+)
+
+// marks a local config that was encrypted with the developer's key
+var encryptedHeader = []byte("sst:encrypted:")
+
+func (p *Project) PathLocalConfig() string {
+	return filepath.Join(p.PathTemp(), "local.config")
+}
+
+func pathLocalKey() string {
+	return filepath.Join(global.ConfigDir(), "local.key")
+}
+
+// localKey is generated on first use and never leaves the developer's machine
+func localKey() ([]byte, error) {
+	data, err := os.ReadFile(pathLocalKey())
+	if err == nil {
+		return base64.StdEncoding.DecodeString(string(bytes.TrimSpace(data)))
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+	key := make([]byte, 32)
+	_, err = rand.Read(key)
+	if err != nil {
+		return nil, err
+	}
+	err = os.MkdirAll(filepath.Dir(pathLocalKey()), 0755)
+	if err != nil {
+		return nil, err
+	}
+	err = os.WriteFile(pathLocalKey(), []byte(base64.StdEncoding.EncodeToString(key)), 0600)
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func localCipher() (cipher.AEAD, error) {
+	key, err := localKey()
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (p *Project) readLocalConfig() ([]byte, bool, error) {
+	data, err := os.ReadFile(p.PathLocalConfig())
+	if err != nil {
+		return nil, false, err
+	}
+	if !bytes.HasPrefix(data, encryptedHeader) {
+		return data, false, nil
+	}
+	sealed, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(data[len(encryptedHeader):])))
+	if err != nil {
+		return nil, true, err
+	}
+	gcm, err := localCipher()
+	if err != nil {
+		return nil, true, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, true, fmt.Errorf("Local config is corrupted")
+	}
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, true, fmt.Errorf("Could not decrypt local config, it was encrypted with a different key")
+	}
+	return plain, true, nil
+}
+
+func (p *Project) EncryptLocalConfig() error {
+	plain, encrypted, err := p.readLocalConfig()
+	if err != nil {
+		return err
+	}
+	if encrypted {
+		return nil
+	}
+	gcm, err := localCipher()
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return err
+	}
+	sealed := gcm.Seal(nonce, nonce, plain, nil)
+	data := append([]byte{}, encryptedHeader...)
+	data = append(data, []byte(base64.StdEncoding.EncodeToString(sealed))...)
+	return os.WriteFile(p.PathLocalConfig(), data, 0600)
+}
+
+func (p *Project) DecryptLocalConfig() error {
+	plain, encrypted, err := p.readLocalConfig()
+	if err != nil {
+		return err
+	}
+	if !encrypted {
+		return nil
+	}
+	return os.WriteFile(p.PathLocalConfig(), plain, 0600)
+}
+
+// the parts of the app config a developer may override locally, everything
+// else like freeze windows or providers is team policy
+var localConfigKeys = map[string][]string{
+	"vars":      nil,
+	"functions": {"environment"},
+}
+
+// localApp returns the app config with the developer's overrides for the
+// current stage merged in. The local config is keyed by stage so overrides
+// never leak into stages they were not written for.
+//
+//	{ "dev": { "vars": { "memory": 2048 } } }
+func (p *Project) localApp() (*App, error) {
+	data, _, err := p.readLocalConfig()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return p.app, nil
+		}
+		return nil, err
+	}
+
+	stages := map[string]map[string]interface{}{}
+	err = json.Unmarshal(data, &stages)
+	if err != nil {
+		return nil, &ConfigError{fmt.Errorf("Invalid local config, expected overrides keyed by stage: %w", err)}
+	}
+	overlay, ok := stages[p.app.Stage]
+	if !ok {
+		return p.app, nil
+	}
+	slog.Info("applying local config", "path", p.PathLocalConfig(), "stage", p.app.Stage)
+
+	for key, value := range overlay {
+		nested, allowed := localConfigKeys[key]
+		if !allowed {
+			return nil, &ConfigError{fmt.Errorf("Local config can not override %q, only %v", key, strings.Join(sortedKeys(localConfigKeys), ", "))}
+		}
+		if nested == nil {
+			continue
+		}
+		items, _ := value.(map[string]interface{})
+		for name, item := range items {
+			fields, _ := item.(map[string]interface{})
+			for field := range fields {
+				if !slices.Contains(nested, field) {
+					return nil, &ConfigError{fmt.Errorf("Local config can not override %v.%v.%v, only %v", key, name, field, strings.Join(nested, ", "))}
+				}
+			}
+		}
+	}
+
+	app, err := json.Marshal(p.app)
+	if err != nil {
+		return nil, err
+	}
+	base := map[string]interface{}{}
+	err = json.Unmarshal(app, &base)
+	if err != nil {
+		return nil, err
+	}
+	merged, err := json.Marshal(mergeConfig(base, overlay))
+	if err != nil {
+		return nil, err
+	}
+	var result App
+	err = json.Unmarshal(merged, &result)
+	if err != nil {
+		return nil, &ConfigError{err}
+	}
+	err = validateFunctions(result.Functions)
+	if err != nil {
+		return nil, &ConfigError{err}
+	}
+	return &result, nil
+}
+
+func mergeConfig(base, overlay map[string]interface{}) map[string]interface{} {
+	for key, value := range overlay {
+		next, ok := value.(map[string]interface{})
+		prev, prevOk := base[key].(map[string]interface{})
+		if ok && prevOk {
+			base[key] = mergeConfig(prev, next)
+			continue
+		}
+		base[key] = value
+	}
+	return base
+}
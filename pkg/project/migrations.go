@@ -0,0 +1,179 @@
+package project
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"time"
+)
+
+type MigrationsConfig struct {
+	// directory with one file per migration, applied in lexical order
+	Directory string `json:"directory,omitempty"`
+	// shell command that applies a migration, with a directory it runs once per
+	// pending file which is passed as SST_MIGRATION
+	Command string `json:"command"`
+}
+
+type Migration struct {
+	Name    string    `json:"name"`
+	Applied time.Time `json:"applied"`
+	Commit  string    `json:"commit,omitempty"`
+}
+
+// tools that track their own migrations are recorded under this name
+const migrationCommand = "command"
+
+func validateMigrations(config *MigrationsConfig) error {
+	if config == nil {
+		return nil
+	}
+	if config.Command == "" {
+		return fmt.Errorf("Migrations need a command")
+	}
+	return nil
+}
+
+// the local record written before migrations were stored with the stage
+func (p *Project) pathMigrations() string {
+	return filepath.Join(p.PathState(), "migrations", p.app.Stage+".json")
+}
+
+// Migrations returns the migrations applied to the stage, oldest first. They
+// are stored in the backend so a fresh checkout does not apply them again.
+func (p *Project) Migrations() ([]Migration, error) {
+	result := []Migration{}
+	found, err := p.readStageData("migrations", &result)
+	if err != nil || found {
+		return result, err
+	}
+	data, err := os.ReadFile(p.pathMigrations())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Migration{}, nil
+		}
+		return nil, err
+	}
+	err = json.Unmarshal(data, &result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (p *Project) recordMigration(name string) error {
+	applied, err := p.Migrations()
+	if err != nil {
+		return err
+	}
+	migration := Migration{Name: name, Applied: time.Now().UTC()}
+	if git := p.Git(); git != nil {
+		migration.Commit = git.Commit
+	}
+	// the command runs on every deploy, only its last run is kept
+	applied = slices.DeleteFunc(applied, func(item Migration) bool {
+		return item.Name == name
+	})
+	applied = append(applied, migration)
+	err = p.writeStageData("migrations", applied)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(p.pathMigrations())
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// PendingMigrations lists the files in the migrations directory that were
+// not applied to the stage yet
+func (p *Project) PendingMigrations() ([]string, error) {
+	config := p.app.Migrations
+	if config == nil {
+		return []string{}, nil
+	}
+	if config.Directory == "" {
+		return []string{migrationCommand}, nil
+	}
+	entries, err := os.ReadDir(filepath.Join(p.root, config.Directory))
+	if err != nil {
+		return nil, err
+	}
+	applied, err := p.Migrations()
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, migration := range applied {
+		names = append(names, migration.Name)
+	}
+	result := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() || slices.Contains(names, entry.Name()) {
+			continue
+		}
+		result = append(result, entry.Name())
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// Migrate applies the pending migrations with the outputs of the last update
+// in the environment, it stops at the first one that fails. The database has
+// to be reachable from this machine.
+func (p *Project) Migrate(onStart func(name string)) ([]string, error) {
+	pending, err := p.PendingMigrations()
+	if err != nil {
+		return nil, err
+	}
+	if len(pending) == 0 {
+		return pending, nil
+	}
+	outputs, err := p.Stack.Outputs()
+	if err != nil {
+		return nil, err
+	}
+
+	applied := []string{}
+	for _, name := range pending {
+		onStart(name)
+		slog.Info("running migration", "name", name)
+		var cmd *exec.Cmd
+		if runtime.GOOS == "windows" {
+			cmd = exec.Command("cmd", "/C", p.app.Migrations.Command)
+		} else {
+			cmd = exec.Command("sh", "-c", p.app.Migrations.Command)
+		}
+		cmd.Dir = p.root
+		cmd.Env = append(os.Environ(), outputEnv(outputs)...)
+		cmd.Env = append(cmd.Env, "SST_STAGE="+p.app.Stage)
+		if name != migrationCommand {
+			cmd.Env = append(cmd.Env,
+				"SST_MIGRATION="+filepath.Join(p.root, p.app.Migrations.Directory, name),
+				"SST_MIGRATION_NAME="+name,
+			)
+		}
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		err = cmd.Run()
+		if err != nil {
+			if exit, ok := err.(*exec.ExitError); ok {
+				return applied, fmt.Errorf("Migration %v failed with exit code %v", name, exit.ExitCode())
+			}
+			return applied, err
+		}
+		err = p.recordMigration(name)
+		if err != nil {
+			return applied, err
+		}
+		applied = append(applied, name)
+	}
+	return applied, nil
+}
@@ -0,0 +1,45 @@
+package project
+
+import (
+	"reflect"
+	"sort"
+)
+
+type OutputDiffOp string
+
+const (
+	OutputAdded   OutputDiffOp = "added"
+	OutputChanged OutputDiffOp = "changed"
+	OutputRemoved OutputDiffOp = "removed"
+)
+
+type OutputDiff struct {
+	Key string
+	Op  OutputDiffOp
+	Old interface{}
+	New interface{}
+}
+
+// DiffOutputs returns the differences between two sets of outputs sorted by key
+func DiffOutputs(old, new map[string]interface{}) []OutputDiff {
+	result := []OutputDiff{}
+	for k, v := range new {
+		prev, ok := old[k]
+		if !ok {
+			result = append(result, OutputDiff{Key: k, Op: OutputAdded, New: v})
+			continue
+		}
+		if !reflect.DeepEqual(prev, v) {
+			result = append(result, OutputDiff{Key: k, Op: OutputChanged, Old: prev, New: v})
+		}
+	}
+	for k, v := range old {
+		if _, ok := new[k]; !ok {
+			result = append(result, OutputDiff{Key: k, Op: OutputRemoved, Old: v})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
@@ -0,0 +1,71 @@
+package project
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// PhaseEvent is reported by long running providers, like gradual traffic
+// shifting, that run outside of the engine's event stream
+type PhaseEvent struct {
+	URN     string `json:"urn"`
+	Message string `json:"message"`
+}
+
+func (p *Project) pathPhases() string {
+	return filepath.Join(p.PathRun(), "phases.ndjson")
+}
+
+// tailPhases polls the phases file written by providers and emits every new
+// line until done is closed
+func (s *stack) tailPhases(emit func(StackEvent), done <-chan struct{}) {
+	tailFile(s.project.pathPhases(), done, func(line []byte) {
+		var evt PhaseEvent
+		if json.Unmarshal(line, &evt) != nil {
+			return
+		}
+		emit(StackEvent{PhaseEvent: &evt})
+	})
+}
+
+// tailFile removes the file left by the previous run and then passes every
+// complete line appended to it to fn until done is closed
+func tailFile(path string, done <-chan struct{}, fn func([]byte)) {
+	os.Remove(path)
+	var offset int64
+	for {
+		select {
+		case <-done:
+			readLines(path, &offset, fn)
+			return
+		case <-time.After(500 * time.Millisecond):
+			readLines(path, &offset, fn)
+		}
+	}
+}
+
+func readLines(path string, offset *int64, fn func([]byte)) {
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	_, err = file.Seek(*offset, io.SeekStart)
+	if err != nil {
+		return
+	}
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			// partial lines are picked up on the next poll
+			return
+		}
+		*offset += int64(len(line))
+		fn(line)
+	}
+}
@@ -0,0 +1,20 @@
+//go:build !windows
+
+package project
+
+import (
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// processStarted identifies a running process together with its pid, so a
+// pid reused by an unrelated process is not mistaken for it
+func processStarted(pid int) (string, bool) {
+	out, err := exec.Command("ps", "-o", "lstart=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return "", false
+	}
+	started := strings.TrimSpace(string(out))
+	return started, started != ""
+}
@@ -0,0 +1,9 @@
+//go:build windows
+
+package project
+
+// processStarted is not supported on windows, updates there can not be
+// interrupted from another process either
+func processStarted(pid int) (string, bool) {
+	return "", false
+}
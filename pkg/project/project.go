@@ -17,6 +17,23 @@ type App struct {
 	Stage         string                       `json:"stage"`
 	RemovalPolicy string                       `json:"removalPolicy"`
 	Providers     map[string]map[string]string `json:"providers"`
+	Protect       []string                     `json:"protect"`
+	Functions     map[string]FunctionConfig    `json:"functions"`
+	Vars          map[string]interface{}       `json:"vars"`
+	Freeze        map[string][]FreezeWindow    `json:"freeze"`
+	Health        *HealthConfig                `json:"health"`
+	Fanout        *FanoutConfig                `json:"fanout"`
+	Quotas        string                       `json:"quotas"`
+	Notify        bool                         `json:"notify"`
+	Tests         *TestsConfig                 `json:"tests"`
+	Migrations    *MigrationsConfig            `json:"migrations"`
+	Seed          *SeedConfig                  `json:"seed"`
+}
+
+type FunctionConfig struct {
+	Environment map[string]string `json:"environment,omitempty"`
+	Link        []string          `json:"link,omitempty"`
+	Warm        *WarmConfig       `json:"warm,omitempty"`
 }
 
 type Project struct {
@@ -26,6 +43,13 @@ type Project struct {
 	app     *App
 	backend provider.Backend
 	env     map[string]string
+	target  *FanoutTarget
+	// set from --fail-on-size
+	bundleLimit int64
+	// runs next to other stages of the same app
+	parallel bool
+	// set from the hidden --inject-failure flag
+	injectFailure string
 
 	Stack *stack
 }
@@ -37,7 +61,7 @@ func Discover() (string, error) {
 	}
 	cfgPath, err := fs.FindUp(cwd, "sst.config.ts")
 	if err != nil {
-		return "", err
+		return "", &ConfigError{err}
 	}
 	return cfgPath, nil
 }
@@ -52,6 +76,10 @@ type ProjctConfig struct {
 }
 
 func New(version, cfgPath string) (*Project, error) {
+	return newProject(version, cfgPath, nil)
+}
+
+func newProject(version, cfgPath string, target *FanoutTarget) (*Project, error) {
 	rootPath := filepath.Dir(cfgPath)
 
 	process, err := js.Start(
@@ -65,6 +93,7 @@ func New(version, cfgPath string) (*Project, error) {
 		version: version,
 		root:    rootPath,
 		process: process,
+		target:  target,
 	}
 	proj.Stack = &stack{
 		project: proj,
@@ -82,22 +111,36 @@ func New(version, cfgPath string) (*Project, error) {
 		}
 	}
 
-	err = process.Eval(
+	err = proj.loadApp()
+	if err != nil {
+		return nil, err
+	}
+	err = proj.initBackend()
+	if err != nil {
+		return nil, err
+	}
+
+	return proj, nil
+}
+
+// loadApp evaluates the app config in sst.config.ts
+func (p *Project) loadApp() error {
+	err := p.process.Eval(
 		js.EvalOptions{
-			Dir:    tmp,
-			Inject: []string{filepath.Join(tmp, "src/shim/boot.js")},
+			Dir:    p.PathTemp(),
+			Inject: []string{filepath.Join(p.PathTemp(), "src/shim/boot.js")},
 			Code: fmt.Sprintf(`
 import mod from '%s';
 console.log("~j" + JSON.stringify(mod.app()))`,
-				cfgPath),
+				p.PathConfig()),
 		},
 	)
 	if err != nil {
-		return nil, err
+		return &ConfigError{err}
 	}
 
 	for {
-		cmd, line := process.Scan()
+		cmd, line := p.process.Scan()
 
 		if cmd == js.CommandDone {
 			break
@@ -108,42 +151,99 @@ console.log("~j" + JSON.stringify(mod.app()))`,
 		}
 
 		var parsed App
-		err = json.Unmarshal([]byte(line), &parsed)
+		err := json.Unmarshal([]byte(line), &parsed)
 		if err != nil {
-			return nil, err
+			return &ConfigError{err}
+		}
+		p.app = &parsed
+
+		if p.app.Providers == nil {
+			p.app.Providers = map[string]map[string]string{}
+		}
+
+		if p.app.Name == "" {
+			return &ConfigError{fmt.Errorf("Project name is required")}
+		}
+
+		if p.app.RemovalPolicy == "" {
+			p.app.RemovalPolicy = "retain"
+		}
+
+		if p.app.RemovalPolicy != "remove" && p.app.RemovalPolicy != "retain" && p.app.RemovalPolicy != "retain-all" {
+			return &ConfigError{fmt.Errorf("RemovalPolicy must be one of: remove, retain, retain-all")}
+		}
+
+		if p.app.Quotas != "" && p.app.Quotas != "warn" && p.app.Quotas != "fail" && p.app.Quotas != "off" {
+			return &ConfigError{fmt.Errorf("Quotas must be one of: warn, fail, off")}
+		}
+
+		err = validateFunctions(p.app.Functions)
+		if err != nil {
+			return &ConfigError{err}
 		}
-		proj.app = &parsed
 
-		if proj.app.Providers == nil {
-			proj.app.Providers = map[string]map[string]string{}
+		err = validateFreeze(p.app.Freeze)
+		if err != nil {
+			return &ConfigError{err}
 		}
 
-		if proj.app.Name == "" {
-			return nil, fmt.Errorf("Project name is required")
+		err = validateHealth(p.app.Health)
+		if err != nil {
+			return &ConfigError{err}
+		}
+
+		err = validateFanout(p.app.Fanout)
+		if err != nil {
+			return &ConfigError{err}
 		}
 
-		if proj.app.RemovalPolicy == "" {
-			proj.app.RemovalPolicy = "retain"
+		err = validateMigrations(p.app.Migrations)
+		if err != nil {
+			return &ConfigError{err}
 		}
 
-		if proj.app.RemovalPolicy != "remove" && proj.app.RemovalPolicy != "retain" && proj.app.RemovalPolicy != "retain-all" {
-			return nil, fmt.Errorf("RemovalPolicy must be one of: remove, retain, retain-all")
+		err = validateSeed(p.app.Seed)
+		if err != nil {
+			return &ConfigError{err}
 		}
 	}
 
-	aws := proj.app.Providers["aws"]
+	return nil
+}
+
+func (p *Project) initBackend() error {
+	aws := p.app.Providers["aws"]
 	if aws == nil {
 		aws = map[string]string{}
-		proj.app.Providers["aws"] = aws
+		p.app.Providers["aws"] = aws
+	}
+	if p.target != nil {
+		p.target.apply(aws)
+		err := os.MkdirAll(p.PathState(), 0755)
+		if err != nil {
+			return err
+		}
 	}
 	prov := &provider.AwsProvider{}
-	proj.backend = prov
-	err = prov.Init(tmp, aws)
+	p.backend = prov
+	err := prov.Init(p.PathState(), aws)
 	if err != nil {
-		return nil, err
+		return &CredentialsError{err}
 	}
 
-	return proj, nil
+	return nil
+}
+
+// Reload evaluates sst.config.ts again in the running process and keeps the
+// stage, so long running processes pick up config changes
+func (p *Project) Reload() error {
+	stage := p.app.Stage
+	err := p.loadApp()
+	if err != nil {
+		return err
+	}
+	p.app.Stage = stage
+	return p.initBackend()
 }
 
 func Create() error {
@@ -176,6 +276,27 @@ func (p *Project) PathTemp() string {
 	return filepath.Join(p.root, ".sst")
 }
 
+// PathState holds the state of the stage, separate for every fan out target
+func (p *Project) PathState() string {
+	if p.target != nil {
+		return filepath.Join(p.PathTemp(), "targets", p.target.Name())
+	}
+	return p.PathTemp()
+}
+
+// PathRun holds the files the program writes while it runs, like function
+// zips and status streams, stages deployed in parallel each get their own
+func (p *Project) PathRun() string {
+	if p.parallel {
+		return filepath.Join(p.PathState(), "stages", p.app.Stage)
+	}
+	return p.PathState()
+}
+
+func (p *Project) PathConfig() string {
+	return filepath.Join(p.root, "sst.config.ts")
+}
+
 func (p *Project) PathRoot() string {
 	return p.root
 }
@@ -0,0 +1,68 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+)
+
+type CertificateValidation struct {
+	DomainName       string
+	ValidationStatus string
+	ResourceRecord   struct {
+		Name  string
+		Type  string
+		Value string
+	}
+}
+
+type Certificate struct {
+	CertificateArn          string
+	DomainName              string
+	Status                  string
+	FailureReason           string
+	DomainValidationOptions []CertificateValidation
+}
+
+// DescribeCertificate looks up the live validation status of a certificate,
+// the region is taken from the arn since CloudFront certificates always live
+// in us-east-1
+func (a *AwsProvider) DescribeCertificate(arn string) (*Certificate, error) {
+	parts := strings.Split(arn, ":")
+	if len(parts) < 4 {
+		return nil, fmt.Errorf("Invalid certificate arn %v", arn)
+	}
+	region := parts[3]
+
+	out, err := acm.NewFromConfig(a.config, func(o *acm.Options) {
+		o.Region = region
+	}).DescribeCertificate(context.TODO(), &acm.DescribeCertificateInput{
+		CertificateArn: aws.String(arn),
+	})
+	if err != nil {
+		return nil, err
+	}
+	detail := out.Certificate
+	result := &Certificate{
+		CertificateArn: aws.ToString(detail.CertificateArn),
+		DomainName:     aws.ToString(detail.DomainName),
+		Status:         string(detail.Status),
+		FailureReason:  string(detail.FailureReason),
+	}
+	for _, option := range detail.DomainValidationOptions {
+		validation := CertificateValidation{
+			DomainName:       aws.ToString(option.DomainName),
+			ValidationStatus: string(option.ValidationStatus),
+		}
+		if option.ResourceRecord != nil {
+			validation.ResourceRecord.Name = aws.ToString(option.ResourceRecord.Name)
+			validation.ResourceRecord.Type = string(option.ResourceRecord.Type)
+			validation.ResourceRecord.Value = aws.ToString(option.ResourceRecord.Value)
+		}
+		result.DomainValidationOptions = append(result.DomainValidationOptions, validation)
+	}
+	return result, nil
+}
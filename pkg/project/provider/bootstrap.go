@@ -0,0 +1,280 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	ssmTypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/google/uuid"
+)
+
+const SSM_NAME_BOOTSTRAP_VERSION = "/sst/bootstrap-version"
+
+// function zips and other assets the components upload, kept apart from the
+// versioned state bucket
+const SSM_NAME_ASSET_BUCKET = "/sst/asset-bucket"
+
+// bootstrapUpgrades[i] upgrades a bootstrap from version i+1 to i+2, version
+// 1 is the bucket and parameter created before versions were tracked
+var bootstrapUpgrades = []func(ctx context.Context, config aws.Config, bucket string) error{
+	func(ctx context.Context, config aws.Config, bucket string) error {
+		client := s3.NewFromConfig(config)
+		_, err := client.PutBucketVersioning(ctx, &s3.PutBucketVersioningInput{
+			Bucket: aws.String(bucket),
+			VersioningConfiguration: &s3types.VersioningConfiguration{
+				Status: s3types.BucketVersioningStatusEnabled,
+			},
+		})
+		if err != nil {
+			return err
+		}
+		return blockPublicAccess(ctx, client, bucket)
+	},
+	func(ctx context.Context, config aws.Config, bucket string) error {
+		ssmClient := ssm.NewFromConfig(config)
+		existing, err := getParameter(ctx, ssmClient, SSM_NAME_ASSET_BUCKET)
+		if err != nil || existing != "" {
+			return err
+		}
+		name := fmt.Sprintf("sst-asset-%v", uuid.New().String())
+		slog.Info("creating asset bucket", "name", name)
+		client := s3.NewFromConfig(config)
+		err = createBucket(ctx, client, config.Region, name)
+		if err != nil {
+			return err
+		}
+		err = blockPublicAccess(ctx, client, name)
+		if err != nil {
+			return err
+		}
+		_, err = ssmClient.PutParameter(ctx, &ssm.PutParameterInput{
+			Name:  aws.String(SSM_NAME_ASSET_BUCKET),
+			Type:  ssmTypes.ParameterTypeString,
+			Value: aws.String(name),
+		})
+		return err
+	},
+}
+
+func createBucket(ctx context.Context, client *s3.Client, region string, name string) error {
+	var config *s3types.CreateBucketConfiguration
+	if region != "us-east-1" {
+		config = &s3types.CreateBucketConfiguration{
+			LocationConstraint: s3types.BucketLocationConstraint(region),
+		}
+	}
+	_, err := client.CreateBucket(ctx, &s3.CreateBucketInput{
+		Bucket:                    aws.String(name),
+		CreateBucketConfiguration: config,
+	})
+	return err
+}
+
+func blockPublicAccess(ctx context.Context, client *s3.Client, bucket string) error {
+	_, err := client.PutPublicAccessBlock(ctx, &s3.PutPublicAccessBlockInput{
+		Bucket: aws.String(bucket),
+		PublicAccessBlockConfiguration: &s3types.PublicAccessBlockConfiguration{
+			BlockPublicAcls:       true,
+			BlockPublicPolicy:     true,
+			IgnorePublicAcls:      true,
+			RestrictPublicBuckets: true,
+		},
+	})
+	return err
+}
+
+var BOOTSTRAP_VERSION = len(bootstrapUpgrades) + 1
+
+type BootstrapStatus struct {
+	Account string
+	Region  string
+	Bucket  string
+	// empty before version 3, assets went to the state bucket
+	AssetBucket string
+	// 0 if the account and region were never bootstrapped
+	Version int
+	Latest  int
+}
+
+func (s *BootstrapStatus) Outdated() bool {
+	return s.Version != 0 && s.Version < s.Latest
+}
+
+func (a *AwsProvider) BootstrapStatus() (*BootstrapStatus, error) {
+	ctx := context.TODO()
+	result := &BootstrapStatus{
+		Region: a.config.Region,
+		Latest: BOOTSTRAP_VERSION,
+	}
+
+	identity, err := sts.NewFromConfig(a.config).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return nil, err
+	}
+	result.Account = aws.ToString(identity.Account)
+
+	ssmClient := ssm.NewFromConfig(a.config)
+	bucket, err := getParameter(ctx, ssmClient, SSM_NAME_BUCKET)
+	if err != nil {
+		return nil, err
+	}
+	if bucket == "" {
+		return result, nil
+	}
+	result.Bucket = bucket
+	result.Version = 1
+
+	version, err := getParameter(ctx, ssmClient, SSM_NAME_BOOTSTRAP_VERSION)
+	if err != nil {
+		return nil, err
+	}
+	if version != "" {
+		result.Version, err = strconv.Atoi(version)
+		if err != nil {
+			return nil, err
+		}
+	}
+	result.AssetBucket, err = getParameter(ctx, ssmClient, SSM_NAME_ASSET_BUCKET)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// UpgradeBootstrap bootstraps the account and region if needed and applies
+// every upgrade newer than the current version
+func (a *AwsProvider) UpgradeBootstrap() (*BootstrapStatus, error) {
+	status, err := a.BootstrapStatus()
+	if err != nil {
+		return nil, err
+	}
+	if status.Version == 0 {
+		err = a.useBucket()
+		if err != nil {
+			return nil, err
+		}
+		return a.BootstrapStatus()
+	}
+	err = a.upgradeBootstrap(context.TODO(), status.Bucket, status.Version)
+	if err != nil {
+		return nil, err
+	}
+	return a.BootstrapStatus()
+}
+
+func (a *AwsProvider) upgradeBootstrap(ctx context.Context, bucket string, from int) error {
+	for version := from; version < BOOTSTRAP_VERSION; version++ {
+		slog.Info("upgrading bootstrap", "bucket", bucket, "from", version)
+		err := bootstrapUpgrades[version-1](ctx, a.config, bucket)
+		if err != nil {
+			return err
+		}
+	}
+	_, err := ssm.NewFromConfig(a.config).PutParameter(ctx, &ssm.PutParameterInput{
+		Name:      aws.String(SSM_NAME_BOOTSTRAP_VERSION),
+		Type:      ssmTypes.ParameterTypeString,
+		Value:     aws.String(strconv.Itoa(BOOTSTRAP_VERSION)),
+		Overwrite: aws.Bool(true),
+	})
+	return err
+}
+
+// RemoveBootstrap deletes the bootstrap bucket with everything in it and the
+// parameters pointing to it. Every app deployed to this account and region
+// loses its state.
+func (a *AwsProvider) RemoveBootstrap() error {
+	ctx := context.TODO()
+	status, err := a.BootstrapStatus()
+	if err != nil {
+		return err
+	}
+	if status.Version == 0 {
+		return nil
+	}
+
+	s3Client := s3.NewFromConfig(a.config)
+	for _, bucket := range []string{status.AssetBucket, status.Bucket} {
+		if bucket == "" {
+			continue
+		}
+		err = deleteBucket(ctx, s3Client, bucket)
+		if err != nil {
+			return err
+		}
+	}
+
+	ssmClient := ssm.NewFromConfig(a.config)
+	for _, name := range []string{SSM_NAME_BUCKET, SSM_NAME_ASSET_BUCKET, SSM_NAME_BOOTSTRAP_VERSION} {
+		_, err = ssmClient.DeleteParameter(ctx, &ssm.DeleteParameterInput{
+			Name: aws.String(name),
+		})
+		var pnf *ssmTypes.ParameterNotFound
+		if err != nil && !errors.As(err, &pnf) {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteBucket empties the bucket, including old versions, and deletes it
+func deleteBucket(ctx context.Context, client *s3.Client, bucket string) error {
+	slog.Info("emptying bucket", "bucket", bucket)
+	input := &s3.ListObjectVersionsInput{
+		Bucket: aws.String(bucket),
+	}
+	for {
+		page, err := client.ListObjectVersions(ctx, input)
+		if err != nil {
+			return err
+		}
+		objects := []s3types.ObjectIdentifier{}
+		for _, version := range page.Versions {
+			objects = append(objects, s3types.ObjectIdentifier{Key: version.Key, VersionId: version.VersionId})
+		}
+		for _, marker := range page.DeleteMarkers {
+			objects = append(objects, s3types.ObjectIdentifier{Key: marker.Key, VersionId: marker.VersionId})
+		}
+		if len(objects) > 0 {
+			_, err = client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+				Bucket: aws.String(bucket),
+				Delete: &s3types.Delete{Objects: objects},
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if !page.IsTruncated {
+			break
+		}
+		input.KeyMarker = page.NextKeyMarker
+		input.VersionIdMarker = page.NextVersionIdMarker
+	}
+	_, err := client.DeleteBucket(ctx, &s3.DeleteBucketInput{
+		Bucket: aws.String(bucket),
+	})
+	return err
+}
+
+// getParameter returns an empty string if the parameter does not exist
+func getParameter(ctx context.Context, client *ssm.Client, name string) (string, error) {
+	result, err := client.GetParameter(ctx, &ssm.GetParameterInput{
+		Name: aws.String(name),
+	})
+	if err != nil {
+		var pnf *ssmTypes.ParameterNotFound
+		if errors.As(err, &pnf) {
+			return "", nil
+		}
+		return "", err
+	}
+	return aws.ToString(result.Parameter.Value), nil
+}
@@ -0,0 +1,88 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
+)
+
+// principalArn is the arn of the user or role behind the credentials, the
+// policy simulator does not accept assumed role session arns
+func (a *AwsProvider) principalArn() (string, error) {
+	identity, err := sts.NewFromConfig(a.config).GetCallerIdentity(context.TODO(), &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", err
+	}
+	arn := aws.ToString(identity.Arn)
+	if !strings.Contains(arn, ":assumed-role/") {
+		return arn, nil
+	}
+	role := strings.Split(strings.SplitN(arn, ":assumed-role/", 2)[1], "/")[0]
+	out, err := iam.NewFromConfig(a.config).GetRole(context.TODO(), &iam.GetRoleInput{
+		RoleName: aws.String(role),
+	})
+	if err != nil {
+		return "", err
+	}
+	return aws.ToString(out.Role.Arn), nil
+}
+
+// Simulate asks the IAM policy simulator which of the actions the credentials
+// are allowed to perform on the resources
+func (a *AwsProvider) Simulate(actions []string, resources []string) (map[string]bool, error) {
+	principal, err := a.principalArn()
+	if err != nil {
+		return nil, err
+	}
+	client := iam.NewFromConfig(a.config)
+	result := map[string]bool{}
+	// the simulator accepts at most 128 actions per call
+	for start := 0; start < len(actions); start += 128 {
+		end := min(start+128, len(actions))
+		pages := iam.NewSimulatePrincipalPolicyPaginator(client, &iam.SimulatePrincipalPolicyInput{
+			PolicySourceArn: aws.String(principal),
+			ActionNames:     actions[start:end],
+			ResourceArns:    resources,
+		})
+		for pages.HasMorePages() {
+			page, err := pages.NextPage(context.TODO())
+			if err != nil {
+				return nil, err
+			}
+			for _, item := range page.EvaluationResults {
+				result[aws.ToString(item.EvalActionName)] = item.EvalDecision == "allowed"
+			}
+		}
+	}
+	return result, nil
+}
+
+// CanWrite checks whether the credentials can write the state of a stage. It
+// returns true when the simulator itself is not allowed, so deploys are never
+// blocked on a guess.
+func (a *AwsProvider) CanWrite() (bool, error) {
+	decisions, err := a.Simulate(
+		[]string{"s3:PutObject", "ssm:PutParameter"},
+		[]string{"*"},
+	)
+	if err != nil {
+		var denied smithy.APIError
+		if errors.As(err, &denied) && denied.ErrorCode() == "AccessDenied" {
+			slog.Info("not allowed to simulate policies, assuming write access", "err", err)
+			return true, nil
+		}
+		return false, err
+	}
+	for _, allowed := range decisions {
+		if !allowed {
+			return false, nil
+		}
+	}
+	return true, nil
+}
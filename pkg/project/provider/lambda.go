@@ -0,0 +1,103 @@
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+type ProvisionedConcurrency struct {
+	Requested int
+	Available int
+	Status    string
+	Reason    string
+}
+
+func (a *AwsProvider) lambdaClient(region string) *lambda.Client {
+	return lambda.NewFromConfig(a.config, func(o *lambda.Options) {
+		if region != "" {
+			o.Region = region
+		}
+	})
+}
+
+// GetProvisionedConcurrency returns nil if the alias has no provisioned
+// concurrency configured
+func (a *AwsProvider) GetProvisionedConcurrency(region string, function string, qualifier string) (*ProvisionedConcurrency, error) {
+	out, err := a.lambdaClient(region).GetProvisionedConcurrencyConfig(context.TODO(), &lambda.GetProvisionedConcurrencyConfigInput{
+		FunctionName: aws.String(function),
+		Qualifier:    aws.String(qualifier),
+	})
+	if err != nil {
+		var missing *types.ProvisionedConcurrencyConfigNotFoundException
+		if errors.As(err, &missing) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &ProvisionedConcurrency{
+		Requested: int(aws.ToInt32(out.RequestedProvisionedConcurrentExecutions)),
+		Available: int(aws.ToInt32(out.AvailableProvisionedConcurrentExecutions)),
+		Status:    string(out.Status),
+		Reason:    aws.ToString(out.StatusReason),
+	}, nil
+}
+
+func (a *AwsProvider) PutProvisionedConcurrency(region string, function string, qualifier string, count int) error {
+	_, err := a.lambdaClient(region).PutProvisionedConcurrencyConfig(context.TODO(), &lambda.PutProvisionedConcurrencyConfigInput{
+		FunctionName:                    aws.String(function),
+		Qualifier:                       aws.String(qualifier),
+		ProvisionedConcurrentExecutions: aws.Int32(int32(count)),
+	})
+	return err
+}
+
+// DeleteProvisionedConcurrency succeeds when the alias has no provisioned
+// concurrency configured, like functions left cold on a personal stage
+func (a *AwsProvider) DeleteProvisionedConcurrency(region string, function string, qualifier string) error {
+	_, err := a.lambdaClient(region).DeleteProvisionedConcurrencyConfig(context.TODO(), &lambda.DeleteProvisionedConcurrencyConfigInput{
+		FunctionName: aws.String(function),
+		Qualifier:    aws.String(qualifier),
+	})
+	var missing *types.ResourceNotFoundException
+	if errors.As(err, &missing) {
+		return nil
+	}
+	return err
+}
+
+type InvokeResult struct {
+	// FunctionError is set when the handler threw, the payload then holds the
+	// error
+	FunctionError string
+	// lambda always responds with json
+	Payload json.RawMessage
+	Logs    string
+}
+
+// Invoke calls the function synchronously and returns the tail of its logs
+func (a *AwsProvider) Invoke(region string, function string, payload []byte) (*InvokeResult, error) {
+	out, err := a.lambdaClient(region).Invoke(context.TODO(), &lambda.InvokeInput{
+		FunctionName:   aws.String(function),
+		Payload:        payload,
+		LogType:        types.LogTypeTail,
+		InvocationType: types.InvocationTypeRequestResponse,
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := &InvokeResult{
+		FunctionError: aws.ToString(out.FunctionError),
+		Payload:       out.Payload,
+	}
+	logs, err := base64.StdEncoding.DecodeString(aws.ToString(out.LogResult))
+	if err == nil {
+		result.Logs = string(logs)
+	}
+	return result, nil
+}
@@ -0,0 +1,33 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/service/organizations"
+	"github.com/aws/aws-sdk-go-v2/service/organizations/types"
+)
+
+// ListAccounts returns the ids of every active account in the organization
+// the credentials belong to, they must be from the management account
+func (a *AwsProvider) ListAccounts() ([]string, error) {
+	ctx := context.TODO()
+	// organizations is a global service served from us-east-1
+	client := organizations.NewFromConfig(a.config, func(o *organizations.Options) {
+		o.Region = "us-east-1"
+	})
+
+	result := []string{}
+	pages := organizations.NewListAccountsPaginator(client, &organizations.ListAccountsInput{})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, account := range page.Accounts {
+			if account.Status == types.AccountStatusActive {
+				result = append(result, *account.Id)
+			}
+		}
+	}
+	return result, nil
+}
@@ -1,6 +1,7 @@
 package provider
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -12,8 +13,10 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/google/uuid"
 
 	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
@@ -25,8 +28,14 @@ type Backend interface {
 	Lock(app string, stage string) error
 	Unlock(app string, stage string) error
 	Cancel(app string, stage string) error
+	IsLocked(app string, stage string) (bool, error)
 	Url() string
 	Env() (map[string]string, error)
+	// data about a stage that every machine deploying it has to agree on,
+	// GetData returns nil if nothing was stored under the kind yet
+	GetData(kind string, app string, stage string) ([]byte, error)
+	PutData(kind string, app string, stage string, data []byte) error
+	DeleteData(kind string, app string, stage string) error
 }
 
 type Provider interface {
@@ -38,7 +47,17 @@ type AwsProvider struct {
 	args        map[string]string
 	config      aws.Config
 	bucket      string
+	bucketErr   error
+	bucketOnce  sync.Once
 	credentials sync.Once
+	// never create the bootstrap resources, set for read-only credentials
+	readOnly bool
+}
+
+var ErrNotBootstrapped = fmt.Errorf("Account and region are not bootstrapped, run `sst bootstrap --upgrade` with credentials that can write")
+
+func (a *AwsProvider) SetReadOnly(readOnly bool) {
+	a.readOnly = readOnly
 }
 
 const SSM_NAME_BUCKET = "/sst/bootstrap"
@@ -56,10 +75,14 @@ func (a *AwsProvider) Url() string {
 
 func (a *AwsProvider) Lock(app string, stage string) error {
 	slog.Info("locking", "app", app, "stage", stage)
+	err := a.useBucket()
+	if err != nil {
+		return err
+	}
 	s3Client := s3.NewFromConfig(a.config)
 
 	lockKey := a.remoteLockFor(app, stage)
-	_, err := s3Client.GetObject(context.TODO(), &s3.GetObjectInput{
+	_, err = s3Client.GetObject(context.TODO(), &s3.GetObjectInput{
 		Bucket: aws.String(a.bucket),
 		Key:    aws.String(lockKey),
 	})
@@ -110,8 +133,69 @@ func (a *AwsProvider) remoteLockFor(app string, stage string) string {
 	return filepath.Join("state", "lock", app, fmt.Sprintf("%v.json", stage))
 }
 
+func (a *AwsProvider) remoteDataFor(kind string, app string, stage string) string {
+	return filepath.Join("state", kind, app, fmt.Sprintf("%v.json", stage))
+}
+
+func (a *AwsProvider) GetData(kind string, app string, stage string) ([]byte, error) {
+	err := a.useBucket()
+	if err != nil {
+		return nil, err
+	}
+	s3Client := s3.NewFromConfig(a.config)
+
+	result, err := s3Client.GetObject(context.TODO(), &s3.GetObjectInput{
+		Bucket: aws.String(a.bucket),
+		Key:    aws.String(a.remoteDataFor(kind, app, stage)),
+	})
+	if err != nil {
+		var nsk *s3types.NoSuchKey
+		if errors.As(err, &nsk) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer result.Body.Close()
+	return io.ReadAll(result.Body)
+}
+
+func (a *AwsProvider) PutData(kind string, app string, stage string, data []byte) error {
+	slog.Info("writing stage data", "kind", kind, "app", app, "stage", stage)
+	err := a.useBucket()
+	if err != nil {
+		return err
+	}
+	s3Client := s3.NewFromConfig(a.config)
+
+	_, err = s3Client.PutObject(context.TODO(), &s3.PutObjectInput{
+		Bucket: aws.String(a.bucket),
+		Key:    aws.String(a.remoteDataFor(kind, app, stage)),
+		Body:   bytes.NewReader(data),
+	})
+	return err
+}
+
+func (a *AwsProvider) DeleteData(kind string, app string, stage string) error {
+	slog.Info("deleting stage data", "kind", kind, "app", app, "stage", stage)
+	err := a.useBucket()
+	if err != nil {
+		return err
+	}
+	s3Client := s3.NewFromConfig(a.config)
+
+	_, err = s3Client.DeleteObject(context.TODO(), &s3.DeleteObjectInput{
+		Bucket: aws.String(a.bucket),
+		Key:    aws.String(a.remoteDataFor(kind, app, stage)),
+	})
+	return err
+}
+
 func (a *AwsProvider) Unlock(app string, stage string) error {
 	slog.Info("unlocking", "app", app, "stage", stage)
+	err := a.useBucket()
+	if err != nil {
+		return err
+	}
 	s3Client := s3.NewFromConfig(a.config)
 
 	file, err := os.Open(a.localStateFor(app, stage))
@@ -142,9 +226,13 @@ func (a *AwsProvider) Unlock(app string, stage string) error {
 
 func (a *AwsProvider) Cancel(app string, stage string) error {
 	slog.Info("canceling", "app", app, "stage", stage)
+	err := a.useBucket()
+	if err != nil {
+		return err
+	}
 	s3Client := s3.NewFromConfig(a.config)
 
-	_, err := s3Client.DeleteObject(context.TODO(), &s3.DeleteObjectInput{
+	_, err = s3Client.DeleteObject(context.TODO(), &s3.DeleteObjectInput{
 		Bucket: aws.String(a.bucket),
 		Key:    aws.String(a.remoteLockFor(app, stage)),
 	})
@@ -155,6 +243,32 @@ func (a *AwsProvider) Cancel(app string, stage string) error {
 	return nil
 }
 
+func (a *AwsProvider) IsLocked(app string, stage string) (bool, error) {
+	slog.Info("checking lock", "app", app, "stage", stage)
+	err := a.useBucket()
+	if errors.Is(err, ErrNotBootstrapped) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	s3Client := s3.NewFromConfig(a.config)
+
+	_, err = s3Client.HeadObject(context.TODO(), &s3.HeadObjectInput{
+		Bucket: aws.String(a.bucket),
+		Key:    aws.String(a.remoteLockFor(app, stage)),
+	})
+	if err != nil {
+		var nf *s3types.NotFound
+		if errors.As(err, &nf) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
 func (a *AwsProvider) Env() (map[string]string, error) {
 	creds, err := a.config.Credentials.Retrieve(context.Background())
 	if err != nil {
@@ -180,17 +294,12 @@ func (a *AwsProvider) Init(workdir string, args map[string]string) (err error) {
 	}
 	a.config = cfg
 
-	bucket, err := a.resolveBucket()
-	if err != nil {
-		return err
-	}
-	a.bucket = bucket
-
 	creds, err := cfg.Credentials.Retrieve(context.TODO())
 	if err != nil {
 		return err
 	}
 	delete(args, "profile")
+	delete(args, "assumeRole")
 	if creds.AccessKeyID != "" {
 		args["accessKey"] = creds.AccessKeyID
 	}
@@ -208,6 +317,15 @@ func (a *AwsProvider) Init(workdir string, args map[string]string) (err error) {
 	return err
 }
 
+// useBucket resolves the bootstrap bucket the first time it is needed,
+// bootstrapping the account and region if they never were
+func (a *AwsProvider) useBucket() error {
+	a.bucketOnce.Do(func() {
+		a.bucket, a.bucketErr = a.resolveBucket()
+	})
+	return a.bucketErr
+}
+
 func (a *AwsProvider) resolveBucket() (string, error) {
 	ctx := context.TODO()
 
@@ -226,6 +344,9 @@ func (a *AwsProvider) resolveBucket() (string, error) {
 	if err != nil {
 		var pnf *ssmTypes.ParameterNotFound
 		if errors.As(err, &pnf) {
+			if a.readOnly {
+				return "", ErrNotBootstrapped
+			}
 			region := a.config.Region
 			bucketName := fmt.Sprintf("sst-bootstrap-%v", uuid.New().String())
 			slog.Info("creating bootstrap bucket", "name", bucketName)
@@ -265,6 +386,11 @@ func (a *AwsProvider) resolveBucket() (string, error) {
 				return "", err
 			}
 
+			err = a.upgradeBootstrap(ctx, bucketName, 1)
+			if err != nil {
+				return "", err
+			}
+
 			return bucketName, nil
 		}
 		return "", err
@@ -290,6 +416,10 @@ func (a *AwsProvider) resolveConfig() (aws.Config, error) {
 	if err != nil {
 		return aws.Config{}, err
 	}
+	if a.args["assumeRole"] != "" {
+		slog.Info("assuming role", "arn", a.args["assumeRole"])
+		cfg.Credentials = aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), a.args["assumeRole"]))
+	}
 	_, err = cfg.Credentials.Retrieve(ctx)
 	if err != nil {
 		return aws.Config{}, err
@@ -0,0 +1,17 @@
+package project
+
+// ReadOnly detects credentials that can not write the state of the stage and
+// switches the backend to never create anything, so previews and status keep
+// working for developers without deploy rights
+func (p *Project) ReadOnly() (bool, error) {
+	aws, err := p.aws()
+	if err != nil {
+		return false, nil
+	}
+	writable, err := aws.CanWrite()
+	if err != nil {
+		return false, err
+	}
+	aws.SetReadOnly(!writable)
+	return !writable, nil
+}
@@ -0,0 +1,66 @@
+package project
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+// Pending returns operations a crashed update left in the checkpoint
+func (s *stack) Pending() ([]apitype.OperationV2, error) {
+	checkpoint, err := s.Checkpoint()
+	if err != nil {
+		return nil, err
+	}
+	if checkpoint == nil || checkpoint.Latest == nil {
+		return []apitype.OperationV2{}, nil
+	}
+	return checkpoint.Latest.PendingOperations, nil
+}
+
+// Heal clears pending operations from the checkpoint and refreshes the
+// affected resources so their state matches what actually exists in the
+// cloud. Resources that were pending creation never got an id recorded so
+// they cannot be looked up and may need to be removed by hand. The stream is
+// nil if no pending resource can be refreshed.
+func (s *stack) Heal() (StackEventStream, error) {
+	pending, err := s.Pending()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(s.pathCheckpoint())
+	if err != nil {
+		return nil, err
+	}
+	versioned := map[string]interface{}{}
+	err = json.Unmarshal(data, &versioned)
+	if err != nil {
+		return nil, err
+	}
+	checkpoint, _ := versioned["checkpoint"].(map[string]interface{})
+	latest, _ := checkpoint["latest"].(map[string]interface{})
+	delete(latest, "pending_operations")
+	data, err = json.MarshalIndent(versioned, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+	err = os.WriteFile(s.pathCheckpoint(), data, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	target := []string{}
+	for _, op := range pending {
+		if op.Type == apitype.OperationTypeCreating || op.Resource.ID == "" {
+			continue
+		}
+		target = append(target, string(op.Resource.URN))
+	}
+	// an empty target would refresh the whole stack
+	if len(target) == 0 {
+		return nil, nil
+	}
+	return s.run("refresh", target...)
+}
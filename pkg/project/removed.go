@@ -0,0 +1,100 @@
+package project
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type RemovedStatus struct {
+	Removed time.Time `json:"removed"`
+	Commit  string    `json:"commit,omitempty"`
+}
+
+// StageRemovedError is returned when reading the outputs of a stage that was
+// removed, so stale values are never handed out
+type StageRemovedError struct {
+	Stage   string
+	Removed time.Time
+}
+
+func (e *StageRemovedError) Error() string {
+	return fmt.Sprintf("Stage %v was removed on %v, deploy it again to get its outputs", e.Stage, e.Removed.Local().Format("2006-01-02 15:04"))
+}
+
+func (p *Project) pathRemoved() string {
+	return filepath.Join(p.PathState(), "removed", p.app.Stage+".json")
+}
+
+// Removed returns nil unless the stage was removed and not deployed since
+func (p *Project) Removed() (*RemovedStatus, error) {
+	data, err := os.ReadFile(p.pathRemoved())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var result RemovedStatus
+	err = json.Unmarshal(data, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (p *Project) clearRemoved() error {
+	err := os.Remove(p.pathRemoved())
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// MarkRemoved records that the stage was removed and deletes the local files
+// that only made sense while it existed
+func (p *Project) MarkRemoved() error {
+	stale := []string{
+		p.Stack.pathDrift(),
+		p.pathMigrations(),
+		p.pathSeed(),
+		p.pathWarmDown(),
+		filepath.Join(p.PathState(), "stages", p.app.Stage),
+	}
+	// types describe the outputs of the local stage
+	if data, err := os.ReadFile(p.pathPersonalStage()); err == nil && strings.TrimSpace(string(data)) == p.app.Stage {
+		stale = append(stale, p.PathTypes())
+	}
+	for _, path := range stale {
+		slog.Info("removing stale file", "path", path)
+		err := os.RemoveAll(path)
+		if err != nil {
+			return err
+		}
+	}
+	// the database went away with the stage
+	for _, kind := range []string{"migrations", "seed", "warm"} {
+		err := p.deleteStageData(kind)
+		if err != nil {
+			return err
+		}
+	}
+
+	status := RemovedStatus{Removed: time.Now().UTC()}
+	if git := p.Git(); git != nil {
+		status.Commit = git.Commit
+	}
+	data, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(p.pathRemoved()), 0755)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(p.pathRemoved(), data, 0644)
+}
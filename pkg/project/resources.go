@@ -0,0 +1,189 @@
+package project
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+type Resource struct {
+	URN       string                 `json:"urn"`
+	Name      string                 `json:"name"`
+	Type      string                 `json:"type"`
+	ID        string                 `json:"id,omitempty"`
+	Parent    string                 `json:"parent,omitempty"`
+	Component string                 `json:"component,omitempty"`
+	Created   *time.Time             `json:"created,omitempty"`
+	Modified  *time.Time             `json:"modified,omitempty"`
+	Inputs    map[string]interface{} `json:"inputs,omitempty"`
+	Outputs   map[string]interface{} `json:"outputs,omitempty"`
+}
+
+type ResourceFilter struct {
+	// Type matches the type token exactly, like aws:s3/bucketV2:BucketV2, a
+	// prefix of it like aws:s3, or its short form like aws:s3:Bucket
+	Type string
+	// Grep matches a substring of the urn or the physical id
+	Grep string
+}
+
+var typeVersion = regexp.MustCompile(`V[0-9]+$`)
+
+// shortType turns aws:s3/bucketV2:BucketV2 into aws:s3:Bucket, the form the
+// docs use, by dropping the module path and the version suffix
+func shortType(token string) string {
+	parts := strings.Split(token, ":")
+	if len(parts) != 3 {
+		return token
+	}
+	module := strings.SplitN(parts[1], "/", 2)[0]
+	return parts[0] + ":" + module + ":" + typeVersion.ReplaceAllString(parts[2], "")
+}
+
+func (f ResourceFilter) matchType(token string) bool {
+	if token == f.Type || strings.HasPrefix(token, f.Type) {
+		return true
+	}
+	short := strings.Count(f.Type, ":") == 2 && !strings.Contains(f.Type, "/")
+	return short && shortType(token) == shortType(f.Type)
+}
+
+func (f ResourceFilter) match(resource Resource) bool {
+	if f.Type != "" && !f.matchType(resource.Type) {
+		return false
+	}
+	if f.Grep != "" {
+		grep := strings.ToLower(f.Grep)
+		if !strings.Contains(strings.ToLower(resource.URN), grep) && !strings.Contains(strings.ToLower(resource.ID), grep) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *Project) stateResources() ([]Resource, error) {
+	checkpoint, err := p.Stack.Checkpoint()
+	if err != nil {
+		return nil, err
+	}
+	result := []Resource{}
+	if checkpoint == nil || checkpoint.Latest == nil {
+		return result, nil
+	}
+	types := map[string]string{}
+	parents := map[string]string{}
+	for _, resource := range checkpoint.Latest.Resources {
+		types[string(resource.URN)] = string(resource.Type)
+		parents[string(resource.URN)] = string(resource.Parent)
+	}
+	for _, resource := range checkpoint.Latest.Resources {
+		if graphHidden(string(resource.Type)) {
+			continue
+		}
+		result = append(result, newResource(resource, types, parents))
+	}
+	return result, nil
+}
+
+func newResource(resource apitype.ResourceV3, types map[string]string, parents map[string]string) Resource {
+	urn := string(resource.URN)
+	result := Resource{
+		URN:      urn,
+		Name:     urnName(urn),
+		Type:     string(resource.Type),
+		ID:       string(resource.ID),
+		Parent:   string(resource.Parent),
+		Created:  resource.Created,
+		Modified: resource.Modified,
+		Inputs:   redactSecrets(resource.Inputs),
+		Outputs:  redactSecrets(resource.Outputs),
+	}
+	for parent := parents[urn]; parent != ""; parent = parents[parent] {
+		if graphComponent(types[parent]) {
+			result.Component = parent
+			break
+		}
+	}
+	return result
+}
+
+// Resources lists the deployed resources, without inputs and outputs
+func (p *Project) Resources(filter ResourceFilter) ([]Resource, error) {
+	all, err := p.stateResources()
+	if err != nil {
+		return nil, err
+	}
+	result := []Resource{}
+	for _, resource := range all {
+		if !filter.match(resource) {
+			continue
+		}
+		resource.Inputs = nil
+		resource.Outputs = nil
+		result = append(result, resource)
+	}
+	return result, nil
+}
+
+// Resource finds a deployed resource by its urn or, if it is unique, by the
+// name given to it in sst.config.ts
+func (p *Project) Resource(urn string) (*Resource, error) {
+	all, err := p.stateResources()
+	if err != nil {
+		return nil, err
+	}
+	matches := []Resource{}
+	for _, resource := range all {
+		if resource.URN == urn {
+			return &resource, nil
+		}
+		if resource.Name == urn {
+			matches = append(matches, resource)
+		}
+	}
+	if len(matches) == 1 {
+		return &matches[0], nil
+	}
+	if len(matches) > 1 {
+		urns := []string{}
+		for _, match := range matches {
+			urns = append(urns, match.URN)
+		}
+		return nil, fmt.Errorf("%v matches more than one resource, use the urn:\n%v", urn, strings.Join(urns, "\n"))
+	}
+	return nil, fmt.Errorf("No resource %v in stage %v", urn, p.app.Stage)
+}
+
+// the signature pulumi uses to mark encrypted values in the checkpoint
+const secretSignature = "4dabf18193072939515e22adb298388d"
+
+func redactSecrets(input map[string]interface{}) map[string]interface{} {
+	if input == nil {
+		return nil
+	}
+	result := map[string]interface{}{}
+	for key, value := range input {
+		result[key] = redactValue(value)
+	}
+	return result
+}
+
+func redactValue(input interface{}) interface{} {
+	switch value := input.(type) {
+	case map[string]interface{}:
+		if _, ok := value[secretSignature]; ok {
+			return "[secret]"
+		}
+		return redactSecrets(value)
+	case []interface{}:
+		result := make([]interface{}, len(value))
+		for i, item := range value {
+			result[i] = redactValue(item)
+		}
+		return result
+	}
+	return input
+}
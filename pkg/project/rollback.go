@@ -0,0 +1,86 @@
+package project
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// PreviousGit returns the commit deployed by the last successful update
+// before the current one, nil if there is none
+func (s *stack) PreviousGit() (*Git, error) {
+	updates, err := s.History()
+	if err != nil {
+		return nil, err
+	}
+	if len(updates) < 2 {
+		return nil, nil
+	}
+	for _, update := range updates[1:] {
+		if update.Kind != "update" || update.Result != "succeeded" {
+			continue
+		}
+		checkpoint, err := readCheckpoint(update.checkpoint)
+		if err != nil {
+			return nil, err
+		}
+		return checkpointGit(checkpoint), nil
+	}
+	return nil, nil
+}
+
+func (p *Project) pathRollback() string {
+	return filepath.Join(p.PathTemp(), "rollback")
+}
+
+// Rollback loads the project as it was at the given commit from a temporary
+// git worktree that shares this project's .sst directory and node_modules.
+// The returned cleanup function stops its node process and removes the
+// worktree.
+func (p *Project) Rollback(commit string) (*Project, func(), error) {
+	top, err := p.git("rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, nil, fmt.Errorf("Rollback requires a git repository")
+	}
+	rel, err := filepath.Rel(top, p.root)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	worktree := p.pathRollback()
+	p.git("worktree", "remove", "--force", worktree)
+	os.RemoveAll(worktree)
+	slog.Info("creating rollback worktree", "commit", commit, "path", worktree)
+	_, err = p.git("worktree", "add", "--detach", worktree, commit)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not check out %v: %w", commit, err)
+	}
+	cleanup := func() {
+		p.git("worktree", "remove", "--force", worktree)
+	}
+
+	root := filepath.Join(worktree, rel)
+	for _, name := range []string{".sst", "node_modules"} {
+		if _, err := os.Stat(filepath.Join(p.root, name)); err != nil {
+			continue
+		}
+		os.RemoveAll(filepath.Join(root, name))
+		err = os.Symlink(filepath.Join(p.root, name), filepath.Join(root, name))
+		if err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+	}
+
+	previous, err := New(p.version, filepath.Join(root, "sst.config.ts"))
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	previous.app.Stage = p.app.Stage
+	return previous, func() {
+		previous.Stack.Kill()
+		cleanup()
+	}, nil
+}
@@ -0,0 +1,146 @@
+package project
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+type SeedConfig struct {
+	// shell commands run in order
+	Commands []string `json:"commands"`
+	// outputs exposed as SST_LINK_<name>, like function links
+	Link []string `json:"link,omitempty"`
+}
+
+type SeedStatus struct {
+	Seeded time.Time `json:"seeded"`
+	Commit string    `json:"commit,omitempty"`
+}
+
+func validateSeed(config *SeedConfig) error {
+	if config == nil {
+		return nil
+	}
+	if len(config.Commands) == 0 {
+		return fmt.Errorf("Seed needs at least one command")
+	}
+	for _, link := range config.Link {
+		if !envNameRegex.MatchString(link) {
+			return fmt.Errorf("Seed: cannot link to %q, link targets must be valid environment variable names", link)
+		}
+	}
+	return nil
+}
+
+// the local marker written before seeding was recorded with the stage
+func (p *Project) pathSeed() string {
+	return filepath.Join(p.PathState(), "seed", p.app.Stage+".json")
+}
+
+// Seeded returns nil if the stage was never seeded, from any machine
+func (p *Project) Seeded() (*SeedStatus, error) {
+	var result SeedStatus
+	found, err := p.readStageData("seed", &result)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		return &result, nil
+	}
+	data, err := os.ReadFile(p.pathSeed())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	err = json.Unmarshal(data, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ShouldSeed is true after the first deploy of a personal stage, shared
+// stages are only seeded with `sst seed`
+func (p *Project) ShouldSeed() (bool, error) {
+	if p.app.Seed == nil || !p.IsPersonal() {
+		return false, nil
+	}
+	seeded, err := p.Seeded()
+	if err != nil {
+		return false, err
+	}
+	return seeded == nil, nil
+}
+
+// Seed runs the seed commands with the linked outputs in the environment and
+// records that the stage was seeded
+func (p *Project) Seed(onStart func(command string)) error {
+	if p.app.Seed == nil {
+		return fmt.Errorf("No seed commands configured")
+	}
+	outputs, err := p.Stack.Outputs()
+	if err != nil {
+		return err
+	}
+	env := append(os.Environ(), outputEnv(outputs)...)
+	env = append(env, "SST_STAGE="+p.app.Stage)
+	for _, link := range p.app.Seed.Link {
+		value, ok := outputs[link]
+		if !ok {
+			return fmt.Errorf("Seed links to %v but no such output exists", link)
+		}
+		text, ok := value.(string)
+		if !ok {
+			data, err := json.Marshal(value)
+			if err != nil {
+				return err
+			}
+			text = string(data)
+		}
+		env = append(env, "SST_LINK_"+link+"="+text)
+	}
+
+	for _, command := range p.app.Seed.Commands {
+		onStart(command)
+		slog.Info("running seed", "command", command)
+		var cmd *exec.Cmd
+		if runtime.GOOS == "windows" {
+			cmd = exec.Command("cmd", "/C", command)
+		} else {
+			cmd = exec.Command("sh", "-c", command)
+		}
+		cmd.Dir = p.root
+		cmd.Env = env
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		err = cmd.Run()
+		if err != nil {
+			if exit, ok := err.(*exec.ExitError); ok {
+				return fmt.Errorf("Seed %q failed with exit code %v", command, exit.ExitCode())
+			}
+			return err
+		}
+	}
+
+	status := SeedStatus{Seeded: time.Now().UTC()}
+	if git := p.Git(); git != nil {
+		status.Commit = git.Commit
+	}
+	err = p.writeStageData("seed", status)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(p.pathSeed())
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
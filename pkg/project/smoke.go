@@ -0,0 +1,85 @@
+package project
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+type TestsConfig struct {
+	// shell command run after every successful deploy
+	Smoke string `json:"smoke,omitempty"`
+	// redeploy the previous commit if the smoke test fails
+	Rollback bool `json:"rollback,omitempty"`
+}
+
+var nonEnvChars = regexp.MustCompile(`[^A-Z0-9_]`)
+
+// outputEnv turns stack outputs into SST_OUTPUT_<NAME> variables, values that
+// are not strings are JSON encoded
+func outputEnv(outputs map[string]interface{}) []string {
+	result := []string{}
+	for _, key := range sortedKeys(outputs) {
+		name := "SST_OUTPUT_" + nonEnvChars.ReplaceAllString(strings.ToUpper(key), "_")
+		value, ok := outputs[key].(string)
+		if !ok {
+			data, err := json.Marshal(outputs[key])
+			if err != nil {
+				continue
+			}
+			value = string(data)
+		}
+		result = append(result, name+"="+value)
+	}
+	return result
+}
+
+// Smoke runs the configured smoke test with the outputs of the last update
+// in its environment. It returns the exit code, -1 if no test is configured.
+func (p *Project) Smoke() (int, error) {
+	if p.app.Tests == nil || p.app.Tests.Smoke == "" {
+		return -1, nil
+	}
+	outputs, err := p.Stack.Outputs()
+	if err != nil {
+		return 0, err
+	}
+
+	slog.Info("running smoke test", "command", p.app.Tests.Smoke)
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", p.app.Tests.Smoke)
+	} else {
+		cmd = exec.Command("sh", "-c", p.app.Tests.Smoke)
+	}
+	cmd.Dir = p.root
+	cmd.Env = append(os.Environ(), outputEnv(outputs)...)
+	cmd.Env = append(cmd.Env, "SST_STAGE="+p.app.Stage)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	if err != nil {
+		if exit, ok := err.(*exec.ExitError); ok {
+			return exit.ExitCode(), p.Audit("deploy-failed", fmt.Sprintf("smoke test exited with %v", exit.ExitCode()))
+		}
+		return 0, err
+	}
+	return 0, nil
+}
+
+// RollbackOnFailure is true if a failure of the gate, "health" or "tests",
+// should redeploy the previous commit
+func (a *App) RollbackOnFailure(gate string) bool {
+	switch gate {
+	case "health":
+		return a.Health != nil && a.Health.Rollback
+	case "tests":
+		return a.Tests != nil && a.Tests.Rollback
+	}
+	return false
+}
@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"os"
 	"path/filepath"
+	"sync"
 
 	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
 	"github.com/sst/ion/pkg/global"
@@ -19,6 +21,10 @@ type StackEvent struct {
 	apitype.EngineEvent
 	StdOutEvent           *StdOutEvent
 	ConcurrentUpdateEvent *ConcurrentUpdateEvent
+	PhaseEvent            *PhaseEvent
+	BuildEvent            *BuildEvent
+	QuotaEvent            *QuotaEvent
+	BundleEvent           *BundleEvent
 }
 
 type StdOutEvent struct {
@@ -27,10 +33,22 @@ type StdOutEvent struct {
 
 type ConcurrentUpdateEvent struct{}
 
+// QuotaEvent is emitted before a deploy for every service quota the update
+// would exceed, or could not be checked
+type QuotaEvent struct {
+	Name    string
+	Limit   float64
+	Usage   float64
+	Planned float64
+	Fail    bool
+	// set when the usage or limit could not be read
+	Error string
+}
+
 type StackEventStream = chan StackEvent
 
-func (s *stack) run(cmd string) (StackEventStream, error) {
-	slog.Info("running stack command", "cmd", cmd)
+func (s *stack) run(cmd string, target ...string) (StackEventStream, error) {
+	slog.Info("running stack command", "cmd", cmd, "target", target)
 
 	if cmd == "cancel" {
 		err := s.project.backend.Cancel(s.project.app.Name, s.project.app.Stage)
@@ -55,6 +73,23 @@ func (s *stack) run(cmd string) (StackEventStream, error) {
 	*/
 
 	env, err := s.project.backend.Env()
+	if err != nil {
+		return nil, &CredentialsError{err}
+	}
+
+	drift := []string{}
+	if cmd == "up" {
+		drift, err = s.Drift()
+		if err != nil {
+			return nil, err
+		}
+		err = s.project.clearRemoved()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	warm, err := s.project.WarmMode()
 	if err != nil {
 		return nil, err
 	}
@@ -63,17 +98,50 @@ func (s *stack) run(cmd string) (StackEventStream, error) {
 		"command": cmd,
 		"backend": s.project.backend.Url(),
 		"paths": map[string]string{
-			"home": global.ConfigDir(),
-			"root": s.project.PathRoot(),
-			"work": s.project.PathTemp(),
+			"home":  global.ConfigDir(),
+			"root":  s.project.PathRoot(),
+			"work":  s.project.PathTemp(),
+			"state": s.project.PathState(),
+			"run":   s.project.PathRun(),
 		},
-		"env": env,
+		"env":           env,
+		"git":           s.project.Git(),
+		"drift":         drift,
+		"target":        target,
+		"warm":          warm,
+		"bundleLimit":   s.project.bundleLimit,
+		"injectFailure": s.project.injectFailure,
+	}
+	err = os.MkdirAll(s.project.PathRun(), 0755)
+	if err != nil {
+		return nil, err
 	}
 	cliBytes, err := json.Marshal(cli)
-	appBytes, err := json.Marshal(s.project.App())
 	if err != nil {
 		return nil, err
 	}
+	app, err := s.project.localApp()
+	if err != nil {
+		return nil, err
+	}
+	appBytes, err := json.Marshal(app)
+	if err != nil {
+		return nil, err
+	}
+	recorder, err := s.record(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	// previews run next to updates, they must not take over the pid file
+	mutates := cmd != "cancel" && cmd != "preview"
+	if mutates {
+		err = s.writePid()
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	err = s.project.process.Eval(js.EvalOptions{
 		Dir: s.project.PathTemp(),
 		Define: map[string]string{
@@ -91,11 +159,37 @@ func (s *stack) run(cmd string) (StackEventStream, error) {
 		),
 	})
 	if err != nil {
+		if mutates {
+			s.removePid()
+		}
 		return nil, err
 	}
 
 	out := make(StackEventStream)
 	go func() {
+		defer recorder.Close()
+		var mu sync.Mutex
+		emit := func(evt StackEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			recorder.Write(evt)
+			out <- evt
+		}
+		done := make(chan struct{})
+		var tailed sync.WaitGroup
+		tailed.Add(3)
+		go func() {
+			s.tailPhases(emit, done)
+			tailed.Done()
+		}()
+		go func() {
+			s.tailBuild(emit, done)
+			tailed.Done()
+		}()
+		go func() {
+			s.tailBundles(emit, done)
+			tailed.Done()
+		}()
 		for {
 			cmd, line := s.project.process.Scan()
 			if cmd == js.CommandDone {
@@ -108,8 +202,8 @@ func (s *stack) run(cmd string) (StackEventStream, error) {
 				if err != nil {
 					continue
 				}
-				slog.Info("stack event", "event", line)
-				out <- evt
+				slog.Debug("stack event", "event", line)
+				emit(evt)
 
 			}
 
@@ -117,17 +211,22 @@ func (s *stack) run(cmd string) (StackEventStream, error) {
 				if line == "" {
 					continue
 				}
-				out <- StackEvent{
+				emit(StackEvent{
 					StdOutEvent: &StdOutEvent{
 						Text: line,
 					},
-				}
+				})
 			}
 		}
+		close(done)
+		tailed.Wait()
 		// err := s.project.backend.Unlock(s.project.app.Name, s.project.app.Stage)
 		// if err != nil {
 		// 	panic(err)
 		// }
+		if mutates {
+			s.removePid()
+		}
 		close(out)
 	}()
 
@@ -138,6 +237,12 @@ func (s *stack) Deploy() (StackEventStream, error) {
 	return s.run("up")
 }
 
+// Preview computes the changes a deploy would make without making them, it
+// only needs read access to the cloud resources
+func (s *stack) Preview() (StackEventStream, error) {
+	return s.run("preview")
+}
+
 func (s *stack) Cancel() (StackEventStream, error) {
 	return s.run("cancel")
 }
@@ -3,6 +3,7 @@ package project
 import (
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 )
 
@@ -26,3 +27,30 @@ func (p *Project) SetPersonalStage(input string) error {
 	p.app.Stage = input
 	return nil
 }
+
+// IsPersonal is true for the stage picked on this machine with `sst deploy`,
+// stages passed explicitly are shared with others
+func (p *Project) IsPersonal() bool {
+	data, err := os.ReadFile(p.pathPersonalStage())
+	if err != nil {
+		return false
+	}
+	return strings.TrimSpace(string(data)) == p.app.Stage && !p.IsProtected()
+}
+
+func (p *Project) IsProtected() bool {
+	return slices.Contains(p.app.Protect, p.app.Stage)
+}
+
+// ForStage loads a copy of the project for another stage of the app, with its
+// own js process so it can run next to the others
+func (p *Project) ForStage(stage string) (*Project, error) {
+	result, err := newProject(p.version, p.PathConfig(), p.target)
+	if err != nil {
+		return nil, err
+	}
+	result.app.Stage = stage
+	result.parallel = true
+	result.injectFailure = p.injectFailure
+	return result, nil
+}
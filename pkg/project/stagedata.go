@@ -0,0 +1,30 @@
+package project
+
+import (
+	"encoding/json"
+)
+
+// readStageData decodes what was stored for the stage in the backend, it
+// returns false if nothing was
+func (p *Project) readStageData(kind string, v interface{}) (bool, error) {
+	data, err := p.backend.GetData(kind, p.app.Name, p.app.Stage)
+	if err != nil {
+		return false, err
+	}
+	if data == nil {
+		return false, nil
+	}
+	return true, json.Unmarshal(data, v)
+}
+
+func (p *Project) writeStageData(kind string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return p.backend.PutData(kind, p.app.Name, p.app.Stage, data)
+}
+
+func (p *Project) deleteStageData(kind string) error {
+	return p.backend.DeleteData(kind, p.app.Name, p.app.Stage)
+}
@@ -0,0 +1,84 @@
+package project
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+type StackStatus struct {
+	Deployed  time.Time
+	Git       *Git
+	Outputs   map[string]interface{}
+	Locked    bool
+	Pending   []apitype.OperationV2
+	Resources int
+}
+
+// Status only reads from the backend, it never touches cloud resources
+func (s *stack) Status() (*StackStatus, error) {
+	result := &StackStatus{
+		Outputs: map[string]interface{}{},
+		Pending: []apitype.OperationV2{},
+	}
+
+	locked, err := s.project.backend.IsLocked(s.project.app.Name, s.project.app.Stage)
+	if err != nil {
+		return nil, err
+	}
+	result.Locked = locked
+
+	checkpoint, err := s.Checkpoint()
+	if err != nil {
+		return nil, err
+	}
+	if checkpoint == nil || checkpoint.Latest == nil {
+		return result, nil
+	}
+
+	result.Deployed = checkpoint.Latest.Manifest.Time
+	result.Pending = checkpoint.Latest.PendingOperations
+	result.Outputs = checkpointOutputs(checkpoint)
+	result.Git = checkpointGit(checkpoint)
+	for _, resource := range checkpoint.Latest.Resources {
+		if resource.Type == "pulumi:pulumi:Stack" {
+			continue
+		}
+		if strings.HasPrefix(string(resource.Type), "pulumi:providers:") {
+			continue
+		}
+		result.Resources++
+	}
+
+	return result, nil
+}
+
+func checkpointGit(checkpoint *apitype.CheckpointV3) *Git {
+	if checkpoint == nil || checkpoint.Latest == nil {
+		return nil
+	}
+	for _, resource := range checkpoint.Latest.Resources {
+		if resource.Type != "pulumi:pulumi:Stack" {
+			continue
+		}
+		if git, ok := resource.Outputs["_git"]; ok {
+			return decodeGit(git)
+		}
+	}
+	return nil
+}
+
+func decodeGit(input interface{}) *Git {
+	data, err := json.Marshal(input)
+	if err != nil {
+		return nil
+	}
+	var result Git
+	err = json.Unmarshal(data, &result)
+	if err != nil {
+		return nil
+	}
+	return &result
+}
@@ -0,0 +1,87 @@
+package project
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Timings keeps how long previous deploys took to create or update each type
+// of resource and how many resources each stage has, used to estimate how
+// long a deploy will take
+type Timings struct {
+	Types     map[string]*TypeTiming `json:"types"`
+	Resources map[string]int         `json:"resources"`
+
+	path  string
+	stage string
+}
+
+type TypeTiming struct {
+	Count   int           `json:"count"`
+	Average time.Duration `json:"average"`
+}
+
+func (p *Project) PathTimings() string {
+	return filepath.Join(p.PathTemp(), "history.json")
+}
+
+func (p *Project) Timings() (*Timings, error) {
+	result := &Timings{
+		Types:     map[string]*TypeTiming{},
+		Resources: map[string]int{},
+		path:      p.PathTimings(),
+		stage:     p.app.Stage,
+	}
+	data, err := os.ReadFile(result.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return result, nil
+		}
+		return nil, err
+	}
+	err = json.Unmarshal(data, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (t *Timings) Record(resourceType string, duration time.Duration) {
+	timing, ok := t.Types[resourceType]
+	if !ok {
+		timing = &TypeTiming{}
+		t.Types[resourceType] = timing
+	}
+	// cumulative average, capped so old runs eventually age out
+	timing.Count = min(timing.Count+1, 20)
+	timing.Average += (duration - timing.Average) / time.Duration(timing.Count)
+}
+
+// Expected returns false if the type was never created or updated before
+func (t *Timings) Expected(resourceType string) (time.Duration, bool) {
+	timing, ok := t.Types[resourceType]
+	if !ok {
+		return 0, false
+	}
+	return timing.Average, true
+}
+
+// StageResources is the number of resources the last deploy of the stage
+// went through, 0 if it was never deployed
+func (t *Timings) StageResources() int {
+	return t.Resources[t.stage]
+}
+
+func (t *Timings) SetStageResources(count int) {
+	t.Resources[t.stage] = count
+}
+
+func (t *Timings) Save() error {
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(t.path, data, 0644)
+}
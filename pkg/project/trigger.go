@@ -0,0 +1,230 @@
+package project
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/sst/ion/pkg/project/provider"
+)
+
+type TriggerResult struct {
+	Function string
+	*provider.InvokeResult
+}
+
+// findResource matches the name given to the resource in sst.config.ts or its
+// physical name
+func (p *Project) findResource(resourceType string, name string) (*apitype.ResourceV3, []string, error) {
+	checkpoint, err := p.Stack.Checkpoint()
+	if err != nil {
+		return nil, nil, err
+	}
+	if checkpoint == nil || checkpoint.Latest == nil {
+		return nil, nil, fmt.Errorf("Stage %v is not deployed", p.app.Stage)
+	}
+	names := []string{}
+	for i, resource := range checkpoint.Latest.Resources {
+		if string(resource.Type) != resourceType {
+			continue
+		}
+		urn := string(resource.URN)
+		logical := urn[strings.LastIndex(urn, "::")+2:]
+		names = append(names, logical)
+		physical, _ := resource.Outputs["name"].(string)
+		if logical == name || physical == name {
+			return &checkpoint.Latest.Resources[i], nil, nil
+		}
+	}
+	return nil, names, nil
+}
+
+func (p *Project) resourcesOf(resourceType string) ([]apitype.ResourceV3, error) {
+	checkpoint, err := p.Stack.Checkpoint()
+	if err != nil {
+		return nil, err
+	}
+	result := []apitype.ResourceV3{}
+	if checkpoint == nil || checkpoint.Latest == nil {
+		return result, nil
+	}
+	for _, resource := range checkpoint.Latest.Resources {
+		if string(resource.Type) == resourceType {
+			result = append(result, resource)
+		}
+	}
+	return result, nil
+}
+
+func notFound(kind string, name string, names []string) error {
+	if len(names) == 0 {
+		return fmt.Errorf("No %v named %v, the stage has none", kind, name)
+	}
+	return fmt.Errorf("No %v named %v, found: %v", kind, name, strings.Join(names, ", "))
+}
+
+// arnRegion is the region field of an arn, like us-east-1 in
+// arn:aws:lambda:us-east-1:123456789012:function:name
+func arnRegion(arn string) string {
+	parts := strings.Split(arn, ":")
+	if len(parts) < 4 {
+		return ""
+	}
+	return parts[3]
+}
+
+func arnAccount(arn string) string {
+	parts := strings.Split(arn, ":")
+	if len(parts) < 5 {
+		return ""
+	}
+	return parts[4]
+}
+
+// TriggerCron invokes every function targeted by the schedule with the event
+// EventBridge would send
+func (p *Project) TriggerCron(name string) ([]TriggerResult, error) {
+	aws, err := p.aws()
+	if err != nil {
+		return nil, err
+	}
+	rule, names, err := p.findResource("aws:cloudwatch/eventRule:EventRule", name)
+	if err != nil {
+		return nil, err
+	}
+	if rule == nil {
+		return nil, notFound("cron", name, names)
+	}
+	ruleName, _ := rule.Outputs["name"].(string)
+	ruleArn, _ := rule.Outputs["arn"].(string)
+
+	targets, err := p.resourcesOf("aws:cloudwatch/eventTarget:EventTarget")
+	if err != nil {
+		return nil, err
+	}
+	result := []TriggerResult{}
+	for _, target := range targets {
+		if target.Outputs["rule"] != ruleName {
+			continue
+		}
+		arn, _ := target.Outputs["arn"].(string)
+		if !strings.Contains(arn, ":lambda:") {
+			return nil, fmt.Errorf("Cron %v targets %v, only functions can be triggered", name, arn)
+		}
+		payload := []byte{}
+		if input, ok := target.Outputs["input"].(string); ok && input != "" {
+			payload = []byte(input)
+		} else {
+			payload, err = json.Marshal(map[string]interface{}{
+				"version":     "0",
+				"id":          uuid.NewString(),
+				"detail-type": "Scheduled Event",
+				"source":      "aws.events",
+				"account":     arnAccount(ruleArn),
+				"time":        time.Now().UTC().Format(time.RFC3339),
+				"region":      arnRegion(ruleArn),
+				"resources":   []string{ruleArn},
+				"detail":      map[string]interface{}{},
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		invoked, err := aws.Invoke(arnRegion(arn), arn, payload)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, TriggerResult{Function: arn, InvokeResult: invoked})
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("Cron %v has no function targets", name)
+	}
+	return result, nil
+}
+
+// TriggerQueue invokes the consumers of the queue with an SQS event holding
+// the payload, a JSON array becomes one record per item
+func (p *Project) TriggerQueue(name string, payload []byte) ([]TriggerResult, error) {
+	aws, err := p.aws()
+	if err != nil {
+		return nil, err
+	}
+	queue, names, err := p.findResource("aws:sqs/queue:Queue", name)
+	if err != nil {
+		return nil, err
+	}
+	if queue == nil {
+		return nil, notFound("queue", name, names)
+	}
+	queueArn, _ := queue.Outputs["arn"].(string)
+
+	bodies := []string{string(payload)}
+	var items []json.RawMessage
+	if json.Unmarshal(payload, &items) == nil {
+		bodies = []string{}
+		for _, item := range items {
+			var text string
+			// strings are sent as is, anything else as its JSON
+			if json.Unmarshal(item, &text) == nil {
+				bodies = append(bodies, text)
+				continue
+			}
+			bodies = append(bodies, string(item))
+		}
+	}
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	records := []map[string]interface{}{}
+	for _, body := range bodies {
+		hash := md5.Sum([]byte(body))
+		records = append(records, map[string]interface{}{
+			"messageId":     uuid.NewString(),
+			"receiptHandle": "sst-trigger",
+			"body":          body,
+			"attributes": map[string]string{
+				"ApproximateReceiveCount":          "1",
+				"SentTimestamp":                    now,
+				"SenderId":                         "sst",
+				"ApproximateFirstReceiveTimestamp": now,
+			},
+			"messageAttributes": map[string]interface{}{},
+			"md5OfBody":         hex.EncodeToString(hash[:]),
+			"eventSource":       "aws:sqs",
+			"eventSourceARN":    queueArn,
+			"awsRegion":         arnRegion(queueArn),
+		})
+	}
+	event, err := json.Marshal(map[string]interface{}{"Records": records})
+	if err != nil {
+		return nil, err
+	}
+
+	mappings, err := p.resourcesOf("aws:lambda/eventSourceMapping:EventSourceMapping")
+	if err != nil {
+		return nil, err
+	}
+	result := []TriggerResult{}
+	for _, mapping := range mappings {
+		if mapping.Outputs["eventSourceArn"] != queueArn {
+			continue
+		}
+		function, _ := mapping.Outputs["functionArn"].(string)
+		if function == "" {
+			function, _ = mapping.Outputs["functionName"].(string)
+		}
+		invoked, err := aws.Invoke(arnRegion(queueArn), function, event)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, TriggerResult{Function: function, InvokeResult: invoked})
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("Queue %v has no consumers", name)
+	}
+	return result, nil
+}
@@ -0,0 +1,107 @@
+package project
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+func (p *Project) PathTypes() string {
+	return filepath.Join(p.root, "sst-env.d.ts")
+}
+
+// GenerateTypes describes the outputs of the last update and the environment
+// variables injected by function links
+func (p *Project) GenerateTypes() (string, error) {
+	outputs, err := p.Stack.Outputs()
+	if err != nil {
+		return "", err
+	}
+
+	links := map[string]bool{}
+	for _, fn := range p.app.Functions {
+		for _, link := range fn.Link {
+			links[link] = true
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("/* This file is generated by `sst types`, do not edit */\n\n")
+	b.WriteString("declare global {\n")
+	b.WriteString("  namespace NodeJS {\n")
+	b.WriteString("    interface ProcessEnv {\n")
+	for _, key := range sortedKeys(links) {
+		fmt.Fprintf(&b, "      SST_LINK_%v?: string;\n", key)
+	}
+	b.WriteString("    }\n")
+	b.WriteString("  }\n")
+	b.WriteString("}\n\n")
+	b.WriteString("export interface Outputs ")
+	b.WriteString(tsType(outputs, 0))
+	b.WriteString("\n")
+	return b.String(), nil
+}
+
+func (p *Project) WriteTypes() error {
+	types, err := p.GenerateTypes()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(p.PathTypes(), []byte(types), 0644)
+}
+
+// TypesOutdated is false if types were never generated for the project
+func (p *Project) TypesOutdated() (bool, error) {
+	existing, err := os.ReadFile(p.PathTypes())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	types, err := p.GenerateTypes()
+	if err != nil {
+		return false, err
+	}
+	return string(existing) != types, nil
+}
+
+func tsType(input interface{}, depth int) string {
+	switch value := input.(type) {
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		if len(value) == 0 {
+			return "unknown[]"
+		}
+		return tsType(value[0], depth) + "[]"
+	case map[string]interface{}:
+		if len(value) == 0 {
+			return "{}"
+		}
+		indent := strings.Repeat("  ", depth+1)
+		var b strings.Builder
+		b.WriteString("{\n")
+		for _, key := range sortedKeys(value) {
+			fmt.Fprintf(&b, "%v%q: %v;\n", indent, key, tsType(value[key], depth+1))
+		}
+		b.WriteString(strings.Repeat("  ", depth) + "}")
+		return b.String()
+	}
+	return "unknown"
+}
+
+func sortedKeys[T any](input map[string]T) []string {
+	result := make([]string, 0, len(input))
+	for key := range input {
+		result = append(result, key)
+	}
+	sort.Strings(result)
+	return result
+}
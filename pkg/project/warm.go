@@ -0,0 +1,245 @@
+package project
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+type WarmConfig struct {
+	Provisioned int    `json:"provisioned,omitempty"`
+	Schedule    string `json:"schedule,omitempty"`
+	Concurrency int    `json:"concurrency,omitempty"`
+	Personal    bool   `json:"personal,omitempty"`
+}
+
+type WarmStatus struct {
+	Function    string
+	Alias       string
+	Configured  int
+	Requested   int
+	Available   int
+	Status      string
+	Schedule    string
+	Concurrency int
+}
+
+const warmSuffix = "-provisioned-sst.FunctionProvisioned"
+
+func validateWarm(name string, warm *WarmConfig) error {
+	if warm == nil {
+		return nil
+	}
+	if warm.Provisioned < 0 {
+		return fmt.Errorf("Function %v: warm.provisioned can not be negative", name)
+	}
+	if warm.Concurrency < 0 || warm.Concurrency > 5 {
+		return fmt.Errorf("Function %v: warm.concurrency must be between 1 and 5", name)
+	}
+	if warm.Schedule != "" {
+		_, err := parseWarmSchedule(warm.Schedule)
+		if err != nil {
+			return fmt.Errorf("Function %v: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// parseWarmSchedule accepts the same "5 minutes" durations as the components
+func parseWarmSchedule(input string) (time.Duration, error) {
+	var count int
+	var unit string
+	_, err := fmt.Sscanf(input, "%d %s", &count, &unit)
+	if err != nil || count <= 0 {
+		return 0, fmt.Errorf("Invalid warm schedule %q, expected something like \"5 minutes\"", input)
+	}
+	switch strings.TrimSuffix(strings.ToLower(unit), "s") {
+	case "minute":
+		return time.Duration(count) * time.Minute, nil
+	case "hour":
+		return time.Duration(count) * time.Hour, nil
+	case "day":
+		return time.Duration(count) * 24 * time.Hour, nil
+	}
+	return 0, fmt.Errorf("Invalid warm schedule %q, lambda can only be warmed every minute or less often", input)
+}
+
+// the local marker written before the scale down was recorded with the stage
+func (p *Project) pathWarmDown() string {
+	return filepath.Join(p.PathTemp(), "warm", p.app.Stage+".down")
+}
+
+type warmDownStatus struct {
+	Down time.Time `json:"down"`
+}
+
+// warmedDown is true after `sst warm down` of the stage, from any machine
+func (p *Project) warmedDown() (bool, error) {
+	var status warmDownStatus
+	found, err := p.readStageData("warm", &status)
+	if err != nil || found {
+		return found, err
+	}
+	_, err = os.Stat(p.pathWarmDown())
+	return err == nil, nil
+}
+
+// WarmMode is passed to the components, personal stages skip warming unless a
+// function opts in and `sst warm down` turns it off until `sst warm up`
+func (p *Project) WarmMode() (string, error) {
+	down, err := p.warmedDown()
+	if err != nil {
+		return "", err
+	}
+	if down {
+		return "off", nil
+	}
+	if p.IsPersonal() {
+		return "personal", nil
+	}
+	return "on", nil
+}
+
+type provisionedResource struct {
+	name     string
+	function string
+	alias    string
+	region   string
+}
+
+func (p *Project) provisionedResources() ([]provisionedResource, error) {
+	checkpoint, err := p.Stack.Checkpoint()
+	if err != nil {
+		return nil, err
+	}
+	result := []provisionedResource{}
+	if checkpoint == nil || checkpoint.Latest == nil {
+		return result, nil
+	}
+	for _, resource := range checkpoint.Latest.Resources {
+		urn := string(resource.URN)
+		if !strings.HasSuffix(urn, warmSuffix) {
+			continue
+		}
+		item := provisionedResource{
+			name: strings.TrimSuffix(urn[strings.LastIndex(urn, "::")+2:], warmSuffix),
+		}
+		item.function, _ = resource.Outputs["functionName"].(string)
+		item.alias, _ = resource.Outputs["alias"].(string)
+		item.region, _ = resource.Outputs["region"].(string)
+		result = append(result, item)
+	}
+	return result, nil
+}
+
+// Warm compares the warm config of every function with the provisioned
+// concurrency that is live
+func (p *Project) Warm() ([]WarmStatus, error) {
+	aws, err := p.aws()
+	if err != nil {
+		return nil, err
+	}
+	resources, err := p.provisionedResources()
+	if err != nil {
+		return nil, err
+	}
+	deployed := map[string]provisionedResource{}
+	for _, resource := range resources {
+		deployed[resource.name] = resource
+	}
+
+	result := []WarmStatus{}
+	for _, name := range sortedKeys(p.app.Functions) {
+		warm := p.app.Functions[name].Warm
+		if warm == nil {
+			continue
+		}
+		status := WarmStatus{
+			Function:    name,
+			Configured:  warm.Provisioned,
+			Schedule:    warm.Schedule,
+			Concurrency: max(warm.Concurrency, 1),
+		}
+		resource, ok := deployed[name]
+		if ok {
+			status.Alias = resource.alias
+			live, err := aws.GetProvisionedConcurrency(resource.region, resource.function, resource.alias)
+			if err != nil {
+				return nil, err
+			}
+			if live != nil {
+				status.Requested = live.Requested
+				status.Available = live.Available
+				status.Status = live.Status
+			}
+		}
+		result = append(result, status)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Function < result[j].Function
+	})
+	return result, nil
+}
+
+// WarmDown removes the provisioned concurrency of the stage right away, later
+// deploys keep it off until WarmUp
+func (p *Project) WarmDown() error {
+	aws, err := p.aws()
+	if err != nil {
+		return err
+	}
+	resources, err := p.provisionedResources()
+	if err != nil {
+		return err
+	}
+	for _, resource := range resources {
+		err := aws.DeleteProvisionedConcurrency(resource.region, resource.function, resource.alias)
+		if err != nil {
+			return err
+		}
+	}
+	err = p.writeStageData("warm", warmDownStatus{Down: time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+	os.Remove(p.pathWarmDown())
+	return nil
+}
+
+// WarmUp restores the configured provisioned concurrency
+func (p *Project) WarmUp() error {
+	err := p.deleteStageData("warm")
+	if err != nil {
+		return err
+	}
+	os.Remove(p.pathWarmDown())
+	mode, err := p.WarmMode()
+	if err != nil {
+		return err
+	}
+	aws, err := p.aws()
+	if err != nil {
+		return err
+	}
+	resources, err := p.provisionedResources()
+	if err != nil {
+		return err
+	}
+	for _, resource := range resources {
+		fn, ok := p.app.Functions[resource.name]
+		if !ok || fn.Warm == nil || fn.Warm.Provisioned == 0 {
+			continue
+		}
+		if mode == "personal" && !fn.Warm.Personal {
+			continue
+		}
+		err := aws.PutProvisionedConcurrency(resource.region, resource.function, resource.alias, fn.Warm.Provisioned)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,292 @@
+package telemetry
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sst/ion/pkg/global"
+)
+
+const DEFAULT_ENDPOINT = "https://telemetry.sst.dev/v1/events"
+
+// Event never includes names of apps, stages or resources
+type Event struct {
+	ID       string `json:"id"`
+	Version  string `json:"version"`
+	OS       string `json:"os"`
+	Arch     string `json:"arch"`
+	CI       bool   `json:"ci"`
+	Command  string `json:"command"`
+	Duration int64  `json:"duration"`
+	Success  bool   `json:"success"`
+	Error    string `json:"error,omitempty"`
+	Time     string `json:"time"`
+}
+
+type settings struct {
+	ID       string `json:"id"`
+	Disabled bool   `json:"disabled"`
+	Notified bool   `json:"notified"`
+}
+
+func pathSettings() string {
+	return filepath.Join(global.ConfigDir(), "telemetry.json")
+}
+
+func pathQueue() string {
+	return filepath.Join(global.ConfigDir(), "telemetry", "queue.ndjson")
+}
+
+func load() (*settings, error) {
+	result := &settings{}
+	data, err := os.ReadFile(pathSettings())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+		result.ID = uuid.New().String()
+		return result, result.save()
+	}
+	err = json.Unmarshal(data, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *settings) save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(pathSettings()), 0755)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(pathSettings(), data, 0644)
+}
+
+// Killed is true when telemetry is turned off through the environment, which
+// takes precedence over sst telemetry enable
+func Killed() bool {
+	for _, key := range []string{"SST_TELEMETRY_DISABLED", "DO_NOT_TRACK"} {
+		value := strings.ToLower(os.Getenv(key))
+		if value != "" && value != "0" && value != "false" {
+			return true
+		}
+	}
+	return false
+}
+
+func Enabled() bool {
+	if Killed() {
+		return false
+	}
+	s, err := load()
+	if err != nil {
+		return false
+	}
+	return !s.Disabled
+}
+
+func Enable() error {
+	return setDisabled(false)
+}
+
+// Disable also drops events that were queued but not sent yet
+func Disable() error {
+	err := setDisabled(true)
+	if err != nil {
+		return err
+	}
+	err = os.RemoveAll(filepath.Dir(pathQueue()))
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func setDisabled(disabled bool) error {
+	s, err := load()
+	if err != nil {
+		return err
+	}
+	s.Disabled = disabled
+	s.Notified = true
+	return s.save()
+}
+
+// Notice is true the first time it is called while telemetry is enabled, so
+// the cli can tell the user how to opt out
+func Notice() bool {
+	if !Enabled() {
+		return false
+	}
+	s, err := load()
+	if err != nil || s.Notified {
+		return false
+	}
+	s.Notified = true
+	return s.save() == nil
+}
+
+// Track queues the event locally, it is sent by a later Flush so commands
+// never wait on the network
+func Track(version string, command string, duration time.Duration, err error) {
+	if !Enabled() {
+		return
+	}
+	s, loadErr := load()
+	if loadErr != nil {
+		return
+	}
+	evt := Event{
+		ID:       s.ID,
+		Version:  version,
+		OS:       runtime.GOOS,
+		Arch:     runtime.GOARCH,
+		CI:       os.Getenv("CI") != "",
+		Command:  command,
+		Duration: duration.Milliseconds(),
+		Success:  err == nil,
+		Time:     time.Now().UTC().Format(time.RFC3339),
+	}
+	if err != nil {
+		evt.Error = fmt.Sprintf("%T", err)
+	}
+	data, marshalErr := json.Marshal(evt)
+	if marshalErr != nil {
+		return
+	}
+	if os.MkdirAll(filepath.Dir(pathQueue()), 0755) != nil {
+		return
+	}
+	file, openErr := os.OpenFile(pathQueue(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if openErr != nil {
+		return
+	}
+	defer file.Close()
+	file.Write(append(data, '\n'))
+}
+
+// Flush sends queued events in the background, events that fail to send stay
+// queued for the next run
+func Flush() {
+	if !Enabled() {
+		return
+	}
+	go func() {
+		requeueOrphans()
+		sending := fmt.Sprintf("%v.%v", pathQueue(), time.Now().UnixNano())
+		err := os.Rename(pathQueue(), sending)
+		if err != nil {
+			return
+		}
+		err = send(sending)
+		if err != nil {
+			slog.Info("telemetry not sent", "err", err)
+			requeue(sending)
+			return
+		}
+		os.Remove(sending)
+	}()
+}
+
+func send(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	events := []json.RawMessage{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		events = append(events, json.RawMessage(append([]byte{}, scanner.Bytes()...)))
+	}
+	if len(events) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+	endpoint := os.Getenv("SST_TELEMETRY_URL")
+	if endpoint == "" {
+		endpoint = DEFAULT_ENDPOINT
+	}
+	client := http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Post(endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("Telemetry endpoint returned %v", resp.StatusCode)
+	}
+	return nil
+}
+
+// requeueOrphans picks up batches left behind by a process that exited while
+// sending them. Batches are named after the time they were taken from the
+// queue, recent ones may still be in flight in another process.
+func requeueOrphans() {
+	matches, _ := filepath.Glob(pathQueue() + ".*")
+	for _, match := range matches {
+		taken, err := strconv.ParseInt(strings.TrimPrefix(match, pathQueue()+"."), 10, 64)
+		if err != nil || time.Since(time.Unix(0, taken)) < time.Minute {
+			continue
+		}
+		requeue(match)
+	}
+}
+
+func requeue(path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	file, err := os.OpenFile(pathQueue(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	_, err = file.Write(data)
+	if err == nil {
+		os.Remove(path)
+	}
+}
+
+const DEFAULT_REPORT_ENDPOINT = "https://telemetry.sst.dev/v1/reports"
+
+// SendReport submits a crash report, it is only called when the user asks for
+// it so it ignores whether telemetry is enabled
+func SendReport(data []byte) error {
+	endpoint := os.Getenv("SST_REPORT_URL")
+	if endpoint == "" {
+		endpoint = DEFAULT_REPORT_ENDPOINT
+	}
+	client := http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Post(endpoint, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("Report endpoint returned %v", resp.StatusCode)
+	}
+	return nil
+}